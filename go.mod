@@ -3,12 +3,16 @@ module edgexfoundry/app-rfid-llrp-inventory
 go 1.16
 
 require (
+	github.com/eclipse/paho.mqtt.golang v1.2.0
 	github.com/edgexfoundry/app-functions-sdk-go v1.3.1
 	github.com/edgexfoundry/go-mod-bootstrap v0.0.57
 	github.com/edgexfoundry/go-mod-configuration v0.0.8
 	github.com/edgexfoundry/go-mod-core-contracts v0.1.112
+	github.com/edgexfoundry/go-mod-messaging v0.1.30
 	github.com/gorilla/mux v1.8.0
+	github.com/mochi-co/mqtt v1.0.5
 	github.com/pelletier/go-toml v1.2.0
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.7.0
+	go.etcd.io/bbolt v1.3.6
 )
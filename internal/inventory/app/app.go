@@ -7,8 +7,9 @@ package inventoryapp
 
 import (
 	"context"
-	"edgexfoundry/app-rfid-llrp-inventory/internal/inventory"
-	"edgexfoundry/app-rfid-llrp-inventory/internal/llrp"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/sink"
 	"fmt"
 	"github.com/edgexfoundry/app-functions-sdk-go/appsdk"
 	"github.com/edgexfoundry/app-functions-sdk-go/pkg/transforms"
@@ -21,6 +22,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
@@ -32,21 +34,34 @@ const (
 	serviceKey = "rfid-llrp-inventory"
 
 	cacheFolder  = "cache"
-	tagCacheFile = "tags.json"
+	webhooksFile = "webhooks.json"
 	folderPerm   = 0755 // folders require the execute flag in order to create new files
-	filePerm     = 0644
 )
 
 type InventoryApp struct {
-	edgexSdk     *appsdk.AppFunctionsSDK
-	lc           logger.LoggingClient
-	devMu        sync.RWMutex
-	devService   llrp.DSClient
-	defaultGrp   *llrp.ReaderGroup
-	snapshotReqs chan snapshotDest
-	reports      chan reportData
-	configClient configuration.Client
-	config       inventory.ConsulConfig
+	edgexSdk    *appsdk.AppFunctionsSDK
+	lc          logger.LoggingClient
+	devMu       sync.RWMutex
+	devService  llrp.DSClient
+	defaultGrp  *llrp.ReaderGroup
+	reconciler  *llrp.Reconciler
+	metadataURL string
+	syncReqs    chan chan syncResult
+	// done is closed when the service starts shutting down,
+	// so requests stop waiting on loops which are exiting.
+	done          chan struct{}
+	syncInterval  chan uint
+	capCache      *llrp.CapabilityCache
+	snapshotReqs  chan snapshotDest
+	reports       chan reportData
+	notifications chan readerNotification
+	configClient  configuration.Client
+	config        inventory.ConsulConfig
+	webhooks      *sink.Webhooks
+
+	// processorMetrics reports the task loop's inventory engine load, once it's started.
+	metricsMu        sync.RWMutex
+	processorMetrics func() inventory.ProcessorMetrics
 }
 
 type reportData struct {
@@ -54,6 +69,16 @@ type reportData struct {
 	info   inventory.ReportInfo
 }
 
+type readerNotification struct {
+	device string
+	data   *llrp.ReaderEventNotificationData
+}
+
+type syncResult struct {
+	res llrp.SyncResult
+	err error
+}
+
 type snapshotDest struct {
 	w      io.Writer
 	result chan error
@@ -61,8 +86,13 @@ type snapshotDest struct {
 
 func NewInventoryApp() *InventoryApp {
 	return &InventoryApp{
-		snapshotReqs: make(chan snapshotDest),
-		reports:      make(chan reportData),
+		snapshotReqs:  make(chan snapshotDest),
+		reports:       make(chan reportData),
+		notifications: make(chan readerNotification, notificationQueueSize),
+		syncReqs:      make(chan chan syncResult),
+		done:          make(chan struct{}),
+		syncInterval:  make(chan uint, 1),
+		capCache:      llrp.NewCapabilityCache(),
 	}
 }
 
@@ -135,16 +165,17 @@ func (app *InventoryApp) Initialize() error {
 		return errors.New("missing device service name")
 	}
 	metadataURI.Path = "/api/v1/device/servicename/" + dsName
-	deviceNames, err := llrp.GetDevices(metadataURI.String(), http.DefaultClient)
-	if err != nil {
-		return errors.Wrapf(err, "failed to get existing device names. path=%s", metadataURI.String())
-	}
-	for _, name := range deviceNames {
-		if err = app.defaultGrp.AddReader(app.devService, name); err != nil {
-			return errors.Wrapf(err, "failed to setup device %s", name)
-		}
+	app.metadataURL = metadataURI.String()
+
+	// Readers are added in the background once the service is running,
+	// so a Reader that can't be configured doesn't prevent startup.
+	app.reconciler = llrp.NewReconciler(app.defaultGrp, app.devService)
+	if _, err := app.syncDevices(); err != nil {
+		return err
 	}
 
+	app.webhooks = app.newWebhooks()
+
 	return app.addRoutes()
 }
 
@@ -224,12 +255,22 @@ func (app *InventoryApp) RunUntilCancelled() error {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	var wg sync.WaitGroup
-	wg.Add(1)
+	wg.Add(3)
 	go func() {
 		defer wg.Done()
 		app.taskLoop(ctx)
 		app.lc.Info("Task loop has exited.")
 	}()
+	go func() {
+		defer wg.Done()
+		app.reconciler.Run(ctx)
+		app.lc.Info("Reconciler has exited.")
+	}()
+	go func() {
+		defer wg.Done()
+		app.syncLoop(ctx)
+		app.lc.Info("Device sync loop has exited.")
+	}()
 
 	// We are doing this because of an issue with running app-functions-sdk inside
 	// of docker-compose where something is hanging and not relinquishing control
@@ -247,6 +288,7 @@ func (app *InventoryApp) RunUntilCancelled() error {
 
 		app.lc.Info(fmt.Sprintf("Received '%s' signal from OS.", s.String()))
 		cancel() // signal the taskLoop to finish
+		close(app.done)
 	}()
 
 	// Subscribe to events.
@@ -268,6 +310,33 @@ func (app *InventoryApp) RunUntilCancelled() error {
 	return nil
 }
 
+// newWebhooks returns the webhook registry, loaded from the cache folder.
+// If the file can't be read, the webhooks are only kept in memory,
+// so the file is left as it is.
+func (app *InventoryApp) newWebhooks() *sink.Webhooks {
+	as := app.config.ApplicationSettings
+	cfg := sink.WebhooksConfig{
+		Path:           filepath.Join(cacheFolder, webhooksFile),
+		MaxAttempts:    int(as.WebhookMaxAttempts),
+		InitialBackoff: time.Duration(as.WebhookInitialBackoffSeconds) * time.Second,
+		MaxBackoff:     time.Duration(as.WebhookMaxBackoffSeconds) * time.Second,
+		Timeout:        time.Duration(as.WebhookTimeoutSeconds) * time.Second,
+	}
+	for _, host := range strings.Split(as.WebhookAllowedHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			cfg.AllowedHosts = append(cfg.AllowedHosts, host)
+		}
+	}
+
+	webhooks, err := sink.NewWebhooks(app.lc, cfg)
+	if err != nil {
+		app.lc.Error("Failed to load webhooks; new ones won't be saved.", "error", err.Error())
+		cfg.Path = ""
+		webhooks, _ = sink.NewWebhooks(app.lc, cfg)
+	}
+	return webhooks
+}
+
 func (app *InventoryApp) LoggingClient() logger.LoggingClient {
 	return app.lc
 }
@@ -6,10 +6,13 @@
 package inventoryapp
 
 import (
+	"context"
 	"fmt"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -181,3 +184,18 @@ func TestBootstrapAliasConfig_Table(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncReaders_notRunning(t *testing.T) {
+	// without a sync loop, a request waits until it's cancelled...
+	app, _ := makeTestApp()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := app.requestSync(ctx)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// ...or the service shuts down
+	close(app.done)
+	rec := httptest.NewRecorder()
+	app.syncReaders(rec, httptest.NewRequest(http.MethodPost, "/api/v1/readers/sync", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -8,16 +8,15 @@ package inventoryapp
 import (
 	"bytes"
 	"context"
-	"edgexfoundry/app-rfid-llrp-inventory/internal/inventory"
-	"edgexfoundry/app-rfid-llrp-inventory/internal/llrp"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
 	"encoding/json"
 	"fmt"
 	"github.com/edgexfoundry/app-functions-sdk-go/appcontext"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 	"github.com/pkg/errors"
 	"io"
-	"io/ioutil"
-	"path/filepath"
+	"net/http"
 	"sync"
 	"time"
 )
@@ -30,7 +29,11 @@ const (
 	resourceInventoryEvent     = "InventoryEvent"
 
 	coreDataPostTimeout = 3 * time.Minute
-	eventChSz           = 100
+	metadataGetTimeout  = 30 * time.Second
+
+	// notificationQueueSize is how many reader notifications may wait for the taskLoop
+	// before new ones are dropped, so a stopped taskLoop can't block the SDK pipeline.
+	notificationQueueSize = 100
 )
 
 // processEdgeXEvent is our core processing logic for EdgeX events after they are first
@@ -99,7 +102,7 @@ func (app *InventoryApp) processEdgeXEvent(_ *appcontext.Context, params ...inte
 				app.lc.Warn("No tag report data in report.", "device", event.Device)
 			} else {
 				// pass the tag report data to the reports channel to be processed by our taskLoop
-				app.reports <- reportData{report, inventory.NewReportInfo(reading)}
+				app.reports <- reportData{report, inventory.NewReportInfo(reading.Device, reading.Origin)}
 				app.lc.Trace("New ROAccessReport.",
 					"device", event.Device, "tags", len(report.TagReportData))
 			}
@@ -112,20 +115,39 @@ func (app *InventoryApp) processEdgeXEvent(_ *appcontext.Context, params ...inte
 // handleReaderEvent handles an llrp.ReaderEventNotification from the Device Service.
 //
 // If a device reports a new connection event,
-// this adds the reader to the list of managed readers.
+// this asks the reconciler to add the reader to the list of managed readers
+// (which also reapplies the current behavior to a reader that rebooted).
 // If a device reports a close event, it removes that reader.
+// In both cases, it updates the reader's connection status.
+//
+// Any other events in the notification update the reader's status
+// and are passed to the taskLoop to be sent to core-data by the inventory engine,
+// unless its queue is full, in which case they're dropped with a warning.
 func (app *InventoryApp) handleReaderEvent(device string, notification *llrp.ReaderEventNotification) error {
 	const connSuccess = llrp.ConnectionAttemptEvent(llrp.ConnSuccess)
 
 	data := notification.ReaderEventNotificationData
+	app.defaultGrp.Health().HandleNotification(device, &data)
+	select {
+	case app.notifications <- readerNotification{device, &data}:
+	default:
+		app.lc.Warn("Dropping reader notification because the task loop isn't keeping up.",
+			"device", device)
+	}
+
 	switch {
 	case data.ConnectionAttemptEvent != nil && *data.ConnectionAttemptEvent == connSuccess:
+		// the reader may have been updated or reconfigured while disconnected
+		app.capCache.Invalidate(device)
+		app.defaultGrp.Health().Connected(device)
 		app.lc.Info(fmt.Sprintf("Adding device to default group: %v", device))
-		return app.defaultGrp.AddReader(app.devService, device)
+		app.reconciler.Want(device)
 
 	case data.ConnectionCloseEvent != nil:
+		app.capCache.Invalidate(device)
+		app.defaultGrp.Health().Disconnected(device)
 		app.lc.Info(fmt.Sprintf("Removing device from default group: %v", device))
-		app.defaultGrp.RemoveReader(device)
+		app.reconciler.Forget(device)
 	}
 
 	return nil
@@ -148,43 +170,149 @@ func (app *InventoryApp) requestInventorySnapshot(w io.Writer) error {
 	return <-writeErr
 }
 
+// syncDevices fetches the device list from core-metadata
+// and syncs the reconciler's desired state with it.
+func (app *InventoryApp) syncDevices() (llrp.SyncResult, error) {
+	devices, err := llrp.GetDeviceInfo(app.metadataURL, &http.Client{Timeout: metadataGetTimeout})
+	if err != nil {
+		return llrp.SyncResult{}, errors.Wrapf(err,
+			"failed to get existing devices. path=%s", app.metadataURL)
+	}
+
+	res := app.reconciler.Sync(devices)
+	if len(res.Added)+len(res.Removed)+len(res.Changed) > 0 {
+		app.lc.Info("Synced devices with core-metadata.",
+			"added", fmt.Sprintf("%v", res.Added),
+			"removed", fmt.Sprintf("%v", res.Removed),
+			"changed", fmt.Sprintf("%v", res.Changed))
+	}
+	return res, nil
+}
+
+// errShuttingDown is returned to requests which can't be handled
+// because the service is shutting down.
+var errShuttingDown = errors.New("service is shutting down")
+
+// requestSync requests an immediate device sync and waits for its result,
+// unless the context is cancelled or the service shuts down first.
+func (app *InventoryApp) requestSync(ctx context.Context) (llrp.SyncResult, error) {
+	result := make(chan syncResult, 1)
+	select {
+	case app.syncReqs <- result:
+	case <-ctx.Done():
+		return llrp.SyncResult{}, ctx.Err()
+	case <-app.done:
+		return llrp.SyncResult{}, errShuttingDown
+	}
+
+	select {
+	case r := <-result:
+		return r.res, r.err
+	case <-ctx.Done():
+		return llrp.SyncResult{}, ctx.Err()
+	case <-app.done:
+		return llrp.SyncResult{}, errShuttingDown
+	}
+}
+
+// syncLoop periodically syncs devices with core-metadata,
+// and handles requests for immediate syncs,
+// so syncs never run concurrently.
+//
+// An interval of 0 disables periodic syncs,
+// though requested syncs still run.
+func (app *InventoryApp) syncLoop(ctx context.Context) {
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	setInterval := func(secs uint) {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+		if secs != 0 {
+			ticker = time.NewTicker(time.Duration(secs) * time.Second)
+			tick = ticker.C
+		}
+	}
+
+	setInterval(app.config.ApplicationSettings.MetadataSyncIntervalSeconds)
+	defer setInterval(0)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-tick:
+			if _, err := app.syncDevices(); err != nil {
+				app.lc.Error("Failed to sync devices.", "error", err.Error())
+			}
+
+		case result := <-app.syncReqs:
+			res, err := app.syncDevices()
+			result <- syncResult{res, err}
+
+		case secs := <-app.syncInterval:
+			setInterval(secs)
+			app.lc.Info(fmt.Sprintf("Changing device sync interval to %d seconds.", secs))
+		}
+	}
+}
+
 // taskLoop is our main event loop for async processes
 // that can't be modeled within the SDK's pipeline event loop.
 //
-// Namely, it launches scheduled tasks and configuration changes.
-// Since nearly every round through this loop must read or write the inventory,
-// this taskLoop ensures the modifications are done safely
-// without requiring a ton of lock contention on the inventory itself.
+// It runs the inventory engine, which processes the inventory and its scheduled tasks,
+// and feeds it tag reports, reader notifications, and configuration changes.
+// Reports pass through this loop so that each Reader's are normalized in order.
 func (app *InventoryApp) taskLoop(ctx context.Context) {
-	departedCheckSeconds := app.config.ApplicationSettings.DepartedCheckIntervalSeconds
-	aggregateDepartedTicker := time.NewTicker(time.Duration(departedCheckSeconds) * time.Second)
-	ageoutTicker := time.NewTicker(1 * time.Hour)
+	syncSeconds := app.config.ApplicationSettings.MetadataSyncIntervalSeconds
 	confErrCh := make(chan error)
 	confUpdateCh := make(chan interface{})
-	eventCh := make(chan []inventory.Event, eventChSz)
 
 	defer func() {
-		aggregateDepartedTicker.Stop()
-		ageoutTicker.Stop()
 		close(confErrCh)
 		close(confUpdateCh)
 	}()
 
 	// load tag data
-	var snapshot []inventory.StaticTag
-	snapshotData, err := ioutil.ReadFile(filepath.Join(cacheFolder, tagCacheFile))
+	as := app.config.ApplicationSettings
+	store, err := inventory.NewSnapshotStore(app.lc, as.SnapshotStore, cacheFolder, as.SnapshotBackups)
 	if err != nil {
-		app.lc.Warn("Failed to load inventory snapshot.", "error", err.Error())
-	} else {
-		if err := json.Unmarshal(snapshotData, &snapshot); err != nil {
-			app.lc.Warn("Failed to unmarshal inventory snapshot.", "error", err.Error())
+		app.lc.Error("Failed to open snapshot store; using the file store.",
+			"store", as.SnapshotStore, "error", err.Error())
+		store, _ = inventory.NewSnapshotStore(app.lc, inventory.FileSnapshotStoreType, cacheFolder, as.SnapshotBackups)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			app.lc.Warn("Failed to close snapshot store.", "error", err.Error())
 		}
+	}()
+
+	opts := []inventory.Option{
+		inventory.WithLogger(app.lc),
+		inventory.WithConfig(app.config),
+		inventory.WithSnapshotStore(store),
+		inventory.WithReaderStatuses(app.defaultGrp.Health().Statuses),
+	}
+	sinks := app.eventSinks()
+	defer func() {
+		for _, sink := range sinks {
+			if sink.close != nil {
+				sink.close()
+			}
+		}
+	}()
+	for _, sink := range sinks {
+		opts = append(opts, app.subscribe(sink))
 	}
 
-	processor := inventory.NewTagProcessor(app.lc, app.config, snapshot)
-	if len(snapshot) > 0 {
-		app.lc.Info(fmt.Sprintf("Restored %d tags from cache.", len(snapshot)))
+	engine, err := inventory.NewEngine(opts...)
+	if err != nil {
+		app.lc.Error("Failed to create the inventory engine.", "error", err.Error())
+		return
 	}
+	app.setProcessorMetrics(engine.Metrics)
 
 	app.configClient.WatchForChanges(confUpdateCh, confErrCh, &app.config, "/")
 
@@ -192,13 +320,9 @@ func (app *InventoryApp) taskLoop(ctx context.Context) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		app.lc.Info("Starting event processor.")
-		for events := range eventCh {
-			if err := app.pushEventsToCoreData(ctx, events); err != nil {
-				app.lc.Error("Failed to push events to CoreData.", "error", err.Error())
-			}
-		}
-		app.lc.Info("Event processor stopped.")
+		app.lc.Info("Starting inventory engine.")
+		_ = engine.Run(ctx)
+		app.lc.Info("Inventory engine stopped.")
 	}()
 
 	app.lc.Info("Starting task loop.")
@@ -206,48 +330,29 @@ func (app *InventoryApp) taskLoop(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			app.lc.Info("Stopping task loop.")
-			close(eventCh)
-			app.persistSnapshot(snapshot)
 			wg.Wait()
 			app.lc.Info("Task loop stopped.")
 			return
 
 		case rd := <-app.reports:
-			// TODO: we should refactor the ReaderGroup/TagReader
-			//   to unite its tag processing with the TagProcessor code;
-			//   the biggest goal is to perform only a single pass on the TagReportData.
-			//   Secondarily, it would allow us to eliminate the ReaderGroup mutex.
-			if !app.defaultGrp.ProcessTagReport(rd.info.DeviceName, rd.report.TagReportData) {
+			n, known := app.defaultGrp.ReportNormalizer(rd.info.DeviceName)
+			if !known {
 				// This can only happen if the device didn't exist when we started,
 				// and we never got a Connection message for it.
 				app.lc.Error("Tag Report for unknown device.", "device", rd.info.DeviceName)
 			}
 
-			events, updatedSnapshot := processor.ProcessReport(rd.report, rd.info)
-			if updatedSnapshot != nil {
-				snapshot = updatedSnapshot // always update the snapshot if available
-			}
-			if len(events) > 0 {
-				app.persistSnapshot(snapshot) // only persist when there are inventory events
-				eventCh <- events
+			nr := inventory.NormalizeReport(rd.report, rd.info.DeviceName, n)
+			if known {
+				app.defaultGrp.Health().ReportReceived(rd.info.DeviceName, nr.EPCs())
 			}
-
-		case t := <-aggregateDepartedTicker.C:
-			app.lc.Debug("Running AggregateDeparted.", "time", fmt.Sprintf("%v", t))
-
-			if events, updatedSnapshot := processor.AggregateDeparted(); len(events) > 0 {
-				if updatedSnapshot != nil { // should always be true if there are events
-					snapshot = updatedSnapshot
-					app.persistSnapshot(snapshot)
-				}
-				eventCh <- events
+			if err := engine.ProcessReport(ctx, nr, rd.info); err != nil {
+				app.lc.Warn("Failed to process tag report.", "device", rd.info.DeviceName, "error", err.Error())
 			}
 
-		case t := <-ageoutTicker.C:
-			app.lc.Debug("Running AgeOut.", "time", fmt.Sprintf("%v", t))
-			if _, updatedSnapshot := processor.AgeOut(); updatedSnapshot != nil {
-				snapshot = updatedSnapshot
-				app.persistSnapshot(snapshot)
+		case n := <-app.notifications:
+			if err := engine.ProcessReaderNotification(ctx, n.device, n.data); err != nil {
+				app.lc.Warn("Failed to process reader notification.", "device", n.device, "error", err.Error())
 			}
 
 		case rawConfig := <-confUpdateCh:
@@ -257,25 +362,31 @@ func (app *InventoryApp) taskLoop(ctx context.Context) {
 				continue
 			}
 
-			if err := newConfig.ApplicationSettings.Validate(); err != nil {
+			if err := engine.UpdateConfig(ctx, *newConfig); err != nil {
 				app.lc.Error("Invalid Consul configuration.", "error", err.Error())
 				continue
 			}
 
 			app.lc.Info("Configuration updated from consul.")
 			app.lc.Debug("New consul config.", "config", fmt.Sprintf("%+v", newConfig))
-			processor.UpdateConfig(*newConfig)
-
-			// check if we need to change the ticker interval
-			if departedCheckSeconds != newConfig.ApplicationSettings.DepartedCheckIntervalSeconds {
-				aggregateDepartedTicker.Stop()
-				departedCheckSeconds = newConfig.ApplicationSettings.DepartedCheckIntervalSeconds
-				aggregateDepartedTicker = time.NewTicker(time.Duration(departedCheckSeconds) * time.Second)
-				app.lc.Info(fmt.Sprintf("Changing aggregate departed check interval to %d seconds.", departedCheckSeconds))
+
+			if syncSeconds != newConfig.ApplicationSettings.MetadataSyncIntervalSeconds {
+				syncSeconds = newConfig.ApplicationSettings.MetadataSyncIntervalSeconds
+				// This is the only sender, so after dropping any change
+				// the syncLoop hasn't seen yet, this won't block.
+				select {
+				case <-app.syncInterval:
+				default:
+				}
+				app.syncInterval <- syncSeconds
 			}
 
 		case req := <-app.snapshotReqs:
-			data, err := json.Marshal(snapshot)
+			snapshot, err := engine.Snapshot(ctx)
+			var data []byte
+			if err == nil {
+				data, err = json.Marshal(snapshot)
+			}
 			if err == nil {
 				_, err = req.w.Write(data) // only write if there was no error already
 			}
@@ -287,19 +398,24 @@ func (app *InventoryApp) taskLoop(ctx context.Context) {
 	}
 }
 
-func (app *InventoryApp) persistSnapshot(snapshot []inventory.StaticTag) {
-	app.lc.Debug("Persisting inventory snapshot.")
-	data, err := json.Marshal(snapshot)
-	if err != nil {
-		app.lc.Warn("Failed to marshal inventory snapshot.", "error", err.Error())
-		return
-	}
+// setProcessorMetrics sets the source of the tag processor's metrics.
+func (app *InventoryApp) setProcessorMetrics(metrics func() inventory.ProcessorMetrics) {
+	app.metricsMu.Lock()
+	app.processorMetrics = metrics
+	app.metricsMu.Unlock()
+}
 
-	if err := ioutil.WriteFile(filepath.Join(cacheFolder, tagCacheFile), data, filePerm); err != nil {
-		app.lc.Warn("Failed to persist inventory snapshot.", "error", err.Error())
-		return
+// getProcessorMetrics returns the tag processor's metrics,
+// or false if the task loop hasn't started it yet.
+func (app *InventoryApp) getProcessorMetrics() (inventory.ProcessorMetrics, bool) {
+	app.metricsMu.RLock()
+	metrics := app.processorMetrics
+	app.metricsMu.RUnlock()
+
+	if metrics == nil {
+		return inventory.ProcessorMetrics{}, false
 	}
-	app.lc.Info("Persisted inventory snapshot.", "tags", len(snapshot))
+	return metrics(), true
 }
 
 // setDefaultBehavior sets the behavior associated with the default device group.
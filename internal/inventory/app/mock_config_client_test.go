@@ -6,7 +6,7 @@
 package inventoryapp
 
 import (
-	"edgexfoundry/app-rfid-llrp-inventory/internal/inventory"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
 	"fmt"
 	"github.com/pelletier/go-toml"
 )
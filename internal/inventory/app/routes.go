@@ -6,7 +6,8 @@
 package inventoryapp
 
 import (
-	"edgexfoundry/app-rfid-llrp-inventory/internal/llrp"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/sink"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
@@ -29,10 +30,22 @@ func (app *InventoryApp) addRoutes() error {
 		"/api/v1/readers", http.MethodGet, app.getReaders); err != nil {
 		return err
 	}
+	if err := app.addRoute(
+		"/api/v1/readers/sync", http.MethodPost, app.syncReaders); err != nil {
+		return err
+	}
+	if err := app.addRoute(
+		"/api/v1/readers/{name}/capabilities", http.MethodGet, app.getReaderCapabilities); err != nil {
+		return err
+	}
 	if err := app.addRoute(
 		"/api/v1/inventory/snapshot", http.MethodGet, app.getSnapshot); err != nil {
 		return err
 	}
+	if err := app.addRoute(
+		"/api/v1/inventory/metrics", http.MethodGet, app.getInventoryMetrics); err != nil {
+		return err
+	}
 	if err := app.addRoute(
 		"/api/v1/command/reading/start", http.MethodPost, app.startReading); err != nil {
 		return err
@@ -41,6 +54,34 @@ func (app *InventoryApp) addRoutes() error {
 		"/api/v1/command/reading/stop", http.MethodPost, app.stopReading); err != nil {
 		return err
 	}
+	if err := app.addRoute(
+		"/api/v1/webhooks", http.MethodGet, app.listWebhooks); err != nil {
+		return err
+	}
+	if err := app.addRoute(
+		"/api/v1/webhooks", http.MethodPost, app.registerWebhook); err != nil {
+		return err
+	}
+	if err := app.addRoute(
+		"/api/v1/webhooks/{id}", http.MethodGet, app.getWebhook); err != nil {
+		return err
+	}
+	if err := app.addRoute(
+		"/api/v1/webhooks/{id}", http.MethodDelete, app.removeWebhook); err != nil {
+		return err
+	}
+	if err := app.addRoute(
+		"/api/v1/webhooks/{id}/dead-letters", http.MethodGet, app.getWebhookDeadLetters); err != nil {
+		return err
+	}
+	if err := app.addRoute(
+		"/api/v1/webhooks/{id}/dead-letters", http.MethodDelete, app.clearWebhookDeadLetters); err != nil {
+		return err
+	}
+	if err := app.addRoute(
+		"/api/v1/webhooks/{id}/dead-letters/redeliver", http.MethodPost, app.redeliverWebhook); err != nil {
+		return err
+	}
 	if err := app.addRoute(
 		"/api/v1/behaviors/{name}", http.MethodGet, app.getBehavior); err != nil {
 		return err
@@ -75,6 +116,48 @@ func (app *InventoryApp) getReaders(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+func (app *InventoryApp) syncReaders(w http.ResponseWriter, req *http.Request) {
+	res, err := app.requestSync(req.Context())
+	if err != nil {
+		msg := fmt.Sprintf("Failed to sync readers: %v", err)
+		app.lc.Error(msg)
+		code := http.StatusBadGateway
+		if errors.Is(err, errShuttingDown) {
+			code = http.StatusServiceUnavailable
+		}
+		http.Error(w, msg, code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		app.lc.Error("Failed to write sync result.", "error", err.Error())
+	}
+}
+
+func (app *InventoryApp) getReaderCapabilities(w http.ResponseWriter, req *http.Request) {
+	name := mux.Vars(req)["name"]
+	if !app.defaultGrp.HasReader(name) {
+		msg := fmt.Sprintf("Request for capabilities of unknown reader. Name: %v", name)
+		app.lc.Error(msg)
+		http.Error(w, msg, http.StatusNotFound)
+		return
+	}
+
+	caps, err := app.capCache.Get(app.devService, name)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to get reader capabilities: %v", err)
+		app.lc.Error(msg)
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(caps); err != nil {
+		app.lc.Error("Failed to write reader capabilities.", "error", err.Error())
+	}
+}
+
 func (app *InventoryApp) getSnapshot(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := app.requestInventorySnapshot(w); err != nil {
@@ -85,6 +168,119 @@ func (app *InventoryApp) getSnapshot(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+func (app *InventoryApp) getInventoryMetrics(w http.ResponseWriter, _ *http.Request) {
+	metrics, ok := app.getProcessorMetrics()
+	if !ok {
+		http.Error(w, "Inventory processing has not started.", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(metrics); err != nil {
+		app.lc.Error("Failed to write inventory metrics.", "error", err.Error())
+	}
+}
+
+func (app *InventoryApp) listWebhooks(w http.ResponseWriter, _ *http.Request) {
+	app.writeJSON(w, http.StatusOK, app.webhooks.List(), "webhooks")
+}
+
+func (app *InventoryApp) registerWebhook(w http.ResponseWriter, req *http.Request) {
+	var cfg sink.WebhookConfig
+	dec := json.NewDecoder(io.LimitReader(req.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to parse webhook: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := app.webhooks.Register(cfg)
+	if err != nil {
+		app.writeWebhookError(w, err)
+		return
+	}
+
+	status, err := app.webhooks.Status(cfg.ID)
+	if err != nil {
+		app.writeWebhookError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusCreated, status, "webhook")
+}
+
+func (app *InventoryApp) getWebhook(w http.ResponseWriter, req *http.Request) {
+	status, err := app.webhooks.Status(mux.Vars(req)["id"])
+	if err != nil {
+		app.writeWebhookError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, status, "webhook")
+}
+
+func (app *InventoryApp) removeWebhook(w http.ResponseWriter, req *http.Request) {
+	if err := app.webhooks.Remove(mux.Vars(req)["id"]); err != nil {
+		app.writeWebhookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *InventoryApp) getWebhookDeadLetters(w http.ResponseWriter, req *http.Request) {
+	letters, err := app.webhooks.DeadLetters(mux.Vars(req)["id"])
+	if err != nil {
+		app.writeWebhookError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, letters, "dead letters")
+}
+
+func (app *InventoryApp) clearWebhookDeadLetters(w http.ResponseWriter, req *http.Request) {
+	if err := app.webhooks.ClearDeadLetters(mux.Vars(req)["id"]); err != nil {
+		app.writeWebhookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *InventoryApp) redeliverWebhook(w http.ResponseWriter, req *http.Request) {
+	n, err := app.webhooks.Redeliver(mux.Vars(req)["id"])
+	if err != nil {
+		app.writeWebhookError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, struct {
+		Queued int `json:"queued"`
+	}{n}, "redelivery result")
+}
+
+// writeWebhookError responds with the status that matches a webhook registry error.
+func (app *InventoryApp) writeWebhookError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, sink.ErrWebhookNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, sink.ErrInvalidWebhook):
+		code = http.StatusBadRequest
+	case errors.Is(err, sink.ErrWebhookNotAllowed):
+		code = http.StatusForbidden
+	case errors.Is(err, sink.ErrWebhookExists):
+		code = http.StatusConflict
+	case errors.Is(err, sink.ErrWebhooksClosed):
+		code = http.StatusServiceUnavailable
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// writeJSON responds with v's JSON, logging an error if it can't;
+// what describes v in that message.
+func (app *InventoryApp) writeJSON(w http.ResponseWriter, code int, v interface{}, what string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		app.lc.Error("Failed to write "+what+".", "error", err.Error())
+	}
+}
+
 func (app *InventoryApp) startReading(w http.ResponseWriter, _ *http.Request) {
 	if err := app.defaultGrp.StartAll(app.devService); err != nil {
 		msg := fmt.Sprintf("Failed to StartAll: %v", err)
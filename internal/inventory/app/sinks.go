@@ -0,0 +1,162 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventoryapp
+
+import (
+	"context"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/sink"
+	"fmt"
+	"github.com/edgexfoundry/go-mod-messaging/messaging"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/pkg/errors"
+	"reflect"
+	"time"
+)
+
+// sinkQueueSize is how many batches of events an asynchronous sink queues
+// before it drops them.
+const sinkQueueSize = 100
+
+// eventSink is a destination for inventory events.
+type eventSink struct {
+	name string
+	send func(ctx context.Context, events []inventory.Event) error
+	// opts configure the sink's subscription, e.g., to restrict the events it receives.
+	opts []inventory.SubscribeOption
+	// close, if set, releases the sink's resources once the engine has stopped.
+	close func()
+}
+
+// eventSinks returns the sinks to which the inventory engine publishes its events.
+// To send events somewhere new, add a sink here.
+// Sinks which aren't configured, or whose configuration is invalid, are left out.
+func (app *InventoryApp) eventSinks() []eventSink {
+	as := app.config.ApplicationSettings
+
+	edgeX := eventSink{name: "core-data", send: app.pushEventsToCoreData}
+	if as.PublishEventsToMessageBus {
+		bus, err := app.newMessageBusSink()
+		if err != nil {
+			app.lc.Error("Failed to create message bus sink; sending events to core-data instead.",
+				"error", err.Error())
+		} else {
+			edgeX = bus
+		}
+	}
+
+	sinks := []eventSink{
+		edgeX,
+		// each webhook has its own queue, so they don't need another
+		{name: "webhooks", send: app.webhooks.Send, close: app.webhooks.Close},
+	}
+	if as.MQTTBrokerURL != "" {
+		m, err := sink.NewMQTT(app.lc, sink.MQTTConfig{
+			BrokerURL:          as.MQTTBrokerURL,
+			ClientID:           as.MQTTClientID,
+			Username:           as.MQTTUsername,
+			Password:           as.MQTTPassword,
+			Topic:              as.MQTTTopic,
+			TagStateTopic:      as.MQTTTagStateTopic,
+			TagStateTTL:        time.Duration(as.AgeOutHours) * time.Hour,
+			Site:               as.SiteName,
+			QoS:                byte(as.MQTTQoS),
+			CAFile:             as.MQTTCAFile,
+			CertFile:           as.MQTTCertFile,
+			KeyFile:            as.MQTTKeyFile,
+			InsecureSkipVerify: as.MQTTSkipVerify,
+		})
+		if err != nil {
+			app.lc.Error("Failed to create MQTT sink; events won't be published to MQTT.", "error", err.Error())
+		} else {
+			sinks = append(sinks, eventSink{
+				name:  "mqtt",
+				send:  m.Send,
+				opts:  []inventory.SubscribeOption{inventory.Async(sinkQueueSize)},
+				close: m.Close,
+			})
+		}
+	}
+
+	return sinks
+}
+
+// busConfiguration holds the SDK's message bus settings.
+type busConfiguration struct {
+	MessageBus types.MessageBusConfig
+	Binding    struct {
+		PublishTopic string
+	}
+}
+
+// newMessageBusSink returns a sink which publishes events to the [Binding] PublishTopic
+// of the SDK's configured [MessageBus], with a suffix for each event type and alias.
+func (app *InventoryApp) newMessageBusSink() (eventSink, error) {
+	res, err := app.configClient.GetConfiguration(&busConfiguration{})
+	if err != nil {
+		return eventSink{}, errors.Wrap(err, "failed to get message bus configuration")
+	}
+	cfg, ok := res.(*busConfiguration)
+	if !ok {
+		return eventSink{}, fmt.Errorf("error converting message bus configuration. type=%v", reflect.TypeOf(res))
+	}
+	return app.messageBusSink(cfg)
+}
+
+// messageBusSink returns a sink which publishes events with its own message bus client.
+//
+// The SDK only publishes the pipeline's output, and ours has none,
+// so this sink's client is the only one publishing to the bus.
+func (app *InventoryApp) messageBusSink(cfg *busConfiguration) (eventSink, error) {
+	if cfg.MessageBus.PublishHost.IsHostInfoEmpty() {
+		return eventSink{}, errors.New("missing message bus publish host")
+	}
+
+	client, err := messaging.NewMessageClient(types.MessageBusConfig{
+		PublishHost: cfg.MessageBus.PublishHost,
+		Type:        cfg.MessageBus.Type,
+		Optional:    cfg.MessageBus.Optional,
+	})
+	if err != nil {
+		return eventSink{}, errors.Wrap(err, "failed to create message bus client")
+	}
+	if err := client.Connect(); err != nil {
+		return eventSink{}, errors.Wrap(err, "failed to connect to message bus")
+	}
+
+	bus, err := sink.NewMessageBus(app.lc, client, sink.MessageBusConfig{
+		Topic:         cfg.Binding.PublishTopic,
+		DeviceName:    eventDeviceName,
+		ReadingPrefix: resourceInventoryEvent,
+	})
+	if err != nil {
+		_ = client.Disconnect()
+		return eventSink{}, err
+	}
+
+	app.lc.Info("Publishing inventory events to the message bus.",
+		"topic", cfg.Binding.PublishTopic, "host", cfg.MessageBus.PublishHost.GetHostURL())
+	return eventSink{
+		name: "message-bus",
+		send: bus.Send,
+		opts: []inventory.SubscribeOption{inventory.Async(sinkQueueSize)},
+		close: func() {
+			if err := client.Disconnect(); err != nil {
+				app.lc.Warn("Failed to disconnect from message bus.", "error", err.Error())
+			}
+		},
+	}, nil
+}
+
+// subscribe returns an option which subscribes the sink to the inventory engine,
+// logging any error it returns.
+func (app *InventoryApp) subscribe(s eventSink) inventory.Option {
+	return inventory.WithSubscriber(func(ctx context.Context, events []inventory.Event) {
+		if err := s.send(ctx, events); err != nil {
+			app.lc.Error("Failed to send inventory events.", "sink", s.name, "error", err.Error())
+		}
+	}, s.opts...)
+}
@@ -0,0 +1,96 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventoryapp
+
+import (
+	"context"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"encoding/json"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/edgexfoundry/go-mod-messaging/messaging"
+	busmqtt "github.com/edgexfoundry/go-mod-messaging/messaging/mqtt"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/mochi-co/mqtt/server"
+	"github.com/mochi-co/mqtt/server/listeners"
+	"github.com/mochi-co/mqtt/server/listeners/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMessageBusSink(t *testing.T) {
+	// an embedded MQTT broker stands in for the message bus
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
+
+	broker := server.New()
+	require.NoError(t, broker.AddListener(listeners.NewTCP("tcp", ln.Addr().String()),
+		&listeners.Config{Auth: new(auth.Allow)}))
+	require.NoError(t, broker.Serve())
+	defer broker.Close()
+
+	host := types.HostInfo{Host: "127.0.0.1", Port: port, Protocol: "tcp"}
+	subscriber, err := messaging.NewMessageClient(types.MessageBusConfig{
+		PublishHost: host,
+		Type:        messaging.MQTT,
+		Optional:    map[string]string{busmqtt.ClientId: "subscriber"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, subscriber.Connect())
+	defer func() { _ = subscriber.Disconnect() }()
+
+	messages := make(chan types.MessageEnvelope, 10)
+	require.NoError(t, subscriber.Subscribe(
+		[]types.TopicChannel{{Topic: "events/Arrived/Freezer", Messages: messages}}, make(chan error, 10)))
+
+	app, _ := makeTestApp()
+	cfg := &busConfiguration{}
+	cfg.MessageBus.PublishHost = host
+	cfg.MessageBus.Type = messaging.MQTT
+	cfg.MessageBus.Optional = map[string]string{busmqtt.ClientId: "publisher"}
+	cfg.Binding.PublishTopic = "events"
+	bus, err := app.messageBusSink(cfg)
+	require.NoError(t, err)
+	defer bus.close()
+
+	// only the events with the subscribed type and alias are received
+	arrived := inventory.ArrivedEvent{BaseEvent: inventory.BaseEvent{EPC: "0102"}, Location: "Freezer"}
+	require.NoError(t, bus.send(context.Background(), []inventory.Event{
+		inventory.ArrivedEvent{BaseEvent: inventory.BaseEvent{EPC: "0304"}, Location: "Dock"},
+		inventory.DepartedEvent{BaseEvent: inventory.BaseEvent{EPC: "0506"}, LastKnownLocation: "Freezer"},
+		arrived,
+	}))
+
+	var envelope types.MessageEnvelope
+	select {
+	case envelope = <-messages:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	assert.Equal(t, clients.ContentTypeJSON, envelope.ContentType)
+	assert.NotEmpty(t, envelope.CorrelationID)
+
+	var edgeXEvent models.Event
+	require.NoError(t, json.Unmarshal(envelope.Payload, &edgeXEvent))
+	assert.Equal(t, eventDeviceName, edgeXEvent.Device)
+	require.Len(t, edgeXEvent.Readings, 1)
+	assert.Equal(t, resourceInventoryEvent+string(inventory.ArrivedType), edgeXEvent.Readings[0].Name)
+
+	payload, err := json.Marshal(arrived)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(payload), edgeXEvent.Readings[0].Value)
+
+	select {
+	case envelope = <-messages:
+		t.Fatalf("unexpected message: %s", envelope.Payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,262 @@
+//
+// Copyright (C) 2020 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"github.com/pkg/errors"
+	"math"
+	"sort"
+	"sync"
+)
+
+// circularBuffer is essentially a moving slice with a max size, where every time a new value is inserted,
+// the oldest value is removed from the slice. This is used for calculating moving averages of values over time.
+// For performance reasons it is implemented as a fixed size slice with a pointer to where to insert the next value
+// such that no new memory allocations need to be made.
+//
+// Each value is stored with a timestamp, so that values older than some time can be expired.
+type circularBuffer struct {
+	values []float64
+	times  []int64
+	total  float64
+	// start is the index of the oldest value, and n is the number of values present.
+	start int
+	n     int
+	mutex sync.RWMutex
+}
+
+// bufferStats are statistics about the values in a circularBuffer.
+type bufferStats struct {
+	min, max, median, stdDev float64
+	// oldest and newest are the earliest and latest non-zero timestamps of the values,
+	// or 0 if none have them.
+	oldest, newest int64
+}
+
+// newCircularBuffer allocates memory for a new circularBuffer with the given windowSize
+func newCircularBuffer(windowSize int) *circularBuffer {
+	if windowSize <= 0 {
+		panic("illegal window size")
+	}
+
+	return &circularBuffer{
+		values: make([]float64, windowSize),
+		times:  make([]int64, windowSize),
+	}
+}
+
+// Len returns the number of actual values present in the buffer
+func (buff *circularBuffer) Len() int {
+	buff.mutex.RLock()
+	defer buff.mutex.RUnlock()
+
+	return buff.n
+}
+
+// Cap returns the maximum number of values the buffer holds.
+func (buff *circularBuffer) Cap() int {
+	buff.mutex.RLock()
+	defer buff.mutex.RUnlock()
+
+	return len(buff.values)
+}
+
+// Mean returns the average value of all data points in the backing slice.
+// Because this is a circular buffer, this value can be considered as a moving average
+//
+// NOTE: If there is no data in the buffer, this function will return: Nan
+func (buff *circularBuffer) Mean() float64 {
+	buff.mutex.RLock()
+	defer buff.mutex.RUnlock()
+
+	return buff.total / float64(buff.n)
+}
+
+// AddValue appends a new value onto the backing slice,
+// overriding the oldest existing value if count has reached windowSize
+func (buff *circularBuffer) AddValue(value float64) {
+	buff.AddValueAt(value, 0)
+}
+
+// AddValueAt is like AddValue, but records the value's timestamp
+// (Unix Epoch milliseconds) so it can later be expired.
+func (buff *circularBuffer) AddValueAt(value float64, timestamp int64) {
+	buff.mutex.Lock()
+	defer buff.mutex.Unlock()
+
+	size := len(buff.values)
+	if buff.n < size {
+		i := (buff.start + buff.n) % size
+		buff.values[i] = value
+		buff.times[i] = timestamp
+		buff.total += value
+		buff.n++
+		return
+	}
+
+	// subtract old value and add new value
+	buff.total = buff.total - buff.values[buff.start] + value
+	// record new value where old was
+	buff.values[buff.start] = value
+	buff.times[buff.start] = timestamp
+
+	buff.start++
+	if buff.start >= size {
+		// wrap if needed
+		buff.start = 0
+	}
+}
+
+// ExpireBefore removes values with timestamps before the given one
+// and returns the number removed.
+//
+// Since values are assumed to be added in time order,
+// it stops at the first value which isn't expired.
+func (buff *circularBuffer) ExpireBefore(timestamp int64) int {
+	buff.mutex.Lock()
+	defer buff.mutex.Unlock()
+
+	removed := 0
+	for buff.n > 0 && buff.times[buff.start] < timestamp {
+		buff.total -= buff.values[buff.start]
+		buff.start = (buff.start + 1) % len(buff.values)
+		buff.n--
+		removed++
+	}
+
+	if buff.n == 0 {
+		// don't let rounding errors accumulate
+		buff.total = 0
+		buff.start = 0
+	}
+	return removed
+}
+
+// Resize changes the buffer's windowSize, keeping as many of the newest values as fit.
+func (buff *circularBuffer) Resize(windowSize int) {
+	if windowSize <= 0 {
+		panic("illegal window size")
+	}
+
+	buff.mutex.Lock()
+	defer buff.mutex.Unlock()
+
+	if windowSize == len(buff.values) {
+		return
+	}
+
+	values := make([]float64, windowSize)
+	times := make([]int64, windowSize)
+	n := buff.n
+	if n > windowSize {
+		n = windowSize
+	}
+
+	total := 0.0
+	size := len(buff.values)
+	for i, j := 0, buff.n-n; i < n; i, j = i+1, j+1 {
+		k := (buff.start + j) % size
+		values[i] = buff.values[k]
+		times[i] = buff.times[k]
+		total += values[i]
+	}
+
+	buff.values, buff.times = values, times
+	buff.start, buff.n, buff.total = 0, n, total
+}
+
+// Stats returns statistics about the values in the buffer.
+//
+// NOTE: If there is no data in the buffer, the values are all 0.
+func (buff *circularBuffer) Stats() bufferStats {
+	buff.mutex.RLock()
+	defer buff.mutex.RUnlock()
+
+	var stats bufferStats
+	if buff.n == 0 {
+		return stats
+	}
+
+	sorted := make([]float64, buff.n)
+	size := len(buff.values)
+	for i := 0; i < buff.n; i++ {
+		k := (buff.start + i) % size
+		sorted[i] = buff.values[k]
+
+		if ts := buff.times[k]; ts != 0 {
+			if stats.oldest == 0 || ts < stats.oldest {
+				stats.oldest = ts
+			}
+			if ts > stats.newest {
+				stats.newest = ts
+			}
+		}
+	}
+	sort.Float64s(sorted)
+
+	stats.min, stats.max = sorted[0], sorted[buff.n-1]
+	if mid := buff.n / 2; buff.n%2 == 1 {
+		stats.median = sorted[mid]
+	} else {
+		stats.median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	mean := buff.total / float64(buff.n)
+	sumSq := 0.0
+	for _, v := range sorted {
+		sumSq += (v - mean) * (v - mean)
+	}
+	stats.stdDev = math.Sqrt(sumSq / float64(buff.n))
+
+	return stats
+}
+
+// History returns a copy of the buffer's contents
+// from which it can be exactly reconstructed with newCircularBufferFromHistory.
+func (buff *circularBuffer) History() RSSIHistory {
+	buff.mutex.RLock()
+	defer buff.mutex.RUnlock()
+
+	size := len(buff.values)
+	h := RSSIHistory{
+		WindowSize: size,
+		Index:      buff.start,
+		Values:     make([]float64, buff.n),
+		Timestamps: make([]int64, buff.n),
+	}
+	for i := 0; i < buff.n; i++ {
+		k := (buff.start + i) % size
+		h.Values[i] = buff.values[k]
+		h.Timestamps[i] = buff.times[k]
+	}
+	return h
+}
+
+// newCircularBufferFromHistory returns a circularBuffer with the contents of the history,
+// or an error if the history is inconsistent.
+func newCircularBufferFromHistory(h RSSIHistory) (*circularBuffer, error) {
+	switch {
+	case h.WindowSize <= 0:
+		return nil, errors.Errorf("invalid window size %d", h.WindowSize)
+	case len(h.Values) != len(h.Timestamps):
+		return nil, errors.Errorf("history has %d values but %d timestamps", len(h.Values), len(h.Timestamps))
+	case len(h.Values) > h.WindowSize:
+		return nil, errors.Errorf("history has %d values, but its window size is %d", len(h.Values), h.WindowSize)
+	case h.Index < 0 || h.Index >= h.WindowSize:
+		return nil, errors.Errorf("history index %d is out of range for window size %d", h.Index, h.WindowSize)
+	}
+
+	buff := newCircularBuffer(h.WindowSize)
+	buff.start = h.Index
+	buff.n = len(h.Values)
+	for i, v := range h.Values {
+		k := (h.Index + i) % h.WindowSize
+		buff.values[k] = v
+		buff.times[k] = h.Timestamps[i]
+		buff.total += v
+	}
+	return buff, nil
+}
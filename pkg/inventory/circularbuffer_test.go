@@ -151,3 +151,107 @@ func TestCircularBuffer_Wrap(t *testing.T) {
 	}
 	assertBufferSize(t, buff, windowSize)
 }
+
+func TestCircularBuffer_ExpireBefore(t *testing.T) {
+	buff := newCircularBuffer(4)
+	for i := 1; i <= 6; i++ {
+		buff.AddValueAt(float64(i), int64(i*100))
+	}
+	// holds 3, 4, 5, 6
+	assert.Equal(t, 0, buff.ExpireBefore(300))
+	assert.Equal(t, 2, buff.ExpireBefore(450))
+	assertBufferSize(t, buff, 2)
+	assert.Equal(t, 5.5, buff.Mean())
+
+	buff.AddValueAt(7, 700)
+	assertBufferSize(t, buff, 3)
+	assert.Equal(t, 6.0, buff.Mean())
+
+	assert.Equal(t, 3, buff.ExpireBefore(1000))
+	assertBufferSize(t, buff, 0)
+	assert.True(t, math.IsNaN(buff.Mean()))
+
+	buff.AddValueAt(10, 1000)
+	assert.Equal(t, 10.0, buff.Mean())
+}
+
+func TestCircularBuffer_Resize(t *testing.T) {
+	buff := newCircularBuffer(3)
+	for i := 1; i <= 5; i++ {
+		buff.AddValueAt(float64(i), int64(i))
+	}
+
+	// grow: keeps 3, 4, 5 and makes room for more
+	buff.Resize(5)
+	assert.Equal(t, 5, buff.Cap())
+	assertBufferSize(t, buff, 3)
+	buff.AddValueAt(6, 6)
+	buff.AddValueAt(7, 7)
+	assertBufferSize(t, buff, 5)
+	assert.Equal(t, 5.0, buff.Mean())
+
+	// shrink: keeps the newest values
+	buff.Resize(2)
+	assertBufferSize(t, buff, 2)
+	assert.Equal(t, 6.5, buff.Mean())
+	assert.Equal(t, 1, buff.ExpireBefore(7))
+	assert.Equal(t, 7.0, buff.Mean())
+}
+
+func TestCircularBuffer_Stats(t *testing.T) {
+	buff := newCircularBuffer(5)
+	assert.Equal(t, bufferStats{}, buff.Stats())
+
+	for i, v := range []float64{-60, -50, -70, -40, -99, -55, -65} {
+		buff.AddValueAt(v, int64(1000+i*250))
+	}
+
+	// holds -70, -40, -99, -55, -65
+	stats := buff.Stats()
+	assert.Equal(t, -99.0, stats.min)
+	assert.Equal(t, -40.0, stats.max)
+	assert.Equal(t, -65.0, stats.median)
+	assert.InDelta(t, 19.51, stats.stdDev, 0.01)
+	assert.Equal(t, int64(1500), stats.oldest)
+	assert.Equal(t, int64(2500), stats.newest)
+
+	buff.Resize(4)
+	assert.Equal(t, -60.0, buff.Stats().median)
+}
+
+func TestCircularBuffer_History(t *testing.T) {
+	buff := newCircularBuffer(4)
+	for i := 1; i <= 6; i++ {
+		buff.AddValueAt(float64(i), int64(i*10))
+	}
+
+	h := buff.History()
+	assert.Equal(t, RSSIHistory{
+		WindowSize: 4,
+		Index:      2,
+		Values:     []float64{3, 4, 5, 6},
+		Timestamps: []int64{30, 40, 50, 60},
+	}, h)
+
+	restored, err := newCircularBufferFromHistory(h)
+	assert.NoError(t, err)
+	assert.Equal(t, buff.values, restored.values)
+	assert.Equal(t, buff.times, restored.times)
+	assert.Equal(t, buff.Mean(), restored.Mean())
+
+	// they continue to behave the same
+	buff.AddValueAt(7, 70)
+	restored.AddValueAt(7, 70)
+	assert.Equal(t, buff.History(), restored.History())
+
+	for _, bad := range []RSSIHistory{
+		{WindowSize: 0},
+		{WindowSize: 2, Values: []float64{1}},
+		{WindowSize: 1, Values: []float64{1, 2}, Timestamps: []int64{1, 2}},
+		{WindowSize: 2, Index: 2},
+		{WindowSize: 2, Index: -1},
+	} {
+		_, err := newCircularBufferFromHistory(bad)
+		assert.Errorf(t, err, "expected an error for %+v", bad)
+	}
+}
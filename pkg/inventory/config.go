@@ -0,0 +1,371 @@
+//
+// Copyright (C) 2020 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"fmt"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+// ApplicationSettings is a struct that defines the ApplicationSettings section of the
+// configuration.toml file.
+type ApplicationSettings struct {
+	MobilityProfileThreshold     float64
+	MobilityProfileHoldoffMillis float64
+	MobilityProfileSlope         float64
+
+	DeviceServiceName  string
+	DeviceServiceURL   string
+	MetadataServiceURL string
+
+	MetadataSyncIntervalSeconds uint
+
+	DepartedThresholdSeconds     uint
+	DepartedCheckIntervalSeconds uint
+	AgeOutHours                  uint
+
+	AdjustLastReadOnByOrigin bool
+
+	ReaderOfflineThresholdSeconds     uint
+	SuppressDepartedForOfflineReaders bool
+
+	AntennaPositions              string
+	PositionUpdateThresholdMeters float64
+	PositionWindowSeconds         uint
+
+	RSSIWindowSize    uint
+	RSSIWindowSeconds uint
+
+	PersistRSSIHistory bool
+	SnapshotBackups    uint
+	SnapshotStore      string
+
+	ProcessorShards uint
+	ShardQueueSize  uint
+
+	PublishEventsToMessageBus bool
+
+	SummaryIntervalSeconds uint
+	SummaryIncludeEPCs     bool
+	SummaryChunkSize       uint
+
+	SiteName string
+
+	MQTTBrokerURL     string
+	MQTTClientID      string
+	MQTTUsername      string
+	MQTTPassword      string
+	MQTTTopic         string
+	MQTTTagStateTopic string
+	MQTTQoS           uint
+	MQTTCAFile        string
+	MQTTCertFile      string
+	MQTTKeyFile       string
+	MQTTSkipVerify    bool
+
+	WebhookMaxAttempts           uint
+	WebhookInitialBackoffSeconds uint
+	WebhookMaxBackoffSeconds     uint
+	WebhookTimeoutSeconds        uint
+	WebhookAllowedHosts          string
+}
+
+// WriteableConfig is a struct representation of the Writeable section of the configuration.toml file.
+type WriteableConfig struct {
+	LogLevel string
+}
+
+// ConsulConfig is the struct representation of all of the sections from the configuration.toml
+// that we are interested in syncing with Consul.
+type ConsulConfig struct {
+	Writable            WriteableConfig
+	ApplicationSettings ApplicationSettings
+	Aliases             map[string]string
+}
+
+var (
+	// ErrUnexpectedConfigItems is returned when the input configuration map has extra keys
+	// and values that are left over after parsing is complete
+	ErrUnexpectedConfigItems = errors.New("unexpected config items")
+	// ErrMissingRequiredKey is returned when we are unable to parse the value for a config key
+	ErrMissingRequiredKey = errors.New("missing required key")
+	// ErrOutOfRange is returned if a config value is syntactically valid for its type,
+	// but otherwise outside of the acceptable range of valid values.
+	ErrOutOfRange = errors.New("config value out of range")
+)
+
+// NewConsulConfig returns a new ConsulConfig instance with default values.
+func NewConsulConfig() ConsulConfig {
+	return ConsulConfig{
+		Aliases: map[string]string{},
+		Writable: WriteableConfig{
+			LogLevel: "INFO",
+		},
+		ApplicationSettings: ApplicationSettings{
+			MobilityProfileThreshold:     6,
+			MobilityProfileHoldoffMillis: 500,
+			MobilityProfileSlope:         -0.008,
+			DeviceServiceName:            "edgex-device-llrp",
+			DeviceServiceURL:             "http://edgex-device-llrp:49989/",
+			MetadataServiceURL:           "http://edgex-core-metadata:48081/",
+			MetadataSyncIntervalSeconds:  300,
+			DepartedThresholdSeconds:     600,
+			DepartedCheckIntervalSeconds: 30,
+			AgeOutHours:                  336,
+			AdjustLastReadOnByOrigin:     true,
+
+			ReaderOfflineThresholdSeconds:     0,
+			SuppressDepartedForOfflineReaders: false,
+
+			AntennaPositions:              "",
+			PositionUpdateThresholdMeters: 1,
+			PositionWindowSeconds:         10,
+
+			RSSIWindowSize:    20,
+			RSSIWindowSeconds: 0,
+
+			PersistRSSIHistory: false,
+			SnapshotBackups:    3,
+			SnapshotStore:      FileSnapshotStoreType,
+
+			ProcessorShards: 1,
+			ShardQueueSize:  100,
+
+			PublishEventsToMessageBus: false,
+
+			SummaryIntervalSeconds: 0,
+			SummaryIncludeEPCs:     false,
+			SummaryChunkSize:       1000,
+
+			SiteName: "",
+
+			MQTTBrokerURL:     "",
+			MQTTClientID:      "app-rfid-llrp-inventory",
+			MQTTTopic:         "rfid/{site}/{eventType}/{alias}",
+			MQTTTagStateTopic: "",
+			MQTTQoS:           0,
+
+			WebhookMaxAttempts:           5,
+			WebhookInitialBackoffSeconds: 1,
+			WebhookMaxBackoffSeconds:     60,
+			WebhookTimeoutSeconds:        10,
+			WebhookAllowedHosts:          "",
+		},
+	}
+}
+
+// Validate returns nil if the ApplicationSettings are valid,
+// or the first validation error it encounters.
+func (as ApplicationSettings) Validate() error {
+	if as.DepartedThresholdSeconds == 0 {
+		return errors.Wrap(ErrOutOfRange, "DepartedThresholdSeconds must be >0")
+	}
+
+	if as.DepartedCheckIntervalSeconds == 0 {
+		return errors.Wrap(ErrOutOfRange, "DepartedCheckIntervalSeconds must be >0")
+	}
+
+	if as.AgeOutHours == 0 {
+		return errors.Wrap(ErrOutOfRange, "AgeOutHours must be >0")
+	}
+
+	if as.PositionUpdateThresholdMeters < 0 {
+		return errors.Wrap(ErrOutOfRange, "PositionUpdateThresholdMeters must be >=0")
+	}
+
+	if as.PositionWindowSeconds == 0 {
+		return errors.Wrap(ErrOutOfRange, "PositionWindowSeconds must be >0")
+	}
+
+	if as.RSSIWindowSize == 0 {
+		return errors.Wrap(ErrOutOfRange, "RSSIWindowSize must be >0")
+	}
+
+	switch strings.ToLower(as.SnapshotStore) {
+	case FileSnapshotStoreType, KVSnapshotStoreType, JournalSnapshotStoreType:
+	default:
+		return errors.Wrapf(ErrOutOfRange, "SnapshotStore must be %q, %q, or %q",
+			FileSnapshotStoreType, KVSnapshotStoreType, JournalSnapshotStoreType)
+	}
+
+	if as.ProcessorShards == 0 {
+		return errors.Wrap(ErrOutOfRange, "ProcessorShards must be >0")
+	}
+
+	if as.ShardQueueSize == 0 {
+		return errors.Wrap(ErrOutOfRange, "ShardQueueSize must be >0")
+	}
+
+	if as.SummaryIntervalSeconds != 0 && as.SummaryChunkSize == 0 {
+		return errors.Wrap(ErrOutOfRange, "SummaryChunkSize must be >0 when SummaryIntervalSeconds is >0")
+	}
+
+	if as.MQTTQoS > 2 {
+		return errors.Wrap(ErrOutOfRange, "MQTTQoS must be 0, 1, or 2")
+	}
+
+	if as.WebhookMaxAttempts == 0 {
+		return errors.Wrap(ErrOutOfRange, "WebhookMaxAttempts must be >0")
+	}
+
+	if as.WebhookInitialBackoffSeconds == 0 {
+		return errors.Wrap(ErrOutOfRange, "WebhookInitialBackoffSeconds must be >0")
+	}
+
+	if as.WebhookMaxBackoffSeconds < as.WebhookInitialBackoffSeconds {
+		return errors.Wrap(ErrOutOfRange, "WebhookMaxBackoffSeconds must be >=WebhookInitialBackoffSeconds")
+	}
+
+	if as.WebhookTimeoutSeconds == 0 {
+		return errors.Wrap(ErrOutOfRange, "WebhookTimeoutSeconds must be >0")
+	}
+
+	if _, err := ParseAntennaPositions(as.AntennaPositions); err != nil {
+		return errors.Wrap(ErrOutOfRange, err.Error())
+	}
+
+	return nil
+}
+
+// ParseConsulConfig returns a new ConsulConfig
+// with settings parsed from the given map,
+// merged with default settings for missing value.
+//
+// It returns a parsing error if a given key's value cannot be parsed,
+// an error wrapping ErrMissingRequiredKey if a required key is missing,
+// and an error wrapping ErrUnexpectedConfigItems if the map has unknown config keys.
+//
+// If the map is missing a non-required key,
+// it logs an INFO message unless the given logging client is nil.
+// todo: switch to using SDK's custom config capability when upgrade to Ireland
+func ParseConsulConfig(lc logger.LoggingClient, configMap map[string]string) (ConsulConfig, error) {
+	cfg := NewConsulConfig()
+	settings := &cfg.ApplicationSettings
+
+	type confItem struct {
+		target   interface{} // pointer to the variable to set
+		required bool        // if true, return an error if not in the map
+	}
+
+	used := make(map[string]bool, len(configMap))
+	for key, ci := range map[string]confItem{
+		"AdjustLastReadOnByOrigin":     {target: &settings.AdjustLastReadOnByOrigin},
+		"DepartedThresholdSeconds":     {target: &settings.DepartedThresholdSeconds},
+		"DepartedCheckIntervalSeconds": {target: &settings.DepartedCheckIntervalSeconds},
+		"AgeOutHours":                  {target: &settings.AgeOutHours},
+		"MobilityProfileThreshold":     {target: &settings.MobilityProfileThreshold},
+		"MobilityProfileHoldoffMillis": {target: &settings.MobilityProfileHoldoffMillis},
+		"MobilityProfileSlope":         {target: &settings.MobilityProfileSlope},
+		"DeviceServiceName":            {target: &settings.DeviceServiceName},
+		"DeviceServiceURL":             {target: &settings.DeviceServiceURL},
+		"MetadataServiceURL":           {target: &settings.MetadataServiceURL},
+		"MetadataSyncIntervalSeconds":  {target: &settings.MetadataSyncIntervalSeconds},
+
+		"ReaderOfflineThresholdSeconds":     {target: &settings.ReaderOfflineThresholdSeconds},
+		"SuppressDepartedForOfflineReaders": {target: &settings.SuppressDepartedForOfflineReaders},
+
+		"AntennaPositions":              {target: &settings.AntennaPositions},
+		"PositionUpdateThresholdMeters": {target: &settings.PositionUpdateThresholdMeters},
+		"PositionWindowSeconds":         {target: &settings.PositionWindowSeconds},
+
+		"RSSIWindowSize":    {target: &settings.RSSIWindowSize},
+		"RSSIWindowSeconds": {target: &settings.RSSIWindowSeconds},
+
+		"PersistRSSIHistory": {target: &settings.PersistRSSIHistory},
+		"SnapshotBackups":    {target: &settings.SnapshotBackups},
+		"SnapshotStore":      {target: &settings.SnapshotStore},
+
+		"ProcessorShards": {target: &settings.ProcessorShards},
+		"ShardQueueSize":  {target: &settings.ShardQueueSize},
+
+		"PublishEventsToMessageBus": {target: &settings.PublishEventsToMessageBus},
+
+		"SummaryIntervalSeconds": {target: &settings.SummaryIntervalSeconds},
+		"SummaryIncludeEPCs":     {target: &settings.SummaryIncludeEPCs},
+		"SummaryChunkSize":       {target: &settings.SummaryChunkSize},
+
+		"SiteName": {target: &settings.SiteName},
+
+		"MQTTBrokerURL":     {target: &settings.MQTTBrokerURL},
+		"MQTTClientID":      {target: &settings.MQTTClientID},
+		"MQTTUsername":      {target: &settings.MQTTUsername},
+		"MQTTPassword":      {target: &settings.MQTTPassword},
+		"MQTTTopic":         {target: &settings.MQTTTopic},
+		"MQTTTagStateTopic": {target: &settings.MQTTTagStateTopic},
+		"MQTTQoS":           {target: &settings.MQTTQoS},
+		"MQTTCAFile":        {target: &settings.MQTTCAFile},
+		"MQTTCertFile":      {target: &settings.MQTTCertFile},
+		"MQTTKeyFile":       {target: &settings.MQTTKeyFile},
+		"MQTTSkipVerify":    {target: &settings.MQTTSkipVerify},
+
+		"WebhookMaxAttempts":           {target: &settings.WebhookMaxAttempts},
+		"WebhookInitialBackoffSeconds": {target: &settings.WebhookInitialBackoffSeconds},
+		"WebhookMaxBackoffSeconds":     {target: &settings.WebhookMaxBackoffSeconds},
+		"WebhookTimeoutSeconds":        {target: &settings.WebhookTimeoutSeconds},
+		"WebhookAllowedHosts":          {target: &settings.WebhookAllowedHosts},
+	} {
+		var err error
+
+		val, ok := configMap[key]
+		if !ok {
+			if ci.required {
+				return cfg, errors.Wrapf(ErrMissingRequiredKey, "no value for %q", key)
+			}
+
+			if lc != nil {
+				lc.Info("Using default value for config item.",
+					"key", key, "value", ci.target)
+			}
+			continue
+		}
+
+		switch target := ci.target.(type) {
+		default:
+			panic(fmt.Sprintf("unhandled type for config item %q: %T",
+				key, ci.target))
+
+		case *string:
+			*target = val
+		case *float64:
+			*target, err = strconv.ParseFloat(val, 64)
+		case *bool:
+			*target, err = strconv.ParseBool(val)
+		case *int:
+			*target, err = strconv.Atoi(val)
+
+		case *uint:
+			u, perr := strconv.ParseUint(val, 10, 0)
+			err = perr
+			*target = uint(u)
+		}
+
+		if err != nil {
+			return cfg, errors.Wrapf(err, "failed to parse config item %q, %q", key, val)
+		}
+
+		used[key] = true
+	}
+
+	if err := settings.Validate(); err != nil {
+		return cfg, err
+	}
+
+	var missed []string
+	for key, val := range configMap {
+		if !used[key] {
+			missed = append(missed, fmt.Sprintf("%q: %q", key, val))
+		}
+	}
+
+	if len(missed) != 0 {
+		return cfg, errors.Wrapf(ErrUnexpectedConfigItems, "unused config items: %s", missed)
+	}
+
+	return cfg, nil
+}
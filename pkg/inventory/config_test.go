@@ -92,6 +92,84 @@ func TestParseConsulConfig(t *testing.T) {
 		{key: "DeviceServiceURL", val: "http://testing:49989/", exp: "http://testing:49989/"},
 		{key: "DeviceServiceURL", val: "", exp: ""},
 		{key: "MetadataServiceURL", val: "", exp: ""},
+
+		{key: "MetadataSyncIntervalSeconds", val: "0", exp: uint(0)},
+		{key: "MetadataSyncIntervalSeconds", val: "60", exp: uint(60)},
+		{key: "MetadataSyncIntervalSeconds", val: "-60", err: strconv.ErrSyntax},
+
+		{key: "ReaderOfflineThresholdSeconds", val: "0", exp: uint(0)},
+		{key: "ReaderOfflineThresholdSeconds", val: "120", exp: uint(120)},
+		{key: "ReaderOfflineThresholdSeconds", val: "-120", err: strconv.ErrSyntax},
+
+		{key: "SuppressDepartedForOfflineReaders", val: "true", exp: true},
+		{key: "SuppressDepartedForOfflineReaders", val: "false", exp: false},
+		{key: "SuppressDepartedForOfflineReaders", val: "yes", err: strconv.ErrSyntax},
+
+		{key: "AntennaPositions", val: "", exp: ""},
+		{key: "AntennaPositions", val: "Reader-1_1=0,0;Reader-1_2=5.5,0,2@F1", exp: "Reader-1_1=0,0;Reader-1_2=5.5,0,2@F1"},
+		{key: "AntennaPositions", val: "Reader-1_1=0", err: ErrOutOfRange},
+		{key: "AntennaPositions", val: "Reader-1_1=a,b", err: ErrOutOfRange},
+		{key: "AntennaPositions", val: "Reader-1_1=0,0;Reader-1_1=1,1", err: ErrOutOfRange},
+
+		{key: "PositionUpdateThresholdMeters", val: "0", exp: 0.0},
+		{key: "PositionUpdateThresholdMeters", val: "2.5", exp: 2.5},
+		{key: "PositionUpdateThresholdMeters", val: "-1", err: ErrOutOfRange},
+
+		{key: "PositionWindowSeconds", val: "30", exp: uint(30)},
+		{key: "PositionWindowSeconds", val: "0", err: ErrOutOfRange},
+
+		{key: "RSSIWindowSize", val: "50", exp: uint(50)},
+		{key: "RSSIWindowSize", val: "0", err: ErrOutOfRange},
+		{key: "RSSIWindowSize", val: "-1", err: strconv.ErrSyntax},
+
+		{key: "RSSIWindowSeconds", val: "0", exp: uint(0)},
+		{key: "RSSIWindowSeconds", val: "30", exp: uint(30)},
+
+		{key: "PersistRSSIHistory", val: "true", exp: true},
+		{key: "PersistRSSIHistory", val: "false", exp: false},
+		{key: "PersistRSSIHistory", val: "1x", err: strconv.ErrSyntax},
+
+		{key: "SnapshotBackups", val: "0", exp: uint(0)},
+		{key: "SnapshotBackups", val: "5", exp: uint(5)},
+		{key: "SnapshotBackups", val: "-1", err: strconv.ErrSyntax},
+
+		{key: "SnapshotStore", val: "file", exp: "file"},
+		{key: "SnapshotStore", val: "KV", exp: "KV"},
+		{key: "SnapshotStore", val: "journal", exp: "journal"},
+		{key: "SnapshotStore", val: "bolt", err: ErrOutOfRange},
+
+		{key: "ProcessorShards", val: "8", exp: uint(8)},
+		{key: "ProcessorShards", val: "0", err: ErrOutOfRange},
+		{key: "ShardQueueSize", val: "1000", exp: uint(1000)},
+		{key: "ShardQueueSize", val: "0", err: ErrOutOfRange},
+
+		{key: "PublishEventsToMessageBus", val: "true", exp: true},
+		{key: "PublishEventsToMessageBus", val: "sure", err: strconv.ErrSyntax},
+
+		{key: "SummaryIntervalSeconds", val: "0", exp: uint(0)},
+		{key: "SummaryIntervalSeconds", val: "300", exp: uint(300)},
+		{key: "SummaryIncludeEPCs", val: "true", exp: true},
+		{key: "SummaryChunkSize", val: "500", exp: uint(500)},
+		// the chunk size only matters if summaries are sent
+		{key: "SummaryChunkSize", val: "0", exp: uint(0)},
+
+		{key: "SiteName", val: "Store1", exp: "Store1"},
+		{key: "MQTTBrokerURL", val: "tcp://localhost:1883", exp: "tcp://localhost:1883"},
+		{key: "MQTTTopic", val: "rfid/{eventType}", exp: "rfid/{eventType}"},
+		{key: "MQTTQoS", val: "2", exp: uint(2)},
+		{key: "MQTTQoS", val: "3", err: ErrOutOfRange},
+		{key: "MQTTSkipVerify", val: "true", exp: true},
+		{key: "MQTTSkipVerify", val: "no", err: strconv.ErrSyntax},
+
+		{key: "WebhookMaxAttempts", val: "10", exp: uint(10)},
+		{key: "WebhookMaxAttempts", val: "0", err: ErrOutOfRange},
+		{key: "WebhookInitialBackoffSeconds", val: "5", exp: uint(5)},
+		{key: "WebhookInitialBackoffSeconds", val: "0", err: ErrOutOfRange},
+		{key: "WebhookInitialBackoffSeconds", val: "120", err: ErrOutOfRange},
+		{key: "WebhookMaxBackoffSeconds", val: "300", exp: uint(300)},
+		{key: "WebhookTimeoutSeconds", val: "30", exp: uint(30)},
+		{key: "WebhookTimeoutSeconds", val: "0", err: ErrOutOfRange},
+		{key: "WebhookAllowedHosts", val: "hooks.example.com, 10.0.0.5", exp: "hooks.example.com, 10.0.0.5"},
 	}
 
 	rt := reflect.TypeOf(ApplicationSettings{})
@@ -120,6 +198,14 @@ func TestParseConsulConfig(t *testing.T) {
 
 }
 
+func TestSummaryChunkSize(t *testing.T) {
+	_, err := ParseConsulConfig(getTestingLogger(), map[string]string{
+		"SummaryIntervalSeconds": "300",
+		"SummaryChunkSize":       "0",
+	})
+	assert.True(t, errors.Is(err, ErrOutOfRange), "expected %v, but got %+v", ErrOutOfRange, err)
+}
+
 func TestQuickCheckStr(t *testing.T) {
 
 	// quick.Check that we return an error (and don't panic) on arbitrary strings.
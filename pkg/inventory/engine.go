@@ -0,0 +1,429 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"context"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"fmt"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+const (
+	ageOutInterval = 1 * time.Hour
+	eventChSz      = 100
+
+	// persistInterval is how often the inventory is persisted, if it changed.
+	// Collecting changes from the processor waits for every shard to catch up,
+	// so doing it for every batch of events would serialize the shards.
+	persistInterval = 10 * time.Second
+)
+
+// ErrEngineStopped is returned by an Engine's methods once its Run has returned.
+var ErrEngineStopped = errors.New("inventory engine stopped")
+
+// EventHandler handles a batch of events, in the order they occurred.
+//
+// An Engine publishes its events to its Subscribers
+// from a goroutine separate from the one processing reads,
+// so a slow synchronous handler delays other handlers, but not processing.
+type EventHandler func(ctx context.Context, events []Event)
+
+// Engine runs a tag inventory, turning reads into inventory events.
+//
+// It wraps a ShardedProcessor with the scheduled tasks around it:
+// checking for departed tags and offline Readers, aging out old tags,
+// summarizing the inventory, and persisting it to a SnapshotStore.
+// Unlike the processors, its methods are safe to call from any goroutine,
+// but they only make progress while Run is running.
+type Engine struct {
+	lc        logger.LoggingClient
+	config    ConsulConfig
+	store     SnapshotStore
+	subs      []subscription
+	statuses  func() []llrp.ReaderStatus
+	processor *ShardedProcessor
+
+	subscribers *Subscribers
+
+	reports      chan submission
+	readerEvents chan []Event
+	configs      chan ConsulConfig
+	snapshotReqs chan chan []StaticTag
+	done         chan struct{}
+}
+
+// submission is a set of reads waiting for the Engine's Run loop to submit them.
+type submission struct {
+	nr    *NormalizedReport
+	info  ReportInfo
+	reads []TagRead
+}
+
+// subscription is a handler waiting for NewEngine to subscribe it.
+type subscription struct {
+	handler EventHandler
+	opts    []SubscribeOption
+}
+
+// Option configures an Engine.
+type Option func(e *Engine)
+
+// WithLogger sets the Engine's logger. By default, it doesn't log.
+func WithLogger(lc logger.LoggingClient) Option {
+	return func(e *Engine) { e.lc = lc }
+}
+
+// WithConfig sets the Engine's initial configuration,
+// including the number of shards it uses to process reads.
+// By default, it uses NewConsulConfig.
+func WithConfig(cfg ConsulConfig) Option {
+	return func(e *Engine) { e.config = cfg }
+}
+
+// WithSnapshotStore sets the store from which the Engine restores its inventory
+// and to which it persists the inventory as it changes.
+// The Engine doesn't close the store.
+// By default, the inventory isn't persisted.
+func WithSnapshotStore(store SnapshotStore) Option {
+	return func(e *Engine) { e.store = store }
+}
+
+// WithEventHandler subscribes a synchronous handler to all the Engine's events.
+func WithEventHandler(h EventHandler) Option {
+	return WithSubscriber(h)
+}
+
+// WithSubscriber subscribes a handler to the Engine's events, as Engine.Subscribe does.
+func WithSubscriber(h EventHandler, opts ...SubscribeOption) Option {
+	return func(e *Engine) { e.subs = append(e.subs, subscription{h, opts}) }
+}
+
+// WithReaderStatuses sets the function the Engine uses to check Readers' health
+// before aggregating departed tags, so it can report Readers which go offline.
+// By default, it doesn't check Readers.
+func WithReaderStatuses(statuses func() []llrp.ReaderStatus) Option {
+	return func(e *Engine) { e.statuses = statuses }
+}
+
+// NewEngine returns a new Engine configured by the options,
+// with its inventory restored from its SnapshotStore, if it has one.
+// It returns an error if its configuration isn't valid,
+// but only logs a warning if it can't restore the inventory.
+func NewEngine(opts ...Option) (*Engine, error) {
+	e := &Engine{
+		lc:           logger.NewMockClient(),
+		config:       NewConsulConfig(),
+		reports:      make(chan submission),
+		readerEvents: make(chan []Event),
+		configs:      make(chan ConsulConfig),
+		snapshotReqs: make(chan chan []StaticTag),
+		done:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	as := e.config.ApplicationSettings
+	if err := as.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid inventory configuration")
+	}
+
+	e.subscribers = NewSubscribers(e.lc)
+	for _, sub := range e.subs {
+		e.subscribers.Subscribe(sub.handler, sub.opts...)
+	}
+	e.subs = nil
+
+	var restored []StaticTag
+	if e.store != nil {
+		var err error
+		if restored, err = e.store.Load(); err != nil {
+			e.lc.Warn("Failed to load inventory snapshot.", "error", err.Error())
+		}
+	}
+
+	e.processor = NewShardedProcessor(e.lc, e.config, restored,
+		int(as.ProcessorShards), int(as.ShardQueueSize))
+	if len(restored) > 0 {
+		e.lc.Info(fmt.Sprintf("Restored %d tags from cache.", len(restored)))
+	}
+	e.lc.Info(fmt.Sprintf("Processing tags with %d shard(s).", as.ProcessorShards))
+	return e, nil
+}
+
+// Run processes reads and runs the Engine's scheduled tasks until ctx is cancelled.
+// It then processes any remaining reads, publishes their events,
+// persists the inventory, waits for asynchronous subscribers to finish,
+// and returns ctx's error.
+//
+// It must only be called once,
+// and afterwards, the Engine's methods return ErrEngineStopped.
+func (e *Engine) Run(ctx context.Context) error {
+	departedCheckSeconds := e.config.ApplicationSettings.DepartedCheckIntervalSeconds
+	aggregateDepartedTicker := time.NewTicker(time.Duration(departedCheckSeconds) * time.Second)
+	ageoutTicker := time.NewTicker(ageOutInterval)
+	persistTicker := time.NewTicker(persistInterval)
+	// unsaved is true if the inventory changed since it was last persisted.
+	var unsaved bool
+	summarySeconds := e.config.ApplicationSettings.SummaryIntervalSeconds
+	summaryTicker, summaryC := newOptionalTicker(summarySeconds)
+	eventCh := make(chan []Event, eventChSz)
+
+	defer func() {
+		aggregateDepartedTicker.Stop()
+		ageoutTicker.Stop()
+		persistTicker.Stop()
+		if summaryTicker != nil {
+			summaryTicker.Stop()
+		}
+		e.processor.Close()
+		close(e.done)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for events := range eventCh {
+			e.subscribers.Publish(ctx, events)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.processor.Flush()
+			if events := e.processor.TakeEvents(); len(events) > 0 {
+				eventCh <- events
+			}
+			close(eventCh)
+			e.persistSnapshot()
+			wg.Wait()
+			e.subscribers.Close()
+			return ctx.Err()
+
+		case s := <-e.reports:
+			if s.nr != nil {
+				e.processor.Submit(s.nr, s.info)
+			} else {
+				e.processor.SubmitReads(s.reads)
+			}
+
+		case <-e.processor.EventsReady():
+			if events := e.processor.TakeEvents(); len(events) > 0 {
+				unsaved = true // only persist when there are inventory events
+				eventCh <- events
+			}
+
+		case events := <-e.readerEvents:
+			eventCh <- events
+
+		case t := <-aggregateDepartedTicker.C:
+			e.lc.Debug("Running AggregateDeparted.", "time", fmt.Sprintf("%v", t))
+
+			// Check Readers first, so AggregateDeparted knows which are offline.
+			if e.statuses != nil {
+				if events := e.processor.CheckReaders(e.statuses()); len(events) > 0 {
+					eventCh <- events
+				}
+			}
+
+			if events := e.processor.AggregateDeparted(); len(events) > 0 {
+				unsaved = true
+				eventCh <- events
+			}
+
+		case t := <-ageoutTicker.C:
+			e.lc.Debug("Running AgeOut.", "time", fmt.Sprintf("%v", t))
+			if e.processor.AgeOut() > 0 {
+				unsaved = true
+			}
+
+		case <-persistTicker.C:
+			if unsaved {
+				e.persistSnapshot()
+				unsaved = false
+			}
+
+		case <-summaryC:
+			as := e.config.ApplicationSettings
+			snapshot := e.processor.Snapshot()
+			e.lc.Debug("Summarizing inventory.", "tags", len(snapshot))
+			eventCh <- NewInventorySummary(snapshot, as.SummaryIncludeEPCs, int(as.SummaryChunkSize), UnixMilliNow())
+
+		case cfg := <-e.configs:
+			e.processor.UpdateConfig(cfg)
+			as := e.config.ApplicationSettings
+			if cfg.ApplicationSettings.ProcessorShards != as.ProcessorShards ||
+				cfg.ApplicationSettings.ShardQueueSize != as.ShardQueueSize {
+				e.lc.Warn("Changing ProcessorShards or ShardQueueSize requires a restart.")
+			}
+
+			// check if we need to change the ticker interval
+			if departedCheckSeconds != cfg.ApplicationSettings.DepartedCheckIntervalSeconds {
+				aggregateDepartedTicker.Stop()
+				departedCheckSeconds = cfg.ApplicationSettings.DepartedCheckIntervalSeconds
+				aggregateDepartedTicker = time.NewTicker(time.Duration(departedCheckSeconds) * time.Second)
+				e.lc.Info(fmt.Sprintf("Changing aggregate departed check interval to %d seconds.", departedCheckSeconds))
+			}
+
+			if summarySeconds != cfg.ApplicationSettings.SummaryIntervalSeconds {
+				if summaryTicker != nil {
+					summaryTicker.Stop()
+				}
+				summarySeconds = cfg.ApplicationSettings.SummaryIntervalSeconds
+				summaryTicker, summaryC = newOptionalTicker(summarySeconds)
+				e.lc.Info(fmt.Sprintf("Changing inventory summary interval to %d seconds.", summarySeconds))
+			}
+
+			// the shard settings can't change, so keep the original ones
+			cfg.ApplicationSettings.ProcessorShards = as.ProcessorShards
+			cfg.ApplicationSettings.ShardQueueSize = as.ShardQueueSize
+			e.config = cfg
+
+		case result := <-e.snapshotReqs:
+			result <- e.processor.Snapshot()
+		}
+	}
+}
+
+// newOptionalTicker returns a ticker with the given interval and its channel,
+// or, if the interval is 0, no ticker and a nil channel, which never receives.
+func newOptionalTicker(seconds uint) (*time.Ticker, <-chan time.Time) {
+	if seconds == 0 {
+		return nil, nil
+	}
+	t := time.NewTicker(time.Duration(seconds) * time.Second)
+	return t, t.C
+}
+
+// persistSnapshot saves the inventory to the store, if there is one,
+// writing just the processor's changes if the store supports it,
+// and otherwise the full snapshot.
+func (e *Engine) persistSnapshot() {
+	if e.store == nil {
+		return
+	}
+
+	e.lc.Debug("Persisting inventory snapshot.")
+	changed, removed := e.processor.TakeChanges()
+	if inc, ok := e.store.(IncrementalSnapshotStore); ok {
+		err := inc.SaveChanges(changed, removed)
+		if err == nil {
+			e.lc.Debug("Persisted inventory changes.", "changed", len(changed), "removed", len(removed))
+			return
+		}
+		// the changes have been taken, so save everything instead
+		e.lc.Warn("Failed to persist inventory changes; saving the full snapshot.", "error", err.Error())
+	}
+
+	snapshot := e.processor.Snapshot()
+	if err := e.store.Save(snapshot); err != nil {
+		e.lc.Warn("Failed to persist inventory snapshot.", "error", err.Error())
+		return
+	}
+	e.lc.Info("Persisted inventory snapshot.", "tags", len(snapshot))
+}
+
+// Subscribe adds a handler for the Engine's events
+// and returns a function which removes it.
+// By default, the handler is called synchronously for every event;
+// use ForTypes and Async to change that.
+func (e *Engine) Subscribe(h EventHandler, opts ...SubscribeOption) (unsubscribe func()) {
+	return e.subscribers.Subscribe(h, opts...)
+}
+
+// ProcessReport queues a report for processing.
+// It blocks while the processor's queues are full,
+// returning early if ctx is cancelled or the Engine stops.
+//
+// Reports from a given Reader must be normalized and processed in the order they arrive.
+func (e *Engine) ProcessReport(ctx context.Context, nr *NormalizedReport, info ReportInfo) error {
+	return e.submit(ctx, submission{nr: nr, info: info})
+}
+
+// ProcessReads queues reads which didn't come from an LLRP report for processing,
+// as described by TagProcessor.ProcessReads.
+// Like ProcessReport, it blocks while the processor's queues are full.
+func (e *Engine) ProcessReads(ctx context.Context, reads []TagRead) error {
+	return e.submit(ctx, submission{reads: reads})
+}
+
+func (e *Engine) submit(ctx context.Context, s submission) error {
+	select {
+	case e.reports <- s:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.done:
+		return ErrEngineStopped
+	}
+}
+
+// ProcessReaderNotification publishes the events for a Reader's notification
+// in order with the inventory's events.
+func (e *Engine) ProcessReaderNotification(ctx context.Context, device string, data *llrp.ReaderEventNotificationData) error {
+	events := NewReaderEvents(device, data)
+	if len(events) == 0 {
+		return nil
+	}
+
+	select {
+	case e.readerEvents <- events:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.done:
+		return ErrEngineStopped
+	}
+}
+
+// UpdateConfig validates and applies a new configuration.
+// The number of shards and their queue sizes can't be changed after the Engine is created.
+func (e *Engine) UpdateConfig(ctx context.Context, cfg ConsulConfig) error {
+	if err := cfg.ApplicationSettings.Validate(); err != nil {
+		return errors.Wrap(err, "invalid inventory configuration")
+	}
+
+	select {
+	case e.configs <- cfg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.done:
+		return ErrEngineStopped
+	}
+}
+
+// Snapshot returns the current inventory,
+// including the results of all reads queued before it was called.
+// The StaticTags share their StatsMaps with the Engine, so they must not be modified.
+func (e *Engine) Snapshot(ctx context.Context) ([]StaticTag, error) {
+	result := make(chan []StaticTag, 1)
+	select {
+	case e.snapshotReqs <- result:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-e.done:
+		return nil, ErrEngineStopped
+	}
+
+	select {
+	case snapshot := <-result:
+		return snapshot, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Metrics returns the load on the Engine's processor.
+func (e *Engine) Metrics() ProcessorMetrics {
+	return e.processor.Metrics()
+}
@@ -0,0 +1,172 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"context"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startEngine runs a new Engine until the returned function is called,
+// which waits for Run to return.
+// Its events are sent to the returned channel.
+func startEngine(t *testing.T, opts ...Option) (*Engine, <-chan []Event, func()) {
+	t.Helper()
+	events := make(chan []Event, 10)
+	opts = append(opts, WithLogger(getTestingLogger()),
+		WithEventHandler(func(_ context.Context, e []Event) { events <- e }))
+	e, err := NewEngine(opts...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- e.Run(ctx) }()
+
+	return e, events, func() {
+		cancel()
+		assert.Equal(t, context.Canceled, <-stopped)
+	}
+}
+
+func nextEvents(t *testing.T, events <-chan []Event) []Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for events")
+		return nil
+	}
+}
+
+func TestEngine(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.ProcessorShards = 2
+	e, events, stop := startEngine(t, WithConfig(cfg))
+	ctx := context.Background()
+
+	sensor, epc, now := nextSensor(), nextEPC(), time.Now()
+	r := &llrp.ROAccessReport{TagReportData: []llrp.TagReportData{
+		tagReportData(t, epc, defaultAntenna, rssiWeak, now),
+	}}
+	require.NoError(t, e.ProcessReport(ctx, NormalizeReport(r, sensor, nil), reportInfo(sensor, now)))
+
+	arrived := nextEvents(t, events)
+	require.Len(t, arrived, 1)
+	assert.Equal(t, ArrivedType, arrived[0].OfType())
+
+	// reads which didn't come from LLRP are processed the same way
+	other := nextEPC()
+	require.NoError(t, e.ProcessReads(ctx, []TagRead{{EPC: other, DeviceName: sensor}}))
+	require.Len(t, nextEvents(t, events), 1)
+
+	snapshot, err := e.Snapshot(ctx)
+	require.NoError(t, err)
+	assert.Len(t, snapshot, 2)
+
+	// reader events are sent to the same subscribers,
+	// including those which subscribe later
+	readerEvents := make(chan []Event, 1)
+	unsubscribe := e.Subscribe(func(_ context.Context, events []Event) { readerEvents <- events },
+		ForTypes(ReportBufferOverflowType), Async(1))
+	require.NoError(t, e.ProcessReaderNotification(ctx, sensor, &llrp.ReaderEventNotificationData{
+		ReportBufferOverflowErrorEvent: &llrp.ReportBufferOverflowErrorEvent{},
+	}))
+	overflow := nextEvents(t, events)
+	require.Len(t, overflow, 1)
+	assert.Equal(t, ReportBufferOverflowType, overflow[0].OfType())
+	assert.Equal(t, overflow, nextEvents(t, readerEvents))
+	unsubscribe()
+
+	cfg.ApplicationSettings.DepartedThresholdSeconds = 0
+	assert.Error(t, e.UpdateConfig(ctx, cfg))
+
+	stop()
+	assert.Equal(t, ErrEngineStopped, e.ProcessReads(ctx, nil))
+	_, err = e.Snapshot(ctx)
+	assert.Equal(t, ErrEngineStopped, err)
+}
+
+func TestEngine_persistsInventory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "engine")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := NewFileSnapshotStore(getTestingLogger(), filepath.Join(dir, "tags.json"), 0)
+
+	e, events, stop := startEngine(t, WithSnapshotStore(store))
+	antenna := defaultAntenna
+	read := TagRead{EPC: nextEPC(), DeviceName: nextSensor(), Antenna: &antenna}
+	require.NoError(t, e.ProcessReads(context.Background(), []TagRead{read}))
+	nextEvents(t, events)
+	stop()
+
+	// a new engine restores the inventory
+	e, _, stop = startEngine(t, WithSnapshotStore(store))
+	defer stop()
+	snapshot, err := e.Snapshot(context.Background())
+	require.NoError(t, err)
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, read.EPC, snapshot[0].EPC)
+}
+
+// countingStore counts the times it's saved.
+type countingStore struct {
+	SnapshotStore
+	saves int
+}
+
+func (cs *countingStore) Save(tags []StaticTag) error {
+	cs.saves++
+	return cs.SnapshotStore.Save(tags)
+}
+
+func TestEngine_persistsPeriodically(t *testing.T) {
+	dir, err := ioutil.TempDir("", "engine")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := &countingStore{SnapshotStore: NewFileSnapshotStore(getTestingLogger(), filepath.Join(dir, "tags.json"), 0)}
+
+	e, events, stop := startEngine(t, WithSnapshotStore(store))
+	antenna := defaultAntenna
+	sensor := nextSensor()
+	for i := 0; i < 5; i++ {
+		read := TagRead{EPC: nextEPC(), DeviceName: sensor, Antenna: &antenna}
+		require.NoError(t, e.ProcessReads(context.Background(), []TagRead{read}))
+		nextEvents(t, events)
+	}
+
+	// batches of events don't each trigger a save, but stopping does
+	assert.Equal(t, 0, store.saves)
+	stop()
+	assert.Equal(t, 1, store.saves)
+}
+
+func TestEngine_contextCancelled(t *testing.T) {
+	// without Run, nothing makes progress, so the context is the only way out
+	e, err := NewEngine()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, e.ProcessReads(ctx, nil))
+	_, err = e.Snapshot(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestNewEngine_invalidConfig(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.ProcessorShards = 0
+	_, err := NewEngine(WithConfig(cfg))
+	assert.True(t, errors.Is(err, ErrOutOfRange))
+}
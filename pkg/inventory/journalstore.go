@@ -0,0 +1,286 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/pkg/errors"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// IncrementalSnapshotStore is a SnapshotStore which can also persist
+// just the changes since the last save.
+type IncrementalSnapshotStore interface {
+	SnapshotStore
+	// SaveChanges persists the changed tags and removes the tags with the given EPCs.
+	SaveChanges(changed []StaticTag, removed []string) error
+}
+
+const (
+	// JournalSnapshotStoreType stores a full snapshot file
+	// along with a journal of the changes made since it was written.
+	JournalSnapshotStoreType = "journal"
+
+	journalName = "tags.journal"
+
+	journalOpPut    = byte('P')
+	journalOpDelete = byte('D')
+
+	// journalEntryHeaderLen is the length of an entry's header:
+	// a 4 byte payload length followed by a 4 byte CRC-32 of the payload.
+	journalEntryHeaderLen = 8
+	// journalMaxEntryLen guards against allocating huge buffers for corrupted lengths.
+	journalMaxEntryLen = 64 << 20
+
+	// journalCompactMinEntries is the fewest journal entries which trigger compaction.
+	journalCompactMinEntries = 1024
+)
+
+// JournalSnapshotStore persists the inventory as a full snapshot file
+// (as written by WriteSnapshotFile)
+// and a write-ahead journal of the tags changed or removed since then,
+// so that each save only writes O(changed tags).
+//
+// Once the journal has more entries than the inventory has tags,
+// it's compacted by writing a new snapshot file and truncating the journal.
+// A crash after writing the snapshot but before truncating the journal is harmless,
+// since replaying the journal over the new snapshot produces the same tags.
+//
+// It holds a copy of the persisted inventory in memory in order to compact it.
+type JournalSnapshotStore struct {
+	lc           logger.LoggingClient
+	snapshotPath string
+	backups      uint
+
+	journal *os.File
+	w       *bufio.Writer
+	entries int
+
+	tags map[string]StaticTag
+}
+
+// OpenJournalSnapshotStore opens or creates a JournalSnapshotStore
+// with the given snapshot file and journal
+// and loads the inventory from them.
+func OpenJournalSnapshotStore(lc logger.LoggingClient, snapshotPath, journalPath string, backups uint) (*JournalSnapshotStore, error) {
+	js := &JournalSnapshotStore{
+		lc:           lc,
+		snapshotPath: snapshotPath,
+		backups:      backups,
+		tags:         map[string]StaticTag{},
+	}
+
+	tags, err := NewFileSnapshotStore(lc, snapshotPath, backups).Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		js.tags[tag.EPC] = tag
+	}
+
+	js.journal, err = os.OpenFile(journalPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open snapshot journal")
+	}
+
+	var replayErr error
+	end, err := readJournalEntries(js.journal, func(op byte, epc string, value []byte) bool {
+		switch op {
+		case journalOpPut:
+			var tag StaticTag
+			if replayErr = json.Unmarshal(value, &tag); replayErr != nil {
+				return false
+			}
+			js.tags[epc] = tag
+		case journalOpDelete:
+			delete(js.tags, epc)
+		default:
+			return false
+		}
+		js.entries++
+		return true
+	})
+	if err == nil {
+		err = replayErr
+	}
+	if err != nil {
+		// keep what was replayed, but the rest of the journal is lost
+		lc.Warn("Failed to replay snapshot journal.", "error", err.Error())
+	}
+
+	// discard any partial entry left by a crash
+	if err := js.journal.Truncate(end); err != nil {
+		js.journal.Close()
+		return nil, errors.Wrap(err, "failed to truncate snapshot journal")
+	}
+	if _, err := js.journal.Seek(end, io.SeekStart); err != nil {
+		js.journal.Close()
+		return nil, errors.Wrap(err, "failed to seek snapshot journal")
+	}
+	js.w = bufio.NewWriter(js.journal)
+
+	if js.entries > 0 {
+		lc.Info(fmt.Sprintf("Replayed %d snapshot journal entries.", js.entries))
+	}
+	return js, nil
+}
+
+// Load returns the persisted inventory.
+func (js *JournalSnapshotStore) Load() ([]StaticTag, error) {
+	tags := make([]StaticTag, 0, len(js.tags))
+	for _, tag := range js.tags {
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// Save replaces the persisted inventory with tags,
+// writing them to a new snapshot file and truncating the journal.
+func (js *JournalSnapshotStore) Save(tags []StaticTag) error {
+	js.tags = make(map[string]StaticTag, len(tags))
+	for _, tag := range tags {
+		js.tags[tag.EPC] = tag
+	}
+	return js.compact()
+}
+
+// SaveChanges appends the changes to the journal,
+// compacting it if it's grown larger than the inventory.
+func (js *JournalSnapshotStore) SaveChanges(changed []StaticTag, removed []string) error {
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	for _, tag := range changed {
+		data, err := json.Marshal(tag)
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal tag %s", tag.EPC)
+		}
+		if err := writeJournalEntry(js.w, journalOpPut, tag.EPC, data); err != nil {
+			return errors.Wrap(err, "failed to write snapshot journal")
+		}
+		js.tags[tag.EPC] = tag
+	}
+	for _, epc := range removed {
+		if err := writeJournalEntry(js.w, journalOpDelete, epc, nil); err != nil {
+			return errors.Wrap(err, "failed to write snapshot journal")
+		}
+		delete(js.tags, epc)
+	}
+	js.entries += len(changed) + len(removed)
+
+	if js.entries >= journalCompactMinEntries && js.entries > len(js.tags) {
+		return js.compact()
+	}
+
+	if err := js.w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to write snapshot journal")
+	}
+	return errors.Wrap(js.journal.Sync(), "failed to sync snapshot journal")
+}
+
+// compact writes the inventory to a new snapshot file and truncates the journal.
+func (js *JournalSnapshotStore) compact() error {
+	tags, _ := js.Load()
+	if err := WriteSnapshotFile(js.snapshotPath, tags, js.backups); err != nil {
+		// the journal still has the changes
+		if flushErr := js.w.Flush(); flushErr == nil {
+			_ = js.journal.Sync()
+		}
+		return err
+	}
+
+	js.w.Reset(js.journal)
+	if err := js.journal.Truncate(0); err != nil {
+		return errors.Wrap(err, "failed to truncate snapshot journal")
+	}
+	if _, err := js.journal.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to seek snapshot journal")
+	}
+	js.entries = 0
+	return errors.Wrap(js.journal.Sync(), "failed to sync snapshot journal")
+}
+
+// Close flushes and closes the journal.
+func (js *JournalSnapshotStore) Close() error {
+	err := js.w.Flush()
+	if closeErr := js.journal.Close(); err == nil {
+		err = closeErr
+	}
+	return errors.Wrap(err, "failed to close snapshot journal")
+}
+
+// readJournalEntries calls f with each entry in r
+// and returns the offset of the end of the last valid one.
+// It stops at the first partial or corrupted entry, or if f returns false.
+func readJournalEntries(r io.Reader, f func(op byte, key string, value []byte) bool) (int64, error) {
+	br := bufio.NewReader(r)
+	var end int64
+	header := make([]byte, journalEntryHeaderLen)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return end, nil
+			}
+			return 0, errors.Wrap(err, "failed to read snapshot journal")
+		}
+
+		length := binary.BigEndian.Uint32(header)
+		if length < 3 || length > journalMaxEntryLen {
+			return end, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return end, nil
+			}
+			return 0, errors.Wrap(err, "failed to read snapshot journal")
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+			return end, nil
+		}
+
+		op, keyLen := payload[0], int(binary.BigEndian.Uint16(payload[1:]))
+		if 3+keyLen > len(payload) {
+			return end, nil
+		}
+		if !f(op, string(payload[3:3+keyLen]), payload[3+keyLen:]) {
+			return end, nil
+		}
+
+		end += int64(journalEntryHeaderLen) + int64(length)
+	}
+}
+
+func writeJournalEntry(w io.Writer, op byte, key string, value []byte) error {
+	if len(key) > 0xFFFF {
+		return errors.Errorf("snapshot journal key is too long: %d bytes", len(key))
+	}
+
+	payloadLen := 3 + len(key) + len(value)
+	if payloadLen > journalMaxEntryLen {
+		return errors.Errorf("snapshot journal entry is too long: %d bytes", payloadLen)
+	}
+
+	entry := make([]byte, journalEntryHeaderLen+payloadLen)
+	payload := entry[journalEntryHeaderLen:]
+	payload[0] = op
+	binary.BigEndian.PutUint16(payload[1:], uint16(len(key)))
+	copy(payload[3:], key)
+	copy(payload[3+len(key):], value)
+	binary.BigEndian.PutUint32(entry, uint32(payloadLen))
+	binary.BigEndian.PutUint32(entry[4:], crc32.ChecksumIEEE(payload))
+
+	_, err := w.Write(entry)
+	return err
+}
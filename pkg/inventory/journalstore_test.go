@@ -0,0 +1,154 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestJournal(t *testing.T, dir string) *JournalSnapshotStore {
+	t.Helper()
+	js, err := OpenJournalSnapshotStore(getTestingLogger(),
+		filepath.Join(dir, "tags.json"), filepath.Join(dir, "tags.journal"), 1)
+	require.NoError(t, err)
+	return js
+}
+
+func journalTag(epc string, lastRead int64) StaticTag {
+	return StaticTag{EPC: epc, State: Present, LastRead: lastRead, StatsMap: map[string]StaticTagStats{}}
+}
+
+func TestJournalSnapshotStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	js := openTestJournal(t, dir)
+	require.NoError(t, js.Save([]StaticTag{journalTag("01", 1), journalTag("02", 2)}))
+	snapshotInfo, err := os.Stat(filepath.Join(dir, "tags.json"))
+	require.NoError(t, err)
+
+	require.NoError(t, js.SaveChanges([]StaticTag{journalTag("02", 20), journalTag("03", 30)}, nil))
+	require.NoError(t, js.SaveChanges(nil, []string{"01"}))
+	require.NoError(t, js.Close())
+
+	// changes only go to the journal
+	info, err := os.Stat(filepath.Join(dir, "tags.json"))
+	require.NoError(t, err)
+	assert.Equal(t, snapshotInfo.ModTime(), info.ModTime())
+	assert.Equal(t, snapshotInfo.Size(), info.Size())
+
+	js = openTestJournal(t, dir)
+	assert.Equal(t, 3, js.entries)
+	tags, err := js.Load()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []StaticTag{journalTag("02", 20), journalTag("03", 30)}, tags)
+
+	// a full save truncates the journal
+	require.NoError(t, js.Save(tags))
+	require.NoError(t, js.Close())
+	info, err = os.Stat(filepath.Join(dir, "tags.journal"))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	js = openTestJournal(t, dir)
+	defer js.Close()
+	tags, err = js.Load()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []StaticTag{journalTag("02", 20), journalTag("03", 30)}, tags)
+}
+
+func TestJournalSnapshotStore_partialEntry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	js := openTestJournal(t, dir)
+	require.NoError(t, js.SaveChanges([]StaticTag{journalTag("01", 1)}, nil))
+	require.NoError(t, js.SaveChanges([]StaticTag{journalTag("01", 2)}, nil))
+	require.NoError(t, js.Close())
+
+	journal := filepath.Join(dir, "tags.journal")
+	info, err := os.Stat(journal)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(journal, info.Size()-5))
+
+	js = openTestJournal(t, dir)
+	defer js.Close()
+	tags, err := js.Load()
+	require.NoError(t, err)
+	assert.Equal(t, []StaticTag{journalTag("01", 1)}, tags)
+	assert.Equal(t, 1, js.entries)
+}
+
+func TestJournalSnapshotStore_compact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	js := openTestJournal(t, dir)
+	tags := []StaticTag{journalTag("01", 0), journalTag("02", 0)}
+	for i := 0; i < journalCompactMinEntries/2; i++ {
+		tags[0].LastRead, tags[1].LastRead = int64(i), int64(i)
+		require.NoError(t, js.SaveChanges(tags, nil))
+	}
+	assert.Zero(t, js.entries)
+	require.NoError(t, js.Close())
+
+	info, err := os.Stat(filepath.Join(dir, "tags.journal"))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	js = openTestJournal(t, dir)
+	defer js.Close()
+	loaded, err := js.Load()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, tags, loaded)
+}
+
+func TestTagProcessor_TakeChanges(t *testing.T) {
+	cfg := NewConsulConfig()
+	ds := newTestDataset(cfg, 3)
+	sensor := nextSensor()
+
+	changed, removed := ds.tp.TakeChanges()
+	assert.Empty(t, changed)
+	assert.Empty(t, removed)
+
+	ds.readAll(t, readParams{deviceName: sensor, antenna: defaultAntenna})
+	changed, removed = ds.tp.TakeChanges()
+	assert.Len(t, changed, 3)
+	assert.Empty(t, removed)
+
+	// taking them clears them
+	changed, _ = ds.tp.TakeChanges()
+	assert.Empty(t, changed)
+
+	ds.readTag(t, ds.epcs[1], readParams{deviceName: sensor, antenna: defaultAntenna})
+	changed, _ = ds.tp.TakeChanges()
+	require.Len(t, changed, 1)
+	assert.Equal(t, ds.epcs[1], changed[0].EPC)
+
+	// departing and aging out are changes too
+	for _, tag := range ds.tp.inventory {
+		tag.LastRead = 0
+	}
+	events := ds.tp.AggregateDeparted()
+	assert.Len(t, events, 3)
+	changed, _ = ds.tp.TakeChanges()
+	assert.Len(t, changed, 3)
+
+	n := ds.tp.AgeOut()
+	assert.Equal(t, 3, n)
+	changed, removed = ds.tp.TakeChanges()
+	assert.Empty(t, changed)
+	assert.ElementsMatch(t, ds.epcs, removed)
+}
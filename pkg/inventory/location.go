@@ -7,7 +7,8 @@ package inventory
 
 import "strconv"
 
-// Location represents a unique Device-Antenna combination
+// Location represents a unique Device-Antenna combination,
+// or for Impinj gateway Readers reporting tag directions, a unique Device-Sector combination.
 type Location struct {
 	// DeviceName is the name of the device which corresponds to the antenna where the
 	// tag is located
@@ -15,6 +16,9 @@ type Location struct {
 	// AntennaID is the id number of the antenna as reported by LLRP and is unique/relative to the
 	// device it is attached to.
 	AntennaID uint16 `json:"antenna_id"`
+	// Sector is the id number of the gateway Reader's sector in which the tag was last seen,
+	// or 0 if the location is based on an antenna.
+	Sector uint8 `json:"sector,omitempty"`
 }
 
 // NewLocation creates a Location object for the specified device, antenna combo.
@@ -22,19 +26,29 @@ func NewLocation(deviceName string, antennaID uint16) Location {
 	return Location{DeviceName: deviceName, AntennaID: antennaID}
 }
 
-// Equals returns true if the receiver Location has the same device and antenna values as
+// NewSectorLocation creates a Location object for the specified device, sector combo.
+func NewSectorLocation(deviceName string, sector uint8) Location {
+	return Location{DeviceName: deviceName, Sector: sector}
+}
+
+// Equals returns true if the receiver Location has the same device, antenna, and sector values as
 // the other Location.
 func (loc Location) Equals(other Location) bool {
-	return loc.AntennaID == other.AntennaID && loc.DeviceName == other.DeviceName
+	return loc.AntennaID == other.AntennaID && loc.DeviceName == other.DeviceName &&
+		loc.Sector == other.Sector
 }
 
 // IsEmpty returns true if this Location value has all default values.
 func (loc Location) IsEmpty() bool {
-	return loc.DeviceName == "" && loc.AntennaID == 0
+	return loc.DeviceName == "" && loc.AntennaID == 0 && loc.Sector == 0
 }
 
 // String returns the string representation of a Location, which is the device name followed by
-// an underscore, followed by the antennaID.
+// an underscore, followed by the antennaID, or for sector locations,
+// by the letter S and the sector id.
 func (loc Location) String() string {
+	if loc.Sector != 0 {
+		return loc.DeviceName + "_S" + strconv.Itoa(int(loc.Sector))
+	}
 	return loc.DeviceName + "_" + strconv.Itoa(int(loc.AntennaID))
 }
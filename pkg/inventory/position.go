@@ -0,0 +1,173 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"github.com/pkg/errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AntennaPosition is the configured position of the antenna at a Location,
+// in meters, within the coordinate system of the given floor.
+type AntennaPosition struct {
+	X, Y, Z float64
+	Floor   string
+}
+
+// Position is a tag's estimated position, in meters,
+// within the coordinate system of the given floor.
+type Position struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Z     float64 `json:"z"`
+	Floor string  `json:"floor,omitempty"`
+	// Timestamp is the last read time of the data used to estimate the position
+	// (Unix Epoch milliseconds).
+	Timestamp int64 `json:"timestamp"`
+}
+
+// distanceTo returns the Euclidean distance between two Positions on the same floor.
+func (p Position) distanceTo(other Position) float64 {
+	return math.Sqrt((p.X-other.X)*(p.X-other.X) +
+		(p.Y-other.Y)*(p.Y-other.Y) +
+		(p.Z-other.Z)*(p.Z-other.Z))
+}
+
+// ParseAntennaPositions parses the AntennaPositions config value.
+//
+// It's a semicolon-separated list of entries of the form
+// `{location}={x},{y}[,{z}][@{floor}]`,
+// where {location} is a default location name (e.g., `Reader-10-EF-25_1`)
+// and the coordinates are in meters.
+// Whitespace around entries and their parts is ignored.
+func ParseAntennaPositions(s string) (map[string]AntennaPosition, error) {
+	positions := map[string]AntennaPosition{}
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		eq := strings.IndexByte(entry, '=')
+		if eq <= 0 {
+			return nil, errors.Errorf("antenna position %q is not of the form location=x,y[,z][@floor]", entry)
+		}
+
+		location := strings.TrimSpace(entry[:eq])
+		coords := entry[eq+1:]
+
+		var pos AntennaPosition
+		if at := strings.IndexByte(coords, '@'); at >= 0 {
+			pos.Floor = strings.TrimSpace(coords[at+1:])
+			coords = coords[:at]
+		}
+
+		parts := strings.Split(coords, ",")
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, errors.Errorf("antenna position for %q must have 2 or 3 coordinates", location)
+		}
+
+		targets := []*float64{&pos.X, &pos.Y, &pos.Z}
+		for i, p := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid coordinate for antenna position %q", location)
+			}
+			*targets[i] = v
+		}
+
+		if _, exists := positions[location]; exists {
+			return nil, errors.Errorf("duplicate antenna position for %q", location)
+		}
+		positions[location] = pos
+	}
+
+	return positions, nil
+}
+
+// updatePosition estimates the tag's position from its stats
+// at Locations with configured antenna positions,
+// and returns a PositionUpdated event if it's the tag's first position,
+// it changed floors, or it moved more than the configured threshold
+// since the last such event.
+//
+// The estimate is the weighted centroid of the antennas on the floor
+// of the antenna with the strongest mean RSSI,
+// limited to those which read the tag within the position window
+// of its most recent read.
+// Each antenna's weight is its mean RSSI converted to linear power (mW),
+// so nearer antennas dominate.
+func (tp *TagProcessor) updatePosition(tag *Tag) Event {
+	type candidate struct {
+		pos    AntennaPosition
+		rssi   float64
+		weight float64
+	}
+
+	tag.statsMu.Lock()
+	var candidates []candidate
+	var lastRead int64
+	for loc, stats := range tag.statsMap {
+		pos, ok := tp.config.antennaPositions[loc]
+		if !ok || stats.rssiCount() == 0 || tag.LastRead-stats.lastRead > tp.config.positionWindowMillis {
+			continue
+		}
+
+		rssi := stats.rssiDbm.Mean()
+		candidates = append(candidates, candidate{pos: pos, rssi: rssi, weight: math.Pow(10, rssi/10)})
+		if stats.lastRead > lastRead {
+			lastRead = stats.lastRead
+		}
+	}
+	tag.statsMu.Unlock()
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].rssi > candidates[j].rssi
+	})
+
+	floor := candidates[0].pos.Floor
+	var x, y, z, total float64
+	for _, c := range candidates {
+		if c.pos.Floor != floor {
+			continue
+		}
+		x += c.weight * c.pos.X
+		y += c.weight * c.pos.Y
+		z += c.weight * c.pos.Z
+		total += c.weight
+	}
+
+	pos := Position{X: x / total, Y: y / total, Z: z / total, Floor: floor, Timestamp: lastRead}
+	tag.Position = &pos
+
+	prev := tag.reportedPosition
+	if prev != nil && prev.Floor == pos.Floor &&
+		pos.distanceTo(*prev) < tp.config.positionUpdateThresholdMeters {
+		return nil
+	}
+
+	tag.reportedPosition = &pos
+	event := PositionUpdatedEvent{
+		BaseEvent: BaseEvent{
+			EPC:       tag.EPC,
+			TID:       tag.TID,
+			Timestamp: lastRead,
+		},
+		Position: pos,
+	}
+	if prev != nil {
+		prevCopy := *prev
+		event.PreviousPosition = &prevCopy
+	}
+	return event
+}
@@ -0,0 +1,132 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseAntennaPositions(t *testing.T) {
+	positions, err := ParseAntennaPositions(" Reader-1_1=0,0 ; Reader-1_2 = 4.5, -1, 2.25 @ Floor 2;")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]AntennaPosition{
+		"Reader-1_1": {},
+		"Reader-1_2": {X: 4.5, Y: -1, Z: 2.25, Floor: "Floor 2"},
+	}, positions)
+
+	positions, err = ParseAntennaPositions("")
+	require.NoError(t, err)
+	assert.Empty(t, positions)
+
+	for _, bad := range []string{
+		"Reader-1_1",
+		"=1,2",
+		"Reader-1_1=1",
+		"Reader-1_1=1,2,3,4",
+		"Reader-1_1=1,x",
+		"Reader-1_1=1,2;Reader-1_1=3,4",
+	} {
+		_, err := ParseAntennaPositions(bad)
+		assert.Errorf(t, err, "expected an error for %q", bad)
+	}
+}
+
+func TestTagProcessor_positions(t *testing.T) {
+	sensor := nextSensor()
+	loc := func(ant uint16) string { return NewLocation(sensor, ant).String() }
+
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AntennaPositions = loc(1) + "=0,0@F1;" +
+		loc(2) + "=10,0@F1;" +
+		loc(3) + "=0,0@F2"
+	cfg.ApplicationSettings.PositionUpdateThresholdMeters = 2
+	ds := newTestDataset(cfg, 1)
+	epc := ds.epcs[0]
+
+	positionEvents := func(events []Event) (pos []PositionUpdatedEvent) {
+		for _, e := range events {
+			if p, ok := e.(PositionUpdatedEvent); ok {
+				pos = append(pos, p)
+			}
+		}
+		return pos
+	}
+
+	// equal RSSI at both antennas places it between them
+	events := ds.readTag(t, epc, readParams{deviceName: sensor, antenna: 1, rssi: -60})
+	events = append(events, ds.readTag(t, epc, readParams{deviceName: sensor, antenna: 2, rssi: -60})...)
+	updates := positionEvents(events)
+	require.Len(t, updates, 2)
+	assert.Nil(t, updates[0].PreviousPosition)
+	assert.InDelta(t, 0, updates[0].Position.X, 1e-9)
+	assert.InDelta(t, 5, updates[1].Position.X, 1e-9)
+	assert.Equal(t, "F1", updates[1].Position.Floor)
+	require.NotNil(t, updates[1].PreviousPosition)
+
+	snapshot := ds.tp.Snapshot()
+	require.Len(t, snapshot, 1)
+	require.NotNil(t, snapshot[0].Position)
+	assert.InDelta(t, 5, snapshot[0].Position.X, 1e-9)
+	assert.Equal(t, snapshot[0].Position, snapshot[0].asTagPtr().Position)
+
+	// a read 6dB stronger raises antenna 2's mean RSSI by 3dB, doubling its weight,
+	// but moving <2m doesn't generate an event
+	events = ds.readTag(t, epc, readParams{deviceName: sensor, antenna: 2, rssi: -54, count: 1})
+	assert.Empty(t, positionEvents(events))
+	assert.Greater(t, ds.tp.inventory[epc].Position.X, 5.0)
+
+	// a much stronger signal moves it far enough to report
+	events = ds.readTag(t, epc, readParams{deviceName: sensor, antenna: 2, rssi: -30, count: 10})
+	updates = positionEvents(events)
+	require.Len(t, updates, 1)
+	assert.Greater(t, updates[0].Position.X, 7.0)
+
+	// the strongest antenna determines the floor
+	events = ds.readTag(t, epc, readParams{deviceName: sensor, antenna: 3, rssi: -20, count: 20})
+	updates = positionEvents(events)
+	require.NotEmpty(t, updates)
+	last := updates[len(updates)-1].Position
+	assert.Equal(t, "F2", last.Floor)
+	assert.InDelta(t, 0, last.X, 1e-9)
+
+	// reads from antennas without positions don't affect it,
+	// and antennas not read within the window are ignored
+	ds.readTag(t, epc, readParams{deviceName: sensor, antenna: 4, rssi: -20,
+		lastSeen: time.Now().Add(time.Duration(cfg.ApplicationSettings.PositionWindowSeconds+1) * time.Second)})
+	assert.Equal(t, &last, ds.tp.inventory[epc].Position)
+}
+
+func TestTagProcessor_positionDepartArrive(t *testing.T) {
+	sensor := nextSensor()
+	loc := func(ant uint16) string { return NewLocation(sensor, ant).String() }
+
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AntennaPositions = loc(1) + "=0,0@F1;" + loc(2) + "=10,0@F1"
+	ds := newTestDataset(cfg, 1)
+	epc := ds.epcs[0]
+	departedThreshold := time.Duration(cfg.ApplicationSettings.DepartedThresholdSeconds) * time.Second
+
+	ds.readTag(t, epc, readParams{deviceName: sensor, antenna: 2, rssi: -60,
+		lastSeen: time.Now().Add(-2 * departedThreshold)})
+	require.NotNil(t, ds.tp.inventory[epc].Position)
+
+	// departing clears the position
+	require.NoError(t, ds.verifyEventPattern(ds.tp.AggregateDeparted(), 1, DepartedType))
+	assert.Nil(t, ds.tp.inventory[epc].Position)
+	assert.Nil(t, ds.tp.Snapshot()[0].Position)
+
+	// so on arrival, its position is based only on new reads
+	events := ds.readTag(t, epc, readParams{deviceName: sensor, antenna: 1, rssi: -60})
+	require.Len(t, events, 2)
+	assert.Equal(t, ArrivedType, events[0].OfType())
+	update, ok := events[1].(PositionUpdatedEvent)
+	require.True(t, ok)
+	assert.Nil(t, update.PreviousPosition)
+	assert.InDelta(t, 0, update.Position.X, 1e-9)
+}
@@ -0,0 +1,202 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"strconv"
+	"time"
+)
+
+// ReaderEvent holds the values common to events generated from ReaderEventNotifications.
+type ReaderEvent struct {
+	// DeviceName is the name of the Reader that sent the notification.
+	DeviceName string `json:"device_name"`
+	// Timestamp is the time at which the Reader says the event occurred
+	// (Unix Epoch milliseconds), or the time the notification was handled
+	// if the Reader reported its uptime instead.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Device returns the name of the Reader that sent the notification,
+// so events generated from notifications can be handled without a type switch.
+func (e ReaderEvent) Device() string {
+	return e.DeviceName
+}
+
+// AntennaEvent is generated when a Reader reports an antenna connected or disconnected.
+type AntennaEvent struct {
+	ReaderEvent
+	AntennaID uint16 `json:"antenna_id"`
+	// Event is either "Connected" or "Disconnected".
+	Event string `json:"event"`
+}
+
+// GPIEvent is generated when a Reader reports a GPI changed state.
+type GPIEvent struct {
+	ReaderEvent
+	Port  uint16 `json:"port"`
+	State bool   `json:"state"`
+}
+
+// ROSpecEvent is generated when a Reader reports an ROSpec started, ended, or was preempted.
+type ROSpecEvent struct {
+	ReaderEvent
+	ROSpecID uint32 `json:"rospec_id"`
+	// PreemptingROSpecID is the ID of the ROSpec that preempted this one, if any.
+	PreemptingROSpecID uint32 `json:"preempting_rospec_id,omitempty"`
+	// Event is one of "Started", "Ended", or "Preempted".
+	Event string `json:"event"`
+}
+
+// AISpecEvent is generated when a Reader reports an AISpec ended.
+type AISpecEvent struct {
+	ReaderEvent
+	ROSpecID  uint32 `json:"rospec_id"`
+	SpecIndex uint16 `json:"spec_index"`
+}
+
+// ReaderExceptionEvent is generated when a Reader reports an unexpected error.
+// The optional IDs identify what the Reader was doing when it occurred.
+type ReaderExceptionEvent struct {
+	ReaderEvent
+	Message      string  `json:"message"`
+	ROSpecID     *uint32 `json:"rospec_id,omitempty"`
+	AntennaID    *uint16 `json:"antenna_id,omitempty"`
+	AccessSpecID *uint32 `json:"access_spec_id,omitempty"`
+}
+
+// ReportBufferWarningEvent is generated when a Reader's report buffer is filling up,
+// usually because reports aren't being read from it fast enough.
+type ReportBufferWarningEvent struct {
+	ReaderEvent
+	// FillPercent is how full the buffer is.
+	FillPercent uint8 `json:"fill_percent"`
+}
+
+// ReportBufferOverflowEvent is generated when a Reader's report buffer overflows,
+// in which case it drops reports.
+type ReportBufferOverflowEvent struct {
+	ReaderEvent
+}
+
+// NewReaderEvents returns the Events for a ReaderEventNotification from the named device.
+//
+// A single notification may include several kinds of events.
+// Connection and other events without a corresponding Event type are ignored,
+// so the result may be empty.
+func NewReaderEvents(device string, data *llrp.ReaderEventNotificationData) (events []Event) {
+	base := ReaderEvent{DeviceName: device, Timestamp: UnixMilliNow()}
+	if data.UTCTimestamp != 0 {
+		base.Timestamp = int64(data.UTCTimestamp) / int64(time.Millisecond/time.Microsecond)
+	}
+
+	if e := data.AntennaEvent; e != nil {
+		event := "Connected"
+		if e.Event == llrp.AntennaDisconnected {
+			event = "Disconnected"
+		}
+		events = append(events, AntennaEvent{
+			ReaderEvent: base,
+			AntennaID:   uint16(e.AntennaID),
+			Event:       event,
+		})
+	}
+
+	if e := data.GPIEvent; e != nil {
+		events = append(events, GPIEvent{ReaderEvent: base, Port: e.Port, State: e.Event})
+	}
+
+	if e := data.ROSpecEvent; e != nil {
+		var event string
+		switch e.Event {
+		case llrp.ROSpecStarted:
+			event = "Started"
+		case llrp.ROSpecEnded:
+			event = "Ended"
+		case llrp.ROSpecPreempted:
+			event = "Preempted"
+		default:
+			event = strconv.Itoa(int(e.Event))
+		}
+		events = append(events, ROSpecEvent{
+			ReaderEvent:        base,
+			ROSpecID:           e.ROSpecID,
+			PreemptingROSpecID: e.PreemptingROSpecID,
+			Event:              event,
+		})
+	}
+
+	if e := data.AISpecEvent; e != nil && e.Event == llrp.AISpecEnded {
+		events = append(events, AISpecEvent{
+			ReaderEvent: base,
+			ROSpecID:    e.ROSpecID,
+			SpecIndex:   e.SpecIndex,
+		})
+	}
+
+	if e := data.ReaderExceptionEvent; e != nil {
+		re := ReaderExceptionEvent{ReaderEvent: base, Message: e.Message}
+		if e.ROSpecID != nil {
+			id := uint32(*e.ROSpecID)
+			re.ROSpecID = &id
+		}
+		if e.AntennaID != nil {
+			id := uint16(*e.AntennaID)
+			re.AntennaID = &id
+		}
+		if e.AccessSpecID != nil {
+			id := uint32(*e.AccessSpecID)
+			re.AccessSpecID = &id
+		}
+		events = append(events, re)
+	}
+
+	if e := data.ReportBufferLevelWarningEvent; e != nil {
+		events = append(events, ReportBufferWarningEvent{ReaderEvent: base, FillPercent: uint8(*e)})
+	}
+
+	if data.ReportBufferOverflowErrorEvent != nil {
+		events = append(events, ReportBufferOverflowEvent{ReaderEvent: base})
+	}
+
+	return events
+}
+
+// OfType for AntennaEvent returns AntennaType
+func (e AntennaEvent) OfType() EventType {
+	return AntennaType
+}
+
+// OfType for GPIEvent returns GPIType
+func (e GPIEvent) OfType() EventType {
+	return GPIType
+}
+
+// OfType for ROSpecEvent returns ROSpecType
+func (e ROSpecEvent) OfType() EventType {
+	return ROSpecType
+}
+
+// OfType for AISpecEvent returns AISpecType
+func (e AISpecEvent) OfType() EventType {
+	return AISpecType
+}
+
+// OfType for ReaderExceptionEvent returns ReaderExceptionType
+func (e ReaderExceptionEvent) OfType() EventType {
+	return ReaderExceptionType
+}
+
+// OfType for ReportBufferWarningEvent returns ReportBufferWarningType
+func (e ReportBufferWarningEvent) OfType() EventType {
+	return ReportBufferWarningType
+}
+
+// OfType for ReportBufferOverflowEvent returns ReportBufferOverflowType
+func (e ReportBufferOverflowEvent) OfType() EventType {
+	return ReportBufferOverflowType
+}
@@ -0,0 +1,59 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewReaderEvents(t *testing.T) {
+	antenna := llrp.AntennaID(2)
+	bufferLevel := llrp.ReportBufferLevelWarningEvent(90)
+	data := &llrp.ReaderEventNotificationData{
+		UTCTimestamp:                  1598062424893123,
+		AntennaEvent:                  &llrp.AntennaEvent{Event: llrp.AntennaDisconnected, AntennaID: antenna},
+		GPIEvent:                      &llrp.GPIEvent{Port: 1, Event: true},
+		ROSpecEvent:                   &llrp.ROSpecEvent{Event: llrp.ROSpecPreempted, ROSpecID: 1, PreemptingROSpecID: 5},
+		AISpecEvent:                   &llrp.AISpecEvent{Event: llrp.AISpecEnded, ROSpecID: 1, SpecIndex: 1},
+		ReaderExceptionEvent:          &llrp.ReaderExceptionEvent{Message: "fault", AntennaID: &antenna},
+		ReportBufferLevelWarningEvent: &bufferLevel,
+	}
+
+	events := NewReaderEvents("reader", data)
+	require.Len(t, events, 6)
+
+	base := ReaderEvent{DeviceName: "reader", Timestamp: 1598062424893}
+	expID := uint16(2)
+	assert.Equal(t, []Event{
+		AntennaEvent{ReaderEvent: base, AntennaID: 2, Event: "Disconnected"},
+		GPIEvent{ReaderEvent: base, Port: 1, State: true},
+		ROSpecEvent{ReaderEvent: base, ROSpecID: 1, PreemptingROSpecID: 5, Event: "Preempted"},
+		AISpecEvent{ReaderEvent: base, ROSpecID: 1, SpecIndex: 1},
+		ReaderExceptionEvent{ReaderEvent: base, Message: "fault", AntennaID: &expID},
+		ReportBufferWarningEvent{ReaderEvent: base, FillPercent: 90},
+	}, events)
+
+	payload, err := json.Marshal(events[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"device_name":"reader","timestamp":1598062424893,"antenna_id":2,"event":"Disconnected"}`,
+		string(payload))
+}
+
+func TestNewReaderEvents_noTimestamp(t *testing.T) {
+	events := NewReaderEvents("reader", &llrp.ReaderEventNotificationData{
+		ReportBufferOverflowErrorEvent: &llrp.ReportBufferOverflowErrorEvent{},
+	})
+	require.Len(t, events, 1)
+	assert.Equal(t, ReportBufferOverflowType, events[0].OfType())
+	assert.NotZero(t, events[0].(ReportBufferOverflowEvent).Timestamp)
+
+	cc := llrp.ConnectionAttemptEvent(llrp.ConnSuccess)
+	assert.Empty(t, NewReaderEvents("reader", &llrp.ReaderEventNotificationData{ConnectionAttemptEvent: &cc}))
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+)
+
+// readerWatchdog tracks which Readers have stopped reporting while they should be reading.
+//
+// A Reader is only watched while its ROSpec is running,
+// since otherwise it's not expected to send reports.
+// It's worth noting that a Reader with no tags in view may not send reports either,
+// so the offline threshold should be set comfortably above the time
+// a Reader is expected to go without seeing any tags.
+type readerWatchdog struct {
+	// watching holds Readers whose ROSpec was last known to be running.
+	// If a Reader disconnects, it keeps its previous value,
+	// since a Reader that drops its connection mid-inventory is exactly the sort
+	// of Reader the watchdog exists to catch.
+	watching map[string]bool
+	// offline maps offline Readers to the time they were marked offline.
+	offline map[string]int64
+	// recovered maps Readers that recovered to the time they recovered.
+	recovered map[string]int64
+}
+
+func newReaderWatchdog() readerWatchdog {
+	return readerWatchdog{
+		watching:  map[string]bool{},
+		offline:   map[string]int64{},
+		recovered: map[string]int64{},
+	}
+}
+
+// CheckReaders compares Reader statuses against the configured
+// readerOfflineThresholdSeconds and returns ReaderOffline events
+// for Readers which should be reading, but haven't reported within the threshold,
+// and ReaderRecovered events for previously offline Readers
+// that have since reported or are no longer expected to be reading.
+//
+// If the threshold is 0, the check is disabled
+// and any Readers currently considered offline are recovered.
+//
+// statuses should include every Reader in the group:
+// the watchdog forgets Readers that aren't in it,
+// so a Reader removed while offline doesn't keep suppressing Departed events.
+func (tp *TagProcessor) CheckReaders(statuses []llrp.ReaderStatus) []Event {
+	return tp.checkReadersAt(statuses, UnixMilliNow())
+}
+
+func (tp *TagProcessor) checkReadersAt(statuses []llrp.ReaderStatus, nowMs int64) (events []Event) {
+	wd := &tp.watchdog
+	thresholdMs := int64(tp.config.readerOfflineThresholdSeconds) * 1000
+	wd.forgetRemoved(statuses)
+
+	for _, s := range statuses {
+		if s.ROSpecRunning {
+			wd.watching[s.Name] = true
+		} else if s.Connected {
+			wd.watching[s.Name] = false
+		}
+
+		if offlineSince, isOffline := wd.offline[s.Name]; isOffline {
+			if thresholdMs != 0 && wd.watching[s.Name] && s.LastReport <= offlineSince {
+				continue
+			}
+
+			delete(wd.offline, s.Name)
+			wd.recovered[s.Name] = nowMs
+			tp.lc.Info("Reader recovered.", "device", s.Name, "offlineMs", nowMs-offlineSince)
+			events = append(events, ReaderRecoveredEvent{
+				DeviceName:   s.Name,
+				Timestamp:    nowMs,
+				OfflineSince: offlineSince,
+			})
+			continue
+		}
+
+		if thresholdMs == 0 || !wd.watching[s.Name] {
+			continue
+		}
+
+		// A Reader that just started reading gets the full threshold to send its first report.
+		lastActive := s.LastReport
+		if s.ROSpecRunningSince > lastActive {
+			lastActive = s.ROSpecRunningSince
+		}
+		if nowMs-lastActive <= thresholdMs {
+			continue
+		}
+
+		wd.offline[s.Name] = nowMs
+		delete(wd.recovered, s.Name)
+		tp.lc.Warn("Reader has not reported within the offline threshold.",
+			"device", s.Name, "lastReport", s.LastReport,
+			"thresholdSeconds", tp.config.readerOfflineThresholdSeconds)
+		events = append(events, ReaderOfflineEvent{
+			DeviceName: s.Name,
+			Timestamp:  nowMs,
+			LastReport: s.LastReport,
+		})
+	}
+
+	return events
+}
+
+// forgetRemoved drops the state of Readers that aren't in statuses.
+func (wd *readerWatchdog) forgetRemoved(statuses []llrp.ReaderStatus) {
+	inGroup := make(map[string]struct{}, len(statuses))
+	for _, s := range statuses {
+		inGroup[s.Name] = struct{}{}
+	}
+
+	for _, m := range []map[string]int64{wd.offline, wd.recovered} {
+		for name := range m {
+			if _, ok := inGroup[name]; !ok {
+				delete(m, name)
+			}
+		}
+	}
+	for name := range wd.watching {
+		if _, ok := inGroup[name]; !ok {
+			delete(wd.watching, name)
+		}
+	}
+}
+
+// suppressesDeparted returns true if tags last seen by the named Reader
+// should not be departed because the Reader is offline
+// or recovered less than graceMs ago.
+func (wd *readerWatchdog) suppressesDeparted(device string, nowMs, graceMs int64) bool {
+	if _, isOffline := wd.offline[device]; isOffline {
+		return true
+	}
+
+	recoveredAt, ok := wd.recovered[device]
+	if !ok {
+		return false
+	}
+	if nowMs-recoveredAt < graceMs {
+		return true
+	}
+	delete(wd.recovered, device)
+	return false
+}
@@ -0,0 +1,138 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func watchdogConfig(thresholdSecs uint, suppress bool) ConsulConfig {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.ReaderOfflineThresholdSeconds = thresholdSecs
+	cfg.ApplicationSettings.SuppressDepartedForOfflineReaders = suppress
+	return cfg
+}
+
+func TestCheckReaders(t *testing.T) {
+	tp := NewTagProcessor(getTestingLogger(), watchdogConfig(60, false), nil)
+	const start = int64(1000000)
+	const minute = int64(60 * 1000)
+
+	running := llrp.ReaderStatus{
+		Name: "running", Connected: true,
+		ROSpecEnabled: true, ROSpecRunning: true, ROSpecRunningSince: start,
+	}
+	idle := llrp.ReaderStatus{Name: "idle", Connected: true, ROSpecEnabled: true}
+
+	// a Reader that just started running gets the full threshold
+	assert.Empty(t, tp.checkReadersAt([]llrp.ReaderStatus{running, idle}, start+minute))
+
+	// an idle Reader is never offline
+	events := tp.checkReadersAt([]llrp.ReaderStatus{running, idle}, start+minute+1)
+	require.Len(t, events, 1)
+	assert.Equal(t, ReaderOfflineEvent{DeviceName: "running", Timestamp: start + minute + 1}, events[0])
+
+	// no repeated events while still offline,
+	// even if it disconnects
+	running.Connected = false
+	assert.Empty(t, tp.checkReadersAt([]llrp.ReaderStatus{running, idle}, start+2*minute))
+
+	// a report recovers it
+	running.Connected = true
+	running.LastReport = start + 2*minute + 1
+	events = tp.checkReadersAt([]llrp.ReaderStatus{running, idle}, start+2*minute+2)
+	require.Len(t, events, 1)
+	assert.Equal(t, ReaderRecoveredEvent{
+		DeviceName:   "running",
+		Timestamp:    start + 2*minute + 2,
+		OfflineSince: start + minute + 1,
+	}, events[0])
+
+	// goes offline again after another threshold without a report
+	events = tp.checkReadersAt([]llrp.ReaderStatus{running}, start+3*minute+2)
+	require.Len(t, events, 1)
+	assert.Equal(t, ReaderOfflineType, events[0].OfType())
+
+	// stopping the ROSpec recovers it
+	running.ROSpecRunning = false
+	running.ROSpecRunningSince = 0
+	events = tp.checkReadersAt([]llrp.ReaderStatus{running}, start+4*minute)
+	require.Len(t, events, 1)
+	assert.Equal(t, ReaderRecoveredType, events[0].OfType())
+	assert.Empty(t, tp.checkReadersAt([]llrp.ReaderStatus{running}, start+10*minute))
+}
+
+func TestCheckReaders_disabled(t *testing.T) {
+	tp := NewTagProcessor(getTestingLogger(), watchdogConfig(60, false), nil)
+	stale := []llrp.ReaderStatus{{Name: "r", Connected: true, ROSpecRunning: true, ROSpecRunningSince: 1}}
+	now := UnixMilliNow()
+
+	require.Len(t, tp.CheckReaders(stale), 1)
+
+	tp.UpdateConfig(watchdogConfig(0, false))
+	events := tp.checkReadersAt(stale, now)
+	require.Len(t, events, 1)
+	assert.Equal(t, ReaderRecoveredType, events[0].OfType())
+	assert.Empty(t, tp.checkReadersAt(stale, now))
+}
+
+func TestCheckReaders_removed(t *testing.T) {
+	tp := NewTagProcessor(getTestingLogger(), watchdogConfig(60, true), nil)
+	now := UnixMilliNow()
+	stale := llrp.ReaderStatus{Name: "r", Connected: true, ROSpecRunning: true, ROSpecRunningSince: 1}
+	recovered := llrp.ReaderStatus{Name: "r2", Connected: true, ROSpecRunning: true, ROSpecRunningSince: 1}
+
+	require.Len(t, tp.checkReadersAt([]llrp.ReaderStatus{stale, recovered}, now), 2)
+	recovered.LastReport = now + 1
+	require.Len(t, tp.checkReadersAt([]llrp.ReaderStatus{stale, recovered}, now+2), 1)
+	assert.True(t, tp.watchdog.suppressesDeparted("r", now+2, 1000))
+	assert.True(t, tp.watchdog.suppressesDeparted("r2", now+2, 1000))
+
+	// removing the Readers from the group drops their state without events
+	assert.Empty(t, tp.checkReadersAt(nil, now+3))
+	assert.Empty(t, tp.watchdog.watching)
+	assert.Empty(t, tp.watchdog.offline)
+	assert.Empty(t, tp.watchdog.recovered)
+	assert.False(t, tp.watchdog.suppressesDeparted("r", now+3, 1000))
+	assert.False(t, tp.watchdog.suppressesDeparted("r2", now+3, 1000))
+}
+
+func TestAggregateDeparted_offlineReader(t *testing.T) {
+	ds := newTestDataset(watchdogConfig(60, true), 10)
+	sensor := nextSensor()
+	departedThreshold := time.Duration(ds.tp.config.departedThresholdSeconds) * time.Second
+
+	ds.readAll(t, readParams{
+		deviceName: sensor,
+		antenna:    defaultAntenna,
+		count:      10,
+		lastSeen:   time.Now().Add(-2 * departedThreshold),
+	})
+
+	status := llrp.ReaderStatus{Name: sensor, Connected: true, ROSpecRunning: true, ROSpecRunningSince: 1}
+	events := ds.tp.CheckReaders([]llrp.ReaderStatus{status})
+	require.Len(t, events, 1)
+
+	// the Reader is offline, so its tags should stay Present
+	events = ds.tp.AggregateDeparted()
+	assert.Empty(t, events)
+	assert.NoError(t, ds.verifyStateAll(Present))
+
+	// once it recovers, it has until the departed threshold to read them again
+	status.ROSpecRunning = false
+	nowMs := UnixMilliNow()
+	require.Len(t, ds.tp.checkReadersAt([]llrp.ReaderStatus{status}, nowMs), 1)
+	events = ds.tp.AggregateDeparted()
+	assert.Empty(t, events)
+
+	ds.tp.watchdog.recovered[sensor] = nowMs - departedThreshold.Milliseconds()
+	events = ds.tp.AggregateDeparted()
+	assert.NoError(t, ds.verifyEventPattern(events, ds.size(), DepartedType))
+}
@@ -0,0 +1,43 @@
+package inventory
+
+import (
+	"time"
+)
+
+// ReportInfo holds both pre-existing as well as computed metadata about an incoming ROAccessReport
+type ReportInfo struct {
+	// DeviceName is the name of the Reader which sent the report.
+	DeviceName string
+	// OriginNanos is when the report was received, in nanoseconds since the Unix epoch.
+	OriginNanos int64
+
+	offsetMicros int64
+	// referenceTimestamp is the same as OriginNanos, but converted to milliseconds
+	referenceTimestamp int64
+}
+
+// NewReportInfo creates a new ReportInfo for a report from the named Reader,
+// received at originNanos, in nanoseconds since the Unix epoch.
+func NewReportInfo(deviceName string, originNanos int64) ReportInfo {
+	return ReportInfo{
+		DeviceName:         deviceName,
+		OriginNanos:        originNanos,
+		referenceTimestamp: originNanos / int64(time.Millisecond),
+	}
+}
+
+// adjustOffset sets offsetMicros, an adjustment of timestamps
+// based on when the device service first saw the message
+// compared to when the sensor said it sent it,
+// given the latest timestamp in the report.
+// This can be affected by the latency,
+// but hopefully that value has relatively little jitter.
+// If a sensor thinks the timestamp is in the future,
+// this will adjust the times to be standardized
+// against all other sensors in the system.
+func (info *ReportInfo) adjustOffset(lastSeenMicros int64) {
+	if lastSeenMicros > 0 {
+		// divide originNanos by 1000 to get to micros
+		info.offsetMicros = (info.OriginNanos / 1000) - lastSeenMicros
+	}
+}
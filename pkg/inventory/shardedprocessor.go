@@ -0,0 +1,404 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedProcessor splits the inventory across several TagProcessors ("shards")
+// by a hash of each tag's EPC, and runs each in its own goroutine,
+// so that reports from many Readers are processed in parallel.
+//
+// Each shard processes its work in the order it was submitted,
+// and all reads of a tag go to the same shard,
+// so each tag's reads are processed in the order they were reported.
+// Operations on the whole inventory, such as Snapshot and AggregateDeparted,
+// are queued to every shard behind any pending reports, and their results are merged.
+//
+// Events from reports are collected as they're produced
+// and retrieved with TakeEvents once EventsReady signals they're available.
+//
+// Other than Metrics, its methods must be called from a single goroutine.
+type ShardedProcessor struct {
+	lc     logger.LoggingClient
+	shards []*shard
+
+	adjustLastReadOnByOrigin bool
+
+	eventsMu sync.Mutex
+	events   []Event
+	ready    chan struct{}
+
+	workers sync.WaitGroup
+}
+
+// shard is a TagProcessor owned by a single worker goroutine.
+type shard struct {
+	tp    *TagProcessor
+	queue chan func(tp *TagProcessor)
+
+	// these are updated atomically, since they're read by Metrics
+	reports       uint64
+	blocked       uint64
+	blockedNanos  int64
+	inventorySize int64
+}
+
+// ShardMetrics describe the load on a single shard of a ShardedProcessor.
+type ShardMetrics struct {
+	Shard int `json:"shard"`
+	// QueueLength is the number of tasks waiting for the shard,
+	// out of its QueueCapacity.
+	QueueLength   int `json:"queue_length"`
+	QueueCapacity int `json:"queue_capacity"`
+	// Reports is the number of (partial) reports the shard has processed.
+	Reports uint64 `json:"reports"`
+	// Blocked is the number of times a task had to wait for room in the shard's queue,
+	// and BlockedMillis is the total time spent waiting.
+	Blocked       uint64 `json:"blocked"`
+	BlockedMillis int64  `json:"blocked_millis"`
+	// Tags is the number of tags in the shard's inventory.
+	Tags int64 `json:"tags"`
+}
+
+// ProcessorMetrics describe the load on a ShardedProcessor.
+type ProcessorMetrics struct {
+	Shards []ShardMetrics `json:"shards"`
+	// PendingEvents is the number of events waiting to be taken with TakeEvents.
+	PendingEvents int `json:"pending_events"`
+}
+
+// NewShardedProcessor returns a ShardedProcessor with the given number of shards,
+// each with a queue of queueSize tasks, and starts their goroutines.
+// The tags are distributed among the shards.
+func NewShardedProcessor(lc logger.LoggingClient, cfg ConsulConfig, tags []StaticTag, shards, queueSize int) *ShardedProcessor {
+	if shards <= 0 {
+		shards = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	sp := &ShardedProcessor{
+		lc:                       lc,
+		shards:                   make([]*shard, shards),
+		adjustLastReadOnByOrigin: cfg.ApplicationSettings.AdjustLastReadOnByOrigin,
+		ready:                    make(chan struct{}, 1),
+	}
+
+	shardTags := make([][]StaticTag, shards)
+	for _, t := range tags {
+		i := sp.shardOf(t.EPC)
+		shardTags[i] = append(shardTags[i], t)
+	}
+
+	for i := range sp.shards {
+		s := &shard{
+			tp:    NewTagProcessor(lc, shardConfig(cfg), shardTags[i]),
+			queue: make(chan func(tp *TagProcessor), queueSize),
+		}
+		s.inventorySize = int64(len(s.tp.inventory))
+		sp.shards[i] = s
+
+		sp.workers.Add(1)
+		go sp.work(s)
+	}
+	return sp
+}
+
+// shardConfig returns a copy of cfg that a shard can use
+// without sharing mutable state with the other shards.
+func shardConfig(cfg ConsulConfig) ConsulConfig {
+	aliases := make(map[string]string, len(cfg.Aliases))
+	for k, v := range cfg.Aliases {
+		aliases[k] = v
+	}
+	cfg.Aliases = aliases
+	return cfg
+}
+
+// work runs the shard's tasks until its queue is closed.
+func (sp *ShardedProcessor) work(s *shard) {
+	defer sp.workers.Done()
+	for task := range s.queue {
+		task(s.tp)
+		atomic.StoreInt64(&s.inventorySize, int64(len(s.tp.inventory)))
+	}
+}
+
+// shardOf returns the index of the shard which owns the tag with the given EPC.
+func (sp *ShardedProcessor) shardOf(epc string) int {
+	if len(sp.shards) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(epc))
+	return int(h.Sum32() % uint32(len(sp.shards)))
+}
+
+// enqueue adds the task to the shard's queue,
+// recording how long it had to wait if the queue is full.
+func (s *shard) enqueue(task func(tp *TagProcessor)) {
+	select {
+	case s.queue <- task:
+		return
+	default:
+	}
+
+	atomic.AddUint64(&s.blocked, 1)
+	start := time.Now()
+	s.queue <- task
+	atomic.AddInt64(&s.blockedNanos, int64(time.Since(start)))
+}
+
+// each runs f on every shard after its pending tasks and waits for them to finish.
+func (sp *ShardedProcessor) each(f func(i int, tp *TagProcessor)) {
+	var wg sync.WaitGroup
+	wg.Add(len(sp.shards))
+	for i, s := range sp.shards {
+		i := i
+		s.enqueue(func(tp *TagProcessor) {
+			defer wg.Done()
+			f(i, tp)
+		})
+	}
+	wg.Wait()
+}
+
+// shardReads are the parts of a report which belong to a single shard.
+type shardReads struct {
+	reads []TagRead
+	locs  []llrp.ImpinjTagLocation
+	dirs  []llrp.ImpinjTagDirection
+}
+
+// Submit splits the report's reads among the shards and queues them for processing.
+// It only blocks if a shard's queue is full.
+// Any resulting events are available from TakeEvents.
+func (sp *ShardedProcessor) Submit(nr *NormalizedReport, info ReportInfo) {
+	if sp.adjustLastReadOnByOrigin {
+		info.adjustOffset(nr.lastSeenMicros)
+	}
+
+	parts := make([]shardReads, len(sp.shards))
+	epcs := nr.epcs
+	for _, read := range nr.reads {
+		i := sp.shardOf(read.EPC)
+		parts[i].reads = append(parts[i].reads, read)
+	}
+	epcs = epcs[len(nr.reads):]
+	for j, loc := range nr.locs {
+		i := sp.shardOf(epcs[j])
+		parts[i].locs = append(parts[i].locs, loc)
+	}
+	epcs = epcs[len(nr.locs):]
+	for j, dir := range nr.dirs {
+		i := sp.shardOf(epcs[j])
+		parts[i].dirs = append(parts[i].dirs, dir)
+	}
+
+	for i, part := range parts {
+		if len(part.reads)+len(part.locs)+len(part.dirs) == 0 {
+			continue
+		}
+
+		s, part := sp.shards[i], part
+		s.enqueue(func(tp *TagProcessor) {
+			atomic.AddUint64(&s.reports, 1)
+			sp.addEvents(tp.processReads(part.reads, part.locs, part.dirs, info))
+		})
+	}
+}
+
+// SubmitReads is like Submit, but for reads processed as by TagProcessor.ProcessReads.
+func (sp *ShardedProcessor) SubmitReads(reads []TagRead) {
+	parts := make([][]TagRead, len(sp.shards))
+	for _, read := range reads {
+		i := sp.shardOf(read.EPC)
+		parts[i] = append(parts[i], read)
+	}
+
+	for i, part := range parts {
+		if len(part) == 0 {
+			continue
+		}
+
+		s, part := sp.shards[i], part
+		s.enqueue(func(tp *TagProcessor) {
+			atomic.AddUint64(&s.reports, 1)
+			sp.addEvents(tp.ProcessReads(part))
+		})
+	}
+}
+
+// addEvents collects events for TakeEvents and signals EventsReady.
+func (sp *ShardedProcessor) addEvents(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	sp.eventsMu.Lock()
+	sp.events = append(sp.events, events...)
+	sp.eventsMu.Unlock()
+
+	select {
+	case sp.ready <- struct{}{}:
+	default:
+	}
+}
+
+// EventsReady returns a channel which receives a value
+// when events may be available from TakeEvents.
+func (sp *ShardedProcessor) EventsReady() <-chan struct{} {
+	return sp.ready
+}
+
+// TakeEvents returns the events produced by submitted reports since the last call.
+// Events for the same tag are in the order they occurred,
+// but events from different shards may be interleaved arbitrarily.
+func (sp *ShardedProcessor) TakeEvents() []Event {
+	sp.eventsMu.Lock()
+	defer sp.eventsMu.Unlock()
+
+	events := sp.events
+	sp.events = nil
+	return events
+}
+
+// Flush waits for all submitted reports to be processed.
+func (sp *ShardedProcessor) Flush() {
+	sp.each(func(int, *TagProcessor) {})
+}
+
+// UpdateConfig updates every shard's configuration.
+func (sp *ShardedProcessor) UpdateConfig(cfg ConsulConfig) {
+	sp.adjustLastReadOnByOrigin = cfg.ApplicationSettings.AdjustLastReadOnByOrigin
+	sp.each(func(_ int, tp *TagProcessor) {
+		tp.UpdateConfig(shardConfig(cfg))
+	})
+}
+
+// Snapshot returns a snapshot of the entire inventory, merged from every shard.
+func (sp *ShardedProcessor) Snapshot() []StaticTag {
+	parts := make([][]StaticTag, len(sp.shards))
+	sp.each(func(i int, tp *TagProcessor) {
+		parts[i] = tp.Snapshot()
+	})
+	return mergeTags(parts)
+}
+
+// TakeChanges returns the tags changed and the EPCs of those removed
+// across all shards since the last call.
+func (sp *ShardedProcessor) TakeChanges() (changed []StaticTag, removed []string) {
+	changedParts := make([][]StaticTag, len(sp.shards))
+	removedParts := make([][]string, len(sp.shards))
+	sp.each(func(i int, tp *TagProcessor) {
+		changedParts[i], removedParts[i] = tp.TakeChanges()
+	})
+
+	changed = mergeTags(changedParts)
+	for _, part := range removedParts {
+		removed = append(removed, part...)
+	}
+	return changed, removed
+}
+
+// AggregateDeparted runs AggregateDeparted on every shard and returns their events.
+func (sp *ShardedProcessor) AggregateDeparted() []Event {
+	parts := make([][]Event, len(sp.shards))
+	sp.each(func(i int, tp *TagProcessor) {
+		parts[i] = tp.AggregateDeparted()
+	})
+	return mergeEvents(parts)
+}
+
+// AgeOut runs AgeOut on every shard and returns the total number of tags removed.
+func (sp *ShardedProcessor) AgeOut() int {
+	removed := make([]int, len(sp.shards))
+	sp.each(func(i int, tp *TagProcessor) {
+		removed[i] = tp.AgeOut()
+	})
+
+	total := 0
+	for _, n := range removed {
+		total += n
+	}
+	return total
+}
+
+// CheckReaders updates every shard's view of which Readers are offline
+// and returns the resulting ReaderOffline and ReaderRecovered events.
+//
+// Each shard makes the same decisions from the same statuses,
+// so only the first shard's events are returned.
+func (sp *ShardedProcessor) CheckReaders(statuses []llrp.ReaderStatus) []Event {
+	nowMs := UnixMilliNow()
+	var events []Event
+	sp.each(func(i int, tp *TagProcessor) {
+		e := tp.checkReadersAt(statuses, nowMs)
+		if i == 0 {
+			events = e
+		}
+	})
+	return events
+}
+
+// Metrics returns the current load on each shard.
+// Unlike the other methods, it's safe to call from any goroutine.
+func (sp *ShardedProcessor) Metrics() ProcessorMetrics {
+	m := ProcessorMetrics{Shards: make([]ShardMetrics, len(sp.shards))}
+	for i, s := range sp.shards {
+		m.Shards[i] = ShardMetrics{
+			Shard:         i,
+			QueueLength:   len(s.queue),
+			QueueCapacity: cap(s.queue),
+			Reports:       atomic.LoadUint64(&s.reports),
+			Blocked:       atomic.LoadUint64(&s.blocked),
+			BlockedMillis: atomic.LoadInt64(&s.blockedNanos) / int64(time.Millisecond),
+			Tags:          atomic.LoadInt64(&s.inventorySize),
+		}
+	}
+
+	sp.eventsMu.Lock()
+	m.PendingEvents = len(sp.events)
+	sp.eventsMu.Unlock()
+	return m
+}
+
+// Close waits for the shards to finish their pending work and stops their goroutines.
+// The ShardedProcessor must not be used afterwards.
+func (sp *ShardedProcessor) Close() {
+	for _, s := range sp.shards {
+		close(s.queue)
+	}
+	sp.workers.Wait()
+}
+
+func mergeTags(parts [][]StaticTag) []StaticTag {
+	n := 0
+	for _, part := range parts {
+		n += len(part)
+	}
+	merged := make([]StaticTag, 0, n)
+	for _, part := range parts {
+		merged = append(merged, part...)
+	}
+	return merged
+}
+
+func mergeEvents(parts [][]Event) []Event {
+	var merged []Event
+	for _, part := range parts {
+		merged = append(merged, part...)
+	}
+	return merged
+}
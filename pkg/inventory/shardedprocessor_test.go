@@ -0,0 +1,182 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"testing"
+	"time"
+)
+
+// eventKeys returns a sorted description of each event,
+// for comparing events whose order across tags doesn't matter.
+func eventKeys(events []Event) []string {
+	keys := make([]string, len(events))
+	for i, e := range events {
+		keys[i] = fmt.Sprintf("%+v", e)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedSnapshot(tags []StaticTag) []StaticTag {
+	sort.Slice(tags, func(i, j int) bool { return tags[i].EPC < tags[j].EPC })
+	return tags
+}
+
+func TestShardedProcessor_matchesTagProcessor(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AdjustLastReadOnByOrigin = false
+
+	const shards = 4
+	ds := newTestDataset(cfg, 200)
+	sp := NewShardedProcessor(getTestingLogger(), cfg, nil, shards, 2)
+	defer sp.Close()
+
+	front, back := nextSensor(), nextSensor()
+	start := time.Now()
+
+	// every tag arrives at the front, then moves to the back,
+	// which only happens if each tag's reads are processed in order
+	var expected []Event
+	for round := 0; round < 10; round++ {
+		sensor, rssi := front, rssiWeak
+		if round >= 5 {
+			sensor, rssi = back, rssiStrong
+		}
+		seen := start.Add(time.Duration(round) * time.Second)
+
+		for i := 0; i < len(ds.epcs); i += 10 {
+			r := &llrp.ROAccessReport{}
+			for _, epc := range ds.epcs[i : i+10] {
+				r.TagReportData = append(r.TagReportData, tagReportData(t, epc, defaultAntenna, rssi, seen))
+			}
+			info := reportInfo(sensor, seen)
+
+			expected = append(expected, ds.tp.ProcessReport(r, info)...)
+			sp.Submit(NormalizeReport(r, info.DeviceName, nil), info)
+		}
+	}
+
+	sp.Flush()
+	select {
+	case <-sp.EventsReady():
+	default:
+		t.Fatal("expected events to be ready")
+	}
+	actual := sp.TakeEvents()
+	assert.Empty(t, sp.TakeEvents())
+
+	require.NotEmpty(t, expected)
+	assert.Equal(t, eventKeys(expected), eventKeys(actual))
+	assert.Equal(t, sortedSnapshot(ds.tp.Snapshot()), sortedSnapshot(sp.Snapshot()))
+
+	changed, removed := sp.TakeChanges()
+	assert.Len(t, changed, len(ds.epcs))
+	assert.Empty(t, removed)
+
+	metrics := sp.Metrics()
+	require.Len(t, metrics.Shards, shards)
+	var tags int64
+	for _, m := range metrics.Shards {
+		assert.NotZero(t, m.Tags, "shard %d has no tags", m.Shard)
+		assert.NotZero(t, m.Reports, "shard %d processed no reports", m.Shard)
+		assert.Equal(t, 2, m.QueueCapacity)
+		tags += m.Tags
+	}
+	assert.Equal(t, int64(len(ds.epcs)), tags)
+}
+
+func TestShardedProcessor_restoresTags(t *testing.T) {
+	ds := newTestDataset(NewConsulConfig(), 50)
+	ds.readAll(t, readParams{deviceName: nextSensor(), antenna: defaultAntenna})
+
+	sp := NewShardedProcessor(getTestingLogger(), NewConsulConfig(), ds.tp.Snapshot(), 3, 10)
+	defer sp.Close()
+
+	assert.Equal(t, sortedSnapshot(ds.tp.Snapshot()), sortedSnapshot(sp.Snapshot()))
+	assert.Equal(t, 0, sp.AgeOut())
+}
+
+func TestShardedProcessor_backpressure(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AdjustLastReadOnByOrigin = false
+	sp := NewShardedProcessor(getTestingLogger(), cfg, nil, 1, 1)
+	defer sp.Close()
+
+	// stall the shard's worker
+	release := make(chan struct{})
+	sp.shards[0].enqueue(func(*TagProcessor) { <-release })
+	require.Eventually(t, func() bool { return sp.Metrics().Shards[0].QueueLength == 0 },
+		time.Second, time.Millisecond)
+
+	sensor := nextSensor()
+	now := time.Now()
+	report := func() *llrp.ROAccessReport {
+		return &llrp.ROAccessReport{TagReportData: []llrp.TagReportData{
+			tagReportData(t, nextEPC(), defaultAntenna, rssiWeak, now),
+		}}
+	}
+
+	// the first fills the queue, and the second has to wait
+	sp.Submit(NormalizeReport(report(), sensor, nil), reportInfo(sensor, now))
+	submitted := make(chan struct{})
+	go func() {
+		sp.Submit(NormalizeReport(report(), sensor, nil), reportInfo(sensor, now))
+		close(submitted)
+	}()
+
+	require.Eventually(t, func() bool { return sp.Metrics().Shards[0].Blocked == 1 },
+		time.Second, time.Millisecond)
+	m := sp.Metrics().Shards[0]
+	assert.Equal(t, 1, m.QueueLength)
+	assert.Zero(t, m.Reports)
+
+	close(release)
+	<-submitted
+	sp.Flush()
+
+	m = sp.Metrics().Shards[0]
+	assert.Equal(t, uint64(2), m.Reports)
+	assert.Equal(t, uint64(1), m.Blocked)
+	assert.Equal(t, int64(2), m.Tags)
+	assert.Len(t, sp.TakeEvents(), 2)
+}
+
+func TestShardedProcessor_CheckReaders(t *testing.T) {
+	sp := NewShardedProcessor(getTestingLogger(), watchdogConfig(60, true), nil, 4, 10)
+	defer sp.Close()
+
+	stale := []llrp.ReaderStatus{{Name: "r", Connected: true, ROSpecRunning: true, ROSpecRunningSince: 1}}
+	events := sp.CheckReaders(stale)
+	require.Len(t, events, 1)
+	assert.Equal(t, ReaderOfflineType, events[0].OfType())
+
+	// every shard knows the Reader is offline
+	sp.each(func(i int, tp *TagProcessor) {
+		assert.Contains(t, tp.watchdog.offline, "r", "shard %d", i)
+	})
+}
+
+func TestShardedProcessor_UpdateConfig(t *testing.T) {
+	cfg := NewConsulConfig()
+	sp := NewShardedProcessor(getTestingLogger(), cfg, nil, 2, 10)
+	defer sp.Close()
+
+	cfg.Aliases = map[string]string{"Reader_1": "Freezer", "": "ignored"}
+	sp.UpdateConfig(cfg)
+
+	// each shard has its own copy of the aliases
+	assert.Contains(t, cfg.Aliases, "")
+	sp.each(func(i int, tp *TagProcessor) {
+		assert.Equal(t, "Freezer", tp.getAlias("Reader_1"), "shard %d", i)
+		assert.NotContains(t, tp.config.aliases, "", "shard %d", i)
+	})
+}
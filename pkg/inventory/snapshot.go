@@ -0,0 +1,70 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SnapshotVersion1 is the original snapshot format: a bare JSON array of StaticTags.
+	SnapshotVersion1 = 1
+	// SnapshotVersion2 wraps the StaticTags in a Snapshot,
+	// which may include each location's RSSIHistory.
+	SnapshotVersion2 = 2
+
+	// SnapshotVersion is the version written by MarshalSnapshot.
+	SnapshotVersion = SnapshotVersion2
+)
+
+// Snapshot is the persisted form of the inventory.
+type Snapshot struct {
+	Version int         `json:"version"`
+	Tags    []StaticTag `json:"tags"`
+}
+
+// RSSIHistory is the raw content of a location's RSSI window,
+// from which it can be exactly reconstructed.
+type RSSIHistory struct {
+	WindowSize int `json:"window_size"`
+	// Index is the position of the oldest value within the window,
+	// which is also where the next value is inserted once the window is full.
+	Index int `json:"index"`
+	// Values and Timestamps are the window's RSSI values
+	// and when they were read (Unix Epoch milliseconds), oldest first.
+	Values     []float64 `json:"values"`
+	Timestamps []int64   `json:"timestamps"`
+}
+
+// MarshalSnapshot encodes the tags as the current SnapshotVersion.
+func MarshalSnapshot(tags []StaticTag) ([]byte, error) {
+	return json.Marshal(Snapshot{Version: SnapshotVersion, Tags: tags})
+}
+
+// UnmarshalSnapshot decodes tags persisted with MarshalSnapshot
+// or by earlier versions of the service.
+func UnmarshalSnapshot(data []byte) ([]StaticTag, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var tags []StaticTag
+		if err := json.Unmarshal(data, &tags); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal version %d snapshot", SnapshotVersion1)
+		}
+		return tags, nil
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal snapshot")
+	}
+
+	if s.Version < SnapshotVersion2 || s.Version > SnapshotVersion {
+		return nil, errors.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	return s.Tags, nil
+}
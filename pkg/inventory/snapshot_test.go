@@ -0,0 +1,83 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_versions(t *testing.T) {
+	tags := []StaticTag{{
+		EPC:      "3034",
+		Location: NewLocation("Reader-1", 1),
+		State:    Present,
+		StatsMap: map[string]StaticTagStats{"Reader-1_1": {LastRead: 1000, MeanRSSI: -60}},
+	}}
+
+	data, err := MarshalSnapshot(tags)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version":2`)
+
+	restored, err := UnmarshalSnapshot(data)
+	require.NoError(t, err)
+	assert.Equal(t, tags, restored)
+
+	// version 1 snapshots are bare arrays
+	restored, err = UnmarshalSnapshot([]byte(` [{"epc":"3034","stats_map":{"Reader-1_1":{"mean_rssi":-60}}}]`))
+	require.NoError(t, err)
+	require.Len(t, restored, 1)
+	assert.Equal(t, -60.0, restored[0].StatsMap["Reader-1_1"].MeanRSSI)
+
+	_, err = UnmarshalSnapshot([]byte(`{"version":99,"tags":[]}`))
+	assert.Error(t, err)
+	_, err = UnmarshalSnapshot([]byte(`{"tags":[]}`))
+	assert.Error(t, err)
+	_, err = UnmarshalSnapshot([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestSnapshot_rssiHistory(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AdjustLastReadOnByOrigin = false
+	cfg.ApplicationSettings.PersistRSSIHistory = true
+	ds := newTestDataset(cfg, 1)
+	epc := ds.epcs[0]
+	front, back := nextSensor(), nextSensor()
+	start := time.Now()
+
+	ds.readTag(t, epc, readParams{deviceName: front, antenna: defaultAntenna, rssi: rssiStrong, count: 10, lastSeen: start})
+	ds.readTag(t, epc, readParams{deviceName: back, antenna: defaultAntenna, rssi: rssiWeak, count: 3, lastSeen: start})
+
+	data, err := MarshalSnapshot(ds.tp.Snapshot())
+	require.NoError(t, err)
+	tags, err := UnmarshalSnapshot(data)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+
+	frontStats := tags[0].StatsMap[NewLocation(front, defaultAntenna).String()]
+	require.NotNil(t, frontStats.RSSIHistory)
+	assert.Len(t, frontStats.RSSIHistory.Values, 10)
+
+	tp := NewTagProcessor(getTestingLogger(), cfg, tags)
+	restored := tp.inventory[epc].getStats(NewLocation(front, defaultAntenna).String())
+	assert.Equal(t, *frontStats.RSSIHistory, restored.rssiDbm.History())
+
+	// a weak read barely changes a full history's mean
+	restoredDS := &testDataset{tp: tp, epcs: ds.epcs}
+	restoredDS.readTag(t, epc, readParams{deviceName: front, antenna: defaultAntenna, rssi: rssiWeak, lastSeen: start})
+	assert.InDelta(t, (10*rssiStrong+rssiWeak)/11, restored.rssiDbm.Mean(), 1e-9)
+
+	// without history, the processor restores only the mean
+	cfg.ApplicationSettings.PersistRSSIHistory = false
+	ds.tp.UpdateConfig(cfg)
+	tags = ds.tp.Snapshot()
+	assert.Nil(t, tags[0].StatsMap[NewLocation(front, defaultAntenna).String()].RSSIHistory)
+	restored = tags[0].asTagPtr().getStats(NewLocation(front, defaultAntenna).String())
+	assert.Equal(t, 1, restored.rssiCount())
+}
@@ -0,0 +1,183 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// snapshotFileMagic starts the header line of snapshot files written by WriteSnapshotFile.
+// The full header is "{magic} v{version} sha256:{hex checksum of the payload}\n".
+const snapshotFileMagic = "rfid-llrp-inventory-snapshot"
+
+// ErrSnapshotChecksum is returned when a snapshot file's payload doesn't match its header's checksum.
+var ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+
+// WriteSnapshotFile persists the tags to path so that a crash at any point
+// leaves either the old or the new snapshot intact.
+//
+// The snapshot is written with a version and checksum header to a temporary file
+// in the same directory, synced, and then renamed over path.
+// If backups is non-zero, the previous snapshot is first rotated
+// to "{path}.1", that one to "{path}.2", and so on, keeping at most backups of them.
+func WriteSnapshotFile(path string, tags []StaticTag, backups uint) error {
+	payload, err := MarshalSnapshot(tags)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(payload)
+	header := fmt.Sprintf("%s v%d sha256:%s\n", snapshotFileMagic, SnapshotVersion, hex.EncodeToString(sum[:]))
+
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary snapshot file")
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	if _, err := tmp.WriteString(header); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write snapshot")
+	}
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write snapshot")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to sync snapshot")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close snapshot")
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return errors.Wrap(err, "failed to set snapshot permissions")
+	}
+
+	if err := rotateSnapshotBackups(path, backups); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "failed to replace snapshot")
+	}
+
+	// sync the directory so the rename itself survives a crash
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// snapshotBackupPath returns the path of the nth backup of the snapshot at path.
+func snapshotBackupPath(path string, n uint) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// rotateSnapshotBackups shifts each of path's backups to the next number,
+// dropping the oldest, and then moves path to the first backup.
+// Missing files are skipped.
+func rotateSnapshotBackups(path string, backups uint) error {
+	if backups == 0 {
+		return nil
+	}
+
+	for n := backups - 1; n >= 1; n-- {
+		err := os.Rename(snapshotBackupPath(path, n), snapshotBackupPath(path, n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to rotate snapshot backup")
+		}
+	}
+
+	// link or copy rather than rename, so path always exists
+	first := snapshotBackupPath(path, 1)
+	if err := os.Remove(first); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to rotate snapshot backup")
+	}
+
+	err := os.Link(path, first)
+	if err == nil || os.IsNotExist(err) {
+		return nil
+	}
+
+	// some filesystems don't support hard links
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		err = ioutil.WriteFile(first, data, 0644)
+	}
+	return errors.Wrap(err, "failed to back up snapshot")
+}
+
+// ReadSnapshotFile returns the tags from the newest valid snapshot
+// among path and its backups (see WriteSnapshotFile),
+// along with the path from which they were read
+// and the reasons any newer files were skipped.
+//
+// Files without a header, as written by earlier versions of the service, are accepted
+// if they can be unmarshaled, though they can't be checked for corruption.
+// If there's no valid snapshot, it returns a nil slice and an empty source.
+func ReadSnapshotFile(path string, backups uint) (tags []StaticTag, source string, skipped []error) {
+	for n := uint(0); n <= backups; n++ {
+		p := path
+		if n > 0 {
+			p = snapshotBackupPath(path, n)
+		}
+
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				skipped = append(skipped, errors.Wrapf(err, "failed to read %q", p))
+			}
+			continue
+		}
+
+		tags, err := decodeSnapshotFile(data)
+		if err != nil {
+			skipped = append(skipped, errors.Wrapf(err, "invalid snapshot %q", p))
+			continue
+		}
+		return tags, p, skipped
+	}
+
+	return nil, "", skipped
+}
+
+// decodeSnapshotFile verifies the header of data, if present, and unmarshals its payload.
+func decodeSnapshotFile(data []byte) ([]StaticTag, error) {
+	if !bytes.HasPrefix(data, []byte(snapshotFileMagic)) {
+		return UnmarshalSnapshot(data)
+	}
+
+	nl := bytes.IndexByte(data, '\n')
+	if nl < 0 {
+		return nil, errors.New("snapshot header is truncated")
+	}
+	header, payload := string(data[:nl]), data[nl+1:]
+
+	var version int
+	var checksum string
+	if _, err := fmt.Sscanf(header, snapshotFileMagic+" v%d sha256:%s", &version, &checksum); err != nil {
+		return nil, errors.Wrapf(err, "malformed snapshot header %q", header)
+	}
+	if version > SnapshotVersion {
+		return nil, errors.Errorf("unsupported snapshot version %d", version)
+	}
+
+	sum := sha256.Sum256(payload)
+	if hex.EncodeToString(sum[:]) != checksum {
+		return nil, ErrSnapshotChecksum
+	}
+	return UnmarshalSnapshot(payload)
+}
@@ -0,0 +1,114 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotFile_rotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tags.json")
+
+	tags, source, skipped := ReadSnapshotFile(path, 2)
+	assert.Nil(t, tags)
+	assert.Empty(t, source)
+	assert.Empty(t, skipped)
+
+	snapshotOf := func(epc string) []StaticTag {
+		return []StaticTag{{EPC: epc, State: Present, StatsMap: map[string]StaticTagStats{}}}
+	}
+	for _, epc := range []string{"01", "02", "03", "04"} {
+		require.NoError(t, WriteSnapshotFile(path, snapshotOf(epc), 2))
+	}
+
+	// only the current snapshot and 2 backups are kept, with no leftover temporary files
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	assert.ElementsMatch(t, []string{"tags.json", "tags.json.1", "tags.json.2"}, names)
+
+	for p, epc := range map[string]string{path: "04", path + ".1": "03", path + ".2": "02"} {
+		data, err := ioutil.ReadFile(p)
+		require.NoError(t, err)
+		tags, err := decodeSnapshotFile(data)
+		require.NoError(t, err)
+		assert.Equal(t, snapshotOf(epc), tags)
+	}
+
+	tags, source, skipped = ReadSnapshotFile(path, 2)
+	assert.Equal(t, snapshotOf("04"), tags)
+	assert.Equal(t, path, source)
+	assert.Empty(t, skipped)
+}
+
+func TestSnapshotFile_fallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tags.json")
+
+	first := []StaticTag{{EPC: "01", State: Present, StatsMap: map[string]StaticTagStats{}}}
+	second := []StaticTag{{EPC: "02", State: Present, StatsMap: map[string]StaticTagStats{}}}
+	require.NoError(t, WriteSnapshotFile(path, first, 1))
+	require.NoError(t, WriteSnapshotFile(path, second, 1))
+
+	// simulate a corrupted write
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-3] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	tags, source, skipped := ReadSnapshotFile(path, 1)
+	assert.Equal(t, first, tags)
+	assert.Equal(t, path+".1", source)
+	require.Len(t, skipped, 1)
+	assert.ErrorIs(t, skipped[0], ErrSnapshotChecksum)
+
+	// a truncated file is also rejected
+	require.NoError(t, ioutil.WriteFile(path, data[:len(data)/2], 0644))
+	_, source, skipped = ReadSnapshotFile(path, 1)
+	assert.Equal(t, path+".1", source)
+	assert.Len(t, skipped, 1)
+
+	// with no valid files, there's nothing to restore
+	require.NoError(t, ioutil.WriteFile(path+".1", []byte("garbage"), 0644))
+	tags, source, skipped = ReadSnapshotFile(path, 1)
+	assert.Nil(t, tags)
+	assert.Empty(t, source)
+	assert.Len(t, skipped, 2)
+}
+
+func TestSnapshotFile_legacy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tags.json")
+
+	// written by earlier versions, without a header
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[{"epc":"01","state":"Present"}]`), 0644))
+	tags, source, skipped := ReadSnapshotFile(path, 3)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "01", tags[0].EPC)
+	assert.Equal(t, path, source)
+	assert.Empty(t, skipped)
+
+	// and it becomes the first backup
+	require.NoError(t, WriteSnapshotFile(path, nil, 3))
+	data, err := ioutil.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, `[{"epc":"01","state":"Present"}]`, string(data))
+}
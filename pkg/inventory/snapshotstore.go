@@ -0,0 +1,260 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SnapshotStore persists inventory snapshots.
+type SnapshotStore interface {
+	// Load returns the most recently saved snapshot,
+	// or an empty one if there isn't any.
+	Load() ([]StaticTag, error)
+	// Save replaces the persisted snapshot with tags.
+	Save(tags []StaticTag) error
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+const (
+	// FileSnapshotStoreType stores the whole snapshot in a single file.
+	FileSnapshotStoreType = "file"
+	// KVSnapshotStoreType stores each tag individually in an embedded key-value store.
+	KVSnapshotStoreType = "kv"
+
+	fileSnapshotName = "tags.json"
+	kvSnapshotName   = "tags.kv"
+)
+
+// NewSnapshotStore returns the SnapshotStore of the given type
+// with its data in the given directory.
+// The backups apply to the file and journal stores,
+// and to the snapshot file the kv store imports when it's created.
+func NewSnapshotStore(lc logger.LoggingClient, storeType, dir string, backups uint) (SnapshotStore, error) {
+	switch strings.ToLower(storeType) {
+	case FileSnapshotStoreType:
+		return NewFileSnapshotStore(lc, filepath.Join(dir, fileSnapshotName), backups), nil
+	case KVSnapshotStoreType:
+		return OpenKVSnapshotStore(lc, filepath.Join(dir, kvSnapshotName), filepath.Join(dir, fileSnapshotName), backups)
+	case JournalSnapshotStoreType:
+		return OpenJournalSnapshotStore(lc,
+			filepath.Join(dir, fileSnapshotName), filepath.Join(dir, journalName), backups)
+	default:
+		return nil, errors.Errorf("unknown snapshot store type %q", storeType)
+	}
+}
+
+// FileSnapshotStore saves the whole snapshot to a single file
+// using WriteSnapshotFile, and restores it with ReadSnapshotFile.
+type FileSnapshotStore struct {
+	lc      logger.LoggingClient
+	path    string
+	backups uint
+}
+
+// NewFileSnapshotStore returns a FileSnapshotStore which writes to path,
+// keeping the given number of backups.
+func NewFileSnapshotStore(lc logger.LoggingClient, path string, backups uint) *FileSnapshotStore {
+	return &FileSnapshotStore{lc: lc, path: path, backups: backups}
+}
+
+// Load returns the newest valid snapshot among the file and its backups,
+// logging any that it skipped.
+func (fs *FileSnapshotStore) Load() ([]StaticTag, error) {
+	tags, source, skipped := ReadSnapshotFile(fs.path, fs.backups)
+	for _, err := range skipped {
+		fs.lc.Warn("Skipped inventory snapshot.", "error", err.Error())
+	}
+
+	switch {
+	case source == "":
+		fs.lc.Warn("No valid inventory snapshot found.", "file", fs.path)
+	case len(skipped) > 0:
+		fs.lc.Warn("Using backup inventory snapshot.", "file", source)
+	default:
+		fs.lc.Debug("Read inventory snapshot.", "file", source)
+	}
+	return tags, nil
+}
+
+// Save writes the snapshot to the file.
+func (fs *FileSnapshotStore) Save(tags []StaticTag) error {
+	return WriteSnapshotFile(fs.path, tags, fs.backups)
+}
+
+// Close does nothing, since the file is only open while it's being read or written.
+func (fs *FileSnapshotStore) Close() error {
+	return nil
+}
+
+// KVSnapshotStore stores each tag individually in an embedded bbolt database,
+// keyed by its EPC.
+// It implements IncrementalSnapshotStore,
+// so the Engine only writes the tags which have changed since its last save.
+type KVSnapshotStore struct {
+	db *bolt.DB
+}
+
+var (
+	// kvTagsBucket holds the tags' JSON, keyed by EPC.
+	kvTagsBucket = []byte("tags")
+	// kvMetaBucket holds kvVersionKey.
+	kvMetaBucket = []byte("meta")
+	// kvVersionKey holds the SnapshotVersion of the tags in a KVSnapshotStore.
+	kvVersionKey = []byte("version")
+)
+
+// kvOpenTimeout is how long to wait for another process to release the database.
+const kvOpenTimeout = 5 * time.Second
+
+// OpenKVSnapshotStore opens or creates a KVSnapshotStore at path.
+//
+// When it creates the store, it imports the snapshot file at importPath, if there is one,
+// so switching to it from the file or journal stores keeps the inventory.
+// The backups are the number of that file's backups to consider, as for ReadSnapshotFile.
+func OpenKVSnapshotStore(lc logger.LoggingClient, path, importPath string, backups uint) (*KVSnapshotStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: kvOpenTimeout})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open key-value snapshot store")
+	}
+	ks := &KVSnapshotStore{db: db}
+
+	var created bool
+	err = db.Update(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket(kvMetaBucket); meta != nil {
+			v := meta.Get(kvVersionKey)
+			var version int
+			if err := json.Unmarshal(v, &version); err != nil || version > SnapshotVersion {
+				return errors.Errorf("unsupported snapshot version %q", v)
+			}
+			return nil
+		}
+
+		created = true
+		meta, err := tx.CreateBucket(kvMetaBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(kvTagsBucket); err != nil {
+			return err
+		}
+		return meta.Put(kvVersionKey, []byte(fmt.Sprint(SnapshotVersion)))
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to initialize key-value snapshot store")
+	}
+
+	if created && importPath != "" {
+		if err := ks.importFile(lc, importPath, backups); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// importFile saves the tags from the snapshot file at path, if there is one.
+func (ks *KVSnapshotStore) importFile(lc logger.LoggingClient, path string, backups uint) error {
+	tags, source, skipped := ReadSnapshotFile(path, backups)
+	for _, err := range skipped {
+		lc.Warn("Skipped inventory snapshot.", "error", err.Error())
+	}
+	if source == "" {
+		return nil
+	}
+
+	if err := ks.Save(tags); err != nil {
+		return errors.Wrapf(err, "failed to import snapshot %q", source)
+	}
+	lc.Info(fmt.Sprintf("Imported %d tags into the key-value snapshot store.", len(tags)), "file", source)
+	return nil
+}
+
+// Load returns the stored tags.
+// Tags which can't be unmarshaled are skipped, and reported in the returned error,
+// along with the valid tags.
+func (ks *KVSnapshotStore) Load() ([]StaticTag, error) {
+	var tags []StaticTag
+	var bad []string
+	err := ks.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(kvTagsBucket)
+		tags = make([]StaticTag, 0, b.Stats().KeyN)
+		return b.ForEach(func(k, v []byte) error {
+			var tag StaticTag
+			if err := json.Unmarshal(v, &tag); err != nil {
+				bad = append(bad, string(k))
+				return nil
+			}
+			tags = append(tags, tag)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read key-value snapshot store")
+	}
+
+	if len(bad) > 0 {
+		return tags, errors.Errorf("skipped %d invalid tags: %s", len(bad), strings.Join(bad, ", "))
+	}
+	return tags, nil
+}
+
+// Save replaces all the stored tags with the given ones.
+func (ks *KVSnapshotStore) Save(tags []StaticTag) error {
+	return ks.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(kvTagsBucket); err != nil {
+			return errors.Wrap(err, "failed to clear tags")
+		}
+		b, err := tx.CreateBucket(kvTagsBucket)
+		if err != nil {
+			return errors.Wrap(err, "failed to clear tags")
+		}
+		return putTags(b, tags)
+	})
+}
+
+// SaveChanges writes the changed tags and deletes the removed ones.
+func (ks *KVSnapshotStore) SaveChanges(changed []StaticTag, removed []string) error {
+	return ks.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(kvTagsBucket)
+		if err := putTags(b, changed); err != nil {
+			return err
+		}
+		for _, epc := range removed {
+			if err := b.Delete([]byte(epc)); err != nil {
+				return errors.Wrapf(err, "failed to delete tag %s", epc)
+			}
+		}
+		return nil
+	})
+}
+
+func putTags(b *bolt.Bucket, tags []StaticTag) error {
+	for _, tag := range tags {
+		data, err := json.Marshal(tag)
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal tag %s", tag.EPC)
+		}
+		if err := b.Put([]byte(tag.EPC), data); err != nil {
+			return errors.Wrapf(err, "failed to write tag %s", tag.EPC)
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying database.
+func (ks *KVSnapshotStore) Close() error {
+	return ks.db.Close()
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSnapshotStore(t *testing.T) {
+	tags := []StaticTag{
+		{EPC: "01", State: Present, StatsMap: map[string]StaticTagStats{"r_1": {LastRead: 10, MeanRSSI: -50}}},
+		{EPC: "02", State: Departed, StatsMap: map[string]StaticTagStats{}},
+	}
+
+	for _, storeType := range []string{FileSnapshotStoreType, KVSnapshotStoreType} {
+		dir, err := ioutil.TempDir("", "snapshotstore")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		store, err := NewSnapshotStore(getTestingLogger(), storeType, dir, 1)
+		require.NoError(t, err, storeType)
+
+		loaded, err := store.Load()
+		require.NoError(t, err, storeType)
+		assert.Empty(t, loaded, storeType)
+
+		require.NoError(t, store.Save(tags), storeType)
+		require.NoError(t, store.Close(), storeType)
+
+		store, err = NewSnapshotStore(getTestingLogger(), storeType, dir, 1)
+		require.NoError(t, err, storeType)
+		loaded, err = store.Load()
+		require.NoError(t, err, storeType)
+		assert.ElementsMatch(t, tags, loaded, storeType)
+		require.NoError(t, store.Close(), storeType)
+	}
+
+	_, err := NewSnapshotStore(getTestingLogger(), "bolt", os.TempDir(), 1)
+	assert.Error(t, err)
+}
+
+func TestKVSnapshotStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshotstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tags.kv")
+
+	store, err := OpenKVSnapshotStore(getTestingLogger(), path, "", 0)
+	require.NoError(t, err)
+
+	tags := []StaticTag{journalTag("01", 1), journalTag("02", 2), journalTag("03", 3)}
+	require.NoError(t, store.Save(tags))
+
+	// changes update just the changed and removed tags
+	changed := journalTag("02", 20)
+	added := journalTag("04", 4)
+	require.NoError(t, store.SaveChanges([]StaticTag{changed, added}, []string{"01"}))
+	require.NoError(t, store.Close())
+
+	store, err = OpenKVSnapshotStore(getTestingLogger(), path, "", 0)
+	require.NoError(t, err)
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []StaticTag{changed, tags[2], added}, loaded)
+
+	// a full save replaces everything
+	require.NoError(t, store.Save(tags[:1]))
+	loaded, err = store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, tags[:1], loaded)
+	require.NoError(t, store.Close())
+}
+
+func TestKVSnapshotStore_import(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshotstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tags := []StaticTag{journalTag("01", 1), journalTag("02", 2)}
+	require.NoError(t, NewFileSnapshotStore(getTestingLogger(), filepath.Join(dir, fileSnapshotName), 1).Save(tags))
+
+	// switching from the file store keeps the inventory
+	store, err := NewSnapshotStore(getTestingLogger(), KVSnapshotStoreType, dir, 1)
+	require.NoError(t, err)
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, tags, loaded)
+
+	// but the file is only imported when the store is created
+	require.NoError(t, store.Save(nil))
+	require.NoError(t, store.Close())
+	store, err = NewSnapshotStore(getTestingLogger(), KVSnapshotStoreType, dir, 1)
+	require.NoError(t, err)
+	defer store.Close()
+	loaded, err = store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, loaded)
+}
@@ -0,0 +1,129 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+// StaticTag represents a Tag object stuck in time for use with APIs
+type StaticTag struct {
+	// EPC stands for Electronic Product Code. EPC was designed as a universal identifier
+	// system to provides a unique identity for every physical object in the world.
+	EPC string `json:"epc"`
+	// TID is commonly referred to as Tag ID or Transponder ID. It is a unique number written to
+	// every RFID tag by the manufacturer and is non-writable.
+	TID string `json:"tid"`
+	// Location keeps track of the tag's current location in the form of Device and Antenna combo.
+	Location Location `json:"location"`
+	// LocationAlias returns the string version of the location adjusted for any user-provided aliases.
+	LocationAlias string `json:"location_alias"`
+	// LastRead keeps track of the last time the tag was seen by any reader/antenna
+	// (Unix Epoch milliseconds). This value is used to determine AgeOut as
+	// well as Departed events.
+	LastRead int64 `json:"last_read"`
+	// LastArrived keeps track of the most recent time this tag generated an ArrivedEvent.
+	// (Unix Epoch milliseconds).
+	LastArrived int64 `json:"last_arrived"`
+	// LastArrived keeps track of the most recent time this tag generated an ArrivedEvent.
+	// (Unix Epoch milliseconds).
+	LastDeparted int64 `json:"last_departed"`
+	// State is the current state of the tag (Present, Departed, Unknown)
+	State TagState `json:"state"`
+	// StatsMap keeps track of read statistics on a per-antenna basis in order to apply
+	// tag location algorithms against.
+	StatsMap map[string]StaticTagStats `json:"stats_map"`
+	// GatewayPosition is the tag's most recent position as estimated by an Impinj gateway Reader,
+	// if any have reported it.
+	GatewayPosition *GatewayPosition `json:"gateway_position,omitempty"`
+	// Position is the tag's estimated position based on reads from antennas
+	// with configured AntennaPositions, if any have read it.
+	Position *Position `json:"position,omitempty"`
+}
+
+// StaticTagStats represents a tagStats object stuck in time for use with APIs
+// and includes pre-calculated data
+type StaticTagStats struct {
+	LastRead int64 `json:"last_read"`
+	// ReadCount is the total number of reads at the location since the tag's stats were last reset.
+	ReadCount uint64 `json:"read_count"`
+
+	// The following are calculated from the RSSI values in the location's window.
+
+	MeanRSSI   float64 `json:"mean_rssi"`
+	MinRSSI    float64 `json:"min_rssi"`
+	MaxRSSI    float64 `json:"max_rssi"`
+	MedianRSSI float64 `json:"median_rssi"`
+	StdDevRSSI float64 `json:"stddev_rssi"`
+	// ReadsPerSecond is the rate of reads within the window.
+	ReadsPerSecond float64 `json:"reads_per_second"`
+	// RSSIHistory is the window's raw content,
+	// which is only included if PersistRSSIHistory is enabled.
+	RSSIHistory *RSSIHistory `json:"rssi_history,omitempty"`
+
+	// The following are only present if the Reader reports them,
+	// and hold the values from the most recent read that included them.
+
+	// PhaseRadians is the RF phase angle of the tag's backscatter, in [0, 2π).
+	PhaseRadians *float64 `json:"phase_radians,omitempty"`
+	// DopplerHz is the RF Doppler frequency of the tag's backscatter.
+	DopplerHz *float64 `json:"doppler_hz,omitempty"`
+	// ChannelIndex is the 1-based index into the Reader's frequency table
+	// of the channel on which the tag was read.
+	ChannelIndex *uint16 `json:"channel_index,omitempty"`
+}
+
+// asTagPtr converts a StaticTag back to a Tag pointer for use in restoring inventory.
+// It will also restore the per-location stats, exactly if they include their RSSIHistory,
+// or otherwise by setting the last read timestamp and a single RSSI value
+// which was the previously computed rolling average.
+func (s StaticTag) asTagPtr() *Tag {
+	t := &Tag{
+		EPC:          s.EPC,
+		TID:          s.TID,
+		Location:     s.Location,
+		LastRead:     s.LastRead,
+		LastDeparted: s.LastDeparted,
+		LastArrived:  s.LastArrived,
+		state:        s.State,
+		statsMap:     make(map[string]*tagStats),
+
+		GatewayPosition:  s.GatewayPosition,
+		Position:         s.Position,
+		reportedPosition: s.Position,
+	}
+
+	// fill in any cached tag stats. without the history, this just adds the mean rssi
+	// as a single value, so some precision is lost by not having every single value,
+	// but it preserves a general view of the data
+	for location, stats := range s.StatsMap {
+		tagStats := t.getStats(location)
+		tagStats.lastRead = stats.LastRead
+		tagStats.readCount = stats.ReadCount
+		if buff, ok := stats.restoreHistory(); ok {
+			tagStats.rssiDbm = buff
+		} else {
+			tagStats.rssiDbm.AddValueAt(stats.MeanRSSI, stats.LastRead)
+		}
+		if stats.PhaseRadians != nil {
+			tagStats.updatePhase(*stats.PhaseRadians)
+		}
+		if stats.DopplerHz != nil {
+			tagStats.updateDoppler(*stats.DopplerHz)
+		}
+		if stats.ChannelIndex != nil {
+			tagStats.updateChannel(*stats.ChannelIndex)
+		}
+	}
+
+	return t
+}
+
+// restoreHistory returns a circularBuffer reconstructed from the stats' RSSIHistory,
+// or false if it doesn't have a usable one.
+func (s StaticTagStats) restoreHistory() (*circularBuffer, bool) {
+	if s.RSSIHistory == nil || len(s.RSSIHistory.Values) == 0 {
+		return nil, false
+	}
+	buff, err := newCircularBufferFromHistory(*s.RSSIHistory)
+	return buff, err == nil
+}
@@ -0,0 +1,203 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"context"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"sync"
+	"sync/atomic"
+)
+
+// Subscribers is a registry of EventHandlers, each of which receives the events
+// published to it, optionally restricted to certain EventTypes.
+//
+// Synchronous subscribers are called in the order they subscribed,
+// on the goroutine which publishes the events,
+// so a slow one delays the rest.
+// Asynchronous subscribers each have their own goroutine and queue;
+// if one's queue is full, the events it would have received are dropped.
+//
+// Its methods are safe to call from multiple goroutines,
+// but synchronous handlers must not subscribe or unsubscribe.
+type Subscribers struct {
+	lc logger.LoggingClient
+
+	mu     sync.RWMutex
+	subs   []*subscriber
+	nextID int
+	closed bool
+}
+
+type subscriber struct {
+	id      int
+	handler EventHandler
+	// types are the EventTypes the subscriber wants, or nil for all of them.
+	types map[EventType]bool
+
+	// queue holds an asynchronous subscriber's pending events; it's nil for synchronous ones.
+	queue   chan publication
+	done    chan struct{}
+	dropped uint64
+}
+
+type publication struct {
+	ctx    context.Context
+	events []Event
+}
+
+// SubscribeOption configures a subscription.
+type SubscribeOption func(s *subscriber)
+
+// ForTypes restricts a subscription to events of the given types.
+// Handlers aren't called for batches with none of them.
+func ForTypes(types ...EventType) SubscribeOption {
+	return func(s *subscriber) {
+		if s.types == nil {
+			s.types = make(map[EventType]bool, len(types))
+		}
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+}
+
+// Async delivers a subscription's events on its own goroutine,
+// queueing up to queueSize batches of events.
+func Async(queueSize int) SubscribeOption {
+	return func(s *subscriber) {
+		if queueSize < 1 {
+			queueSize = 1
+		}
+		s.queue = make(chan publication, queueSize)
+	}
+}
+
+// NewSubscribers returns an empty registry
+// which logs when an asynchronous subscriber drops events.
+func NewSubscribers(lc logger.LoggingClient) *Subscribers {
+	return &Subscribers{lc: lc}
+}
+
+// Subscribe adds a handler to the registry and returns a function which removes it.
+// Once it's removed, an asynchronous subscriber finishes handling its queued events.
+// If the registry is closed, the handler is never called.
+func (subs *Subscribers) Subscribe(h EventHandler, opts ...SubscribeOption) (unsubscribe func()) {
+	s := &subscriber{handler: h}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+	if subs.closed {
+		return func() {}
+	}
+
+	subs.nextID++
+	s.id = subs.nextID
+	if s.queue != nil {
+		s.done = make(chan struct{})
+		go s.run()
+	}
+	subs.subs = append(subs.subs, s)
+
+	var once sync.Once
+	return func() { once.Do(func() { subs.remove(s.id) }) }
+}
+
+// remove removes the subscriber with the given ID, if it's still subscribed.
+func (subs *Subscribers) remove(id int) {
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+	for i, s := range subs.subs {
+		if s.id != id {
+			continue
+		}
+		subs.subs = append(subs.subs[:i], subs.subs[i+1:]...)
+		s.stop()
+		return
+	}
+}
+
+// Publish sends the events to each subscriber which wants them.
+func (subs *Subscribers) Publish(ctx context.Context, events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	subs.mu.RLock()
+	defer subs.mu.RUnlock()
+	for _, s := range subs.subs {
+		wanted := s.filter(events)
+		if len(wanted) == 0 {
+			continue
+		}
+
+		if s.queue == nil {
+			s.handler(ctx, wanted)
+			continue
+		}
+
+		select {
+		case s.queue <- publication{ctx, wanted}:
+		default:
+			n := atomic.AddUint64(&s.dropped, uint64(len(wanted)))
+			subs.lc.Warn("Event subscriber's queue is full; dropping events.",
+				"subscriber", s.id, "events", len(wanted), "totalDropped", n)
+		}
+	}
+}
+
+// Close removes all subscribers, waiting for asynchronous ones
+// to finish handling their queued events.
+// Afterwards, Publish does nothing, and Subscribe doesn't add subscribers.
+func (subs *Subscribers) Close() {
+	subs.mu.Lock()
+	removed := subs.subs
+	subs.subs = nil
+	subs.closed = true
+	for _, s := range removed {
+		s.stop()
+	}
+	subs.mu.Unlock()
+
+	for _, s := range removed {
+		if s.done != nil {
+			<-s.done
+		}
+	}
+}
+
+// filter returns the events the subscriber wants.
+func (s *subscriber) filter(events []Event) []Event {
+	if s.types == nil {
+		return events
+	}
+
+	var wanted []Event
+	for _, e := range events {
+		if s.types[e.OfType()] {
+			wanted = append(wanted, e)
+		}
+	}
+	return wanted
+}
+
+// run handles an asynchronous subscriber's events until its queue is closed.
+func (s *subscriber) run() {
+	defer close(s.done)
+	for p := range s.queue {
+		s.handler(p.ctx, p.events)
+	}
+}
+
+// stop closes an asynchronous subscriber's queue. The caller must hold the write lock.
+func (s *subscriber) stop() {
+	if s.queue != nil {
+		close(s.queue)
+	}
+}
@@ -0,0 +1,83 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSubscribers(t *testing.T) {
+	subs := NewSubscribers(getTestingLogger())
+	ctx := context.Background()
+
+	var all, departed [][]Event
+	unsubscribe := subs.Subscribe(func(_ context.Context, events []Event) { all = append(all, events) })
+	subs.Subscribe(func(_ context.Context, events []Event) { departed = append(departed, events) },
+		ForTypes(DepartedType))
+
+	arrived := ArrivedEvent{BaseEvent: BaseEvent{EPC: "01"}}
+	gone := DepartedEvent{BaseEvent: BaseEvent{EPC: "02"}}
+	subs.Publish(ctx, []Event{arrived, gone})
+	subs.Publish(ctx, []Event{arrived})
+	subs.Publish(ctx, nil)
+
+	assert.Equal(t, [][]Event{{arrived, gone}, {arrived}}, all)
+	// typed subscribers only get events of their types, and only when there are some
+	assert.Equal(t, [][]Event{{gone}}, departed)
+
+	unsubscribe()
+	unsubscribe()
+	subs.Publish(ctx, []Event{gone})
+	assert.Len(t, all, 2)
+	assert.Len(t, departed, 2)
+}
+
+func TestSubscribers_async(t *testing.T) {
+	subs := NewSubscribers(getTestingLogger())
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	received := make(chan []Event, 10)
+	subs.Subscribe(func(_ context.Context, events []Event) {
+		<-release
+		received <- events
+	}, Async(1))
+
+	var inline [][]Event
+	subs.Subscribe(func(_ context.Context, events []Event) { inline = append(inline, events) })
+
+	// the first batch is being handled, the second is queued, and the third is dropped,
+	// but none of that delays the synchronous subscriber
+	batch := func(epc string) []Event { return []Event{ArrivedEvent{BaseEvent: BaseEvent{EPC: epc}}} }
+	first, second, third := batch("01"), batch("02"), batch("03")
+	subs.Publish(ctx, first)
+	require.Eventually(t, func() bool { return len(subs.subs[0].queue) == 0 },
+		time.Second, time.Millisecond)
+	subs.Publish(ctx, second)
+	subs.Publish(ctx, third)
+	assert.Equal(t, [][]Event{first, second, third}, inline)
+	assert.Equal(t, uint64(1), subs.subs[0].dropped)
+
+	// closing waits for the queued events to be handled
+	close(release)
+	subs.Close()
+	close(received)
+	var got [][]Event
+	for events := range received {
+		got = append(got, events)
+	}
+	assert.Equal(t, [][]Event{first, second}, got)
+
+	// once closed, nothing is delivered
+	called := false
+	subs.Subscribe(func(context.Context, []Event) { called = true })()
+	subs.Publish(ctx, first)
+	assert.False(t, called)
+}
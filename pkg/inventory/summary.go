@@ -0,0 +1,94 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"sort"
+)
+
+// InventorySummaryEvent is generated every SummaryIntervalSeconds to summarize the inventory,
+// so consumers which missed events can reconcile without polling the snapshot.
+//
+// If the summary includes EPCs, it's split into Chunks events
+// with at most SummaryChunkSize EPCs each,
+// numbered from 0 and sharing the same Timestamp.
+// Only the first has the Counts.
+type InventorySummaryEvent struct {
+	// Timestamp is the time at which the summary was taken (Unix Epoch milliseconds).
+	Timestamp int64 `json:"timestamp"`
+	Chunk     int   `json:"chunk"`
+	Chunks    int   `json:"chunks"`
+	// Counts has the number of tags in each state by location alias.
+	// Departed tags are counted at their last known location.
+	Counts map[string]map[TagState]int `json:"counts,omitempty"`
+	// EPCs has the EPCs of the Present tags by location alias, if SummaryIncludeEPCs is set.
+	EPCs map[string][]string `json:"epcs,omitempty"`
+}
+
+// OfType for InventorySummaryEvent returns InventorySummaryType
+func (s InventorySummaryEvent) OfType() EventType {
+	return InventorySummaryType
+}
+
+// NewInventorySummary returns the events which summarize the snapshot.
+// If includeEPCs is true, they list the Present tags' EPCs, sorted by alias and EPC,
+// split into chunks of at most chunkSize EPCs.
+// There's always at least one event, even if the inventory is empty.
+func NewInventorySummary(snapshot []StaticTag, includeEPCs bool, chunkSize int, timestamp int64) []Event {
+	counts := map[string]map[TagState]int{}
+	present := map[string][]string{}
+	for _, tag := range snapshot {
+		if counts[tag.LocationAlias] == nil {
+			counts[tag.LocationAlias] = map[TagState]int{}
+		}
+		counts[tag.LocationAlias][tag.State]++
+		if includeEPCs && tag.State == Present {
+			present[tag.LocationAlias] = append(present[tag.LocationAlias], tag.EPC)
+		}
+	}
+
+	summaries := []InventorySummaryEvent{{Timestamp: timestamp, Counts: counts}}
+	if includeEPCs {
+		if chunkSize < 1 {
+			chunkSize = 1
+		}
+
+		aliases := make([]string, 0, len(present))
+		for alias := range present {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+
+		current, n := &summaries[0], 0
+		for _, alias := range aliases {
+			epcs := present[alias]
+			sort.Strings(epcs)
+			for len(epcs) > 0 {
+				if n == chunkSize {
+					summaries = append(summaries, InventorySummaryEvent{Timestamp: timestamp})
+					current, n = &summaries[len(summaries)-1], 0
+				}
+				take := chunkSize - n
+				if take > len(epcs) {
+					take = len(epcs)
+				}
+				if current.EPCs == nil {
+					current.EPCs = map[string][]string{}
+				}
+				current.EPCs[alias] = append(current.EPCs[alias], epcs[:take]...)
+				epcs, n = epcs[take:], n+take
+			}
+		}
+	}
+
+	events := make([]Event, len(summaries))
+	for i := range summaries {
+		summaries[i].Chunk = i
+		summaries[i].Chunks = len(summaries)
+		events[i] = summaries[i]
+	}
+	return events
+}
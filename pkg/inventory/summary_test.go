@@ -0,0 +1,71 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewInventorySummary(t *testing.T) {
+	snapshot := []StaticTag{
+		{EPC: "04", LocationAlias: "Freezer", State: Present},
+		{EPC: "01", LocationAlias: "Freezer", State: Present},
+		{EPC: "02", LocationAlias: "Door", State: Present},
+		{EPC: "03", LocationAlias: "Freezer", State: Departed},
+		{EPC: "05", LocationAlias: "Freezer", State: Present},
+	}
+	counts := map[string]map[TagState]int{
+		"Door":    {Present: 1},
+		"Freezer": {Present: 3, Departed: 1},
+	}
+
+	assert.Equal(t, []Event{
+		InventorySummaryEvent{Timestamp: 10, Chunks: 1, Counts: counts},
+	}, NewInventorySummary(snapshot, false, 2, 10))
+
+	// present EPCs are sorted by alias, then EPC, and split into chunks
+	assert.Equal(t, []Event{
+		InventorySummaryEvent{Timestamp: 10, Chunk: 0, Chunks: 2, Counts: counts,
+			EPCs: map[string][]string{"Door": {"02"}, "Freezer": {"01"}}},
+		InventorySummaryEvent{Timestamp: 10, Chunk: 1, Chunks: 2,
+			EPCs: map[string][]string{"Freezer": {"04", "05"}}},
+	}, NewInventorySummary(snapshot, true, 2, 10))
+
+	// an empty inventory still gets a summary
+	assert.Equal(t, []Event{
+		InventorySummaryEvent{Timestamp: 10, Chunks: 1, Counts: map[string]map[TagState]int{}},
+	}, NewInventorySummary(nil, true, 2, 10))
+}
+
+func TestEngine_summary(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.SummaryIntervalSeconds = 1
+	cfg.ApplicationSettings.SummaryIncludeEPCs = true
+	e, events, stop := startEngine(t, WithConfig(cfg))
+	defer stop()
+
+	sensor, epc := nextSensor(), nextEPC()
+	require.NoError(t, e.ProcessReads(context.Background(), []TagRead{{EPC: epc, DeviceName: sensor}}))
+	arrived := nextEvents(t, events)
+	require.Len(t, arrived, 1)
+	alias := arrived[0].(ArrivedEvent).Location
+
+	var summary []Event
+	select {
+	case summary = <-events:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for summary")
+	}
+	require.Len(t, summary, 1)
+	s, ok := summary[0].(InventorySummaryEvent)
+	require.True(t, ok)
+	assert.Equal(t, map[string]map[TagState]int{alias: {Present: 1}}, s.Counts)
+	assert.Equal(t, map[string][]string{alias: {epc}}, s.EPCs)
+}
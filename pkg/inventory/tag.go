@@ -41,7 +41,15 @@ type Tag struct {
 	// LastArrived keeps track of the most recent time this tag generated an ArrivedEvent.
 	// (Unix Epoch milliseconds).
 	LastArrived int64
+	// GatewayPosition is the tag's most recent position as estimated by an Impinj gateway Reader
+	// operating in its Location role, or nil if none has reported it.
+	GatewayPosition *GatewayPosition
+	// Position is the tag's estimated position based on reads from antennas
+	// with configured positions, or nil if none have read it.
+	Position *Position
 
+	// reportedPosition is the position in the tag's most recent PositionUpdated event.
+	reportedPosition *Position
 	// state is the current state of the tag (Present, Departed, Unknown)
 	state TagState
 	// statsMap keeps track of read statistics on a per-antenna basis in order to apply
@@ -49,6 +57,18 @@ type Tag struct {
 	statsMap map[string]*tagStats
 	// statsMu is a mutex to synchronize access to the statsMap
 	statsMu sync.Mutex
+	// static caches the tag's StaticTag until it next changes.
+	static *StaticTag
+}
+
+// GatewayPosition is a tag's position relative to the gateway Reader which estimated it.
+type GatewayPosition struct {
+	DeviceName string `json:"device_name"`
+	// XCentimeters and YCentimeters use the coordinate system configured on the Reader.
+	XCentimeters int32 `json:"x_cm"`
+	YCentimeters int32 `json:"y_cm"`
+	// Timestamp is when the tag was last seen at this position (Unix Epoch milliseconds).
+	Timestamp int64 `json:"timestamp"`
 }
 
 // NewTag creates a new tag object with the specified EPC. THe state is set to Unknown and
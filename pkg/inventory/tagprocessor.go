@@ -0,0 +1,620 @@
+//
+// Copyright (C) 2020 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"encoding/hex"
+	"fmt"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	contract "github.com/edgexfoundry/go-mod-core-contracts/models"
+	"strings"
+	"time"
+)
+
+type processorConfig struct {
+	profile mobilityProfile
+	aliases map[string]string
+
+	departedThresholdSeconds uint
+	ageOutHours              uint
+	adjustLastReadOnByOrigin bool
+
+	readerOfflineThresholdSeconds     uint
+	suppressDepartedForOfflineReaders bool
+
+	// antennaPositions maps default location names to their antennas' positions.
+	antennaPositions              map[string]AntennaPosition
+	positionUpdateThresholdMeters float64
+	positionWindowMillis          int64
+
+	rssiWindow         rssiWindow
+	persistRSSIHistory bool
+
+	// debugLogEnabled is used to be able to only log things when Debug logging is enabled
+	// note: this should be something that is able to be determined via the logger.LoggingClient,
+	// however currently EdgeX does not support querying the log level
+	// see: https://github.com/edgexfoundry/go-mod-core-contracts/issues/294
+	debugLogEnabled bool
+}
+
+// TagProcessor holds the current inventory data and processes incoming tag read data
+type TagProcessor struct {
+	lc        logger.LoggingClient
+	inventory map[string]*Tag
+	config    processorConfig
+	watchdog  readerWatchdog
+
+	// changed and removed are the EPCs of tags updated or removed since the last TakeChanges.
+	changed map[string]bool
+	removed map[string]bool
+}
+
+// NewTagProcessor creates a tag processor and pre-loads its mobility profile
+func NewTagProcessor(lc logger.LoggingClient, cfg ConsulConfig, tags []StaticTag) *TagProcessor {
+	tp := &TagProcessor{
+		lc:        lc,
+		inventory: make(map[string]*Tag),
+		watchdog:  newReaderWatchdog(),
+		changed:   make(map[string]bool),
+		removed:   make(map[string]bool),
+	}
+	tp.UpdateConfig(cfg)
+
+	for _, t := range tags {
+		tp.inventory[t.EPC] = t.asTagPtr()
+	}
+
+	return tp
+}
+
+// UpdateConfig takes in a ConsulConfig raw config object and converts it into a locally cached
+// version that is understood by the TagProcessor. It also generates the correct mobility profile
+// based on the supplied values, and the alias map as well.
+func (tp *TagProcessor) UpdateConfig(cfg ConsulConfig) {
+	as := cfg.ApplicationSettings
+	profile := newMobilityProfile(as.MobilityProfileSlope, as.MobilityProfileThreshold, as.MobilityProfileHoldoffMillis)
+	aliases := cfg.Aliases
+	delete(aliases, "")
+
+	// Validate has already checked that these parse.
+	positions, err := ParseAntennaPositions(as.AntennaPositions)
+	if err != nil {
+		tp.lc.Error("Ignoring invalid AntennaPositions.", "error", err.Error())
+		positions = nil
+	}
+
+	logLevel := strings.ToUpper(cfg.Writable.LogLevel)
+	tp.config = processorConfig{
+		adjustLastReadOnByOrigin: as.AdjustLastReadOnByOrigin,
+		departedThresholdSeconds: as.DepartedThresholdSeconds,
+		ageOutHours:              as.AgeOutHours,
+		debugLogEnabled:          logLevel == contract.DebugLog || logLevel == contract.TraceLog,
+		profile:                  profile,
+		aliases:                  aliases,
+
+		readerOfflineThresholdSeconds:     as.ReaderOfflineThresholdSeconds,
+		suppressDepartedForOfflineReaders: as.SuppressDepartedForOfflineReaders,
+
+		antennaPositions:              positions,
+		positionUpdateThresholdMeters: as.PositionUpdateThresholdMeters,
+		positionWindowMillis:          int64(as.PositionWindowSeconds) * 1000,
+
+		rssiWindow: rssiWindow{
+			size:         int(as.RSSIWindowSize),
+			maxAgeMillis: int64(as.RSSIWindowSeconds) * 1000,
+		},
+		persistRSSIHistory: as.PersistRSSIHistory,
+	}
+
+	// aliases and persistRSSIHistory change tags' StaticTags
+	for _, tag := range tp.inventory {
+		tag.static = nil
+	}
+}
+
+// ProcessReport takes an incoming ROAccessReport and processes each TagReportData.
+// For every TagReportData it will update the corresponding tag our in-memory tag database
+// based on the latest information.
+//
+// It doesn't return a snapshot of the inventory, since building one is expensive
+// for large inventories; use Snapshot or TakeChanges when one is needed.
+//
+// The report's TagReportData must not have ambiguous nil parameters;
+// to process reports which might, use NormalizeReport and ProcessNormalizedReport.
+func (tp *TagProcessor) ProcessReport(r *llrp.ROAccessReport, info ReportInfo) (events []Event) {
+	return tp.ProcessNormalizedReport(NormalizeReport(r, info.DeviceName, nil), info)
+}
+
+// ProcessNormalizedReport is like ProcessReport, but for a report from NormalizeReport.
+func (tp *TagProcessor) ProcessNormalizedReport(nr *NormalizedReport, info ReportInfo) (events []Event) {
+	if tp.config.adjustLastReadOnByOrigin {
+		info.adjustOffset(nr.lastSeenMicros)
+	}
+	return tp.processReads(nr.reads, nr.locs, nr.dirs, info)
+}
+
+// ProcessReads processes reads which didn't come from an LLRP report,
+// updating the inventory just as ProcessReport would.
+//
+// Their Timestamps must already be in terms of this host's clock,
+// since there's no report origin by which to adjust them.
+// Reads without a Timestamp are treated as happening now,
+// and their Timestamp is set accordingly.
+func (tp *TagProcessor) ProcessReads(reads []TagRead) (events []Event) {
+	now := UnixMilliNow()
+	for i := range reads {
+		if reads[i].Timestamp == 0 {
+			reads[i].Timestamp = now
+		}
+		events = tp.processRead(&reads[i], reads[i].Timestamp, events)
+	}
+	return events
+}
+
+// processReads processes the reads of a report whose offset has already been adjusted.
+// It sets the Timestamp of each read which has a Reader timestamp
+// to that timestamp plus the offset.
+func (tp *TagProcessor) processReads(reads []TagRead, locs []llrp.ImpinjTagLocation,
+	dirs []llrp.ImpinjTagDirection, info ReportInfo) (events []Event) {
+	for i := range reads {
+		if reads[i].lastSeenMicros != 0 {
+			// offset each read, divide by 1000 to go from microseconds to milliseconds
+			reads[i].Timestamp = (reads[i].lastSeenMicros + info.offsetMicros) / 1000
+		}
+		events = tp.processRead(&reads[i], info.referenceTimestamp, events)
+	}
+	for _, loc := range locs {
+		if event := tp.processGatewayLocation(loc, info); event != nil {
+			events = append(events, event)
+		}
+	}
+	for _, dir := range dirs {
+		if event := tp.processGatewayDirection(dir, info); event != nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// processRead processes a single read and appends its resulting events, if any.
+func (tp *TagProcessor) processRead(read *TagRead, referenceMillis int64, events []Event) []Event {
+	if event := tp.processData(read, referenceMillis); event != nil {
+		events = append(events, event)
+	}
+
+	if len(tp.config.antennaPositions) == 0 {
+		return events
+	}
+	if tag, ok := tp.inventory[read.EPC]; ok {
+		if event := tp.updatePosition(tag); event != nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// getAlias returns the alias associated with a location if one has been defined,
+// otherwise it returns back the original location.
+func (tp *TagProcessor) getAlias(location string) string {
+	if alias, exists := tp.config.aliases[location]; exists && alias != "" {
+		return alias
+	}
+	return location
+}
+
+// Snapshot takes a snapshot of the entire tag inventory as a slice of StaticTag objects.
+// It does this by converting the inventory map of Tag pointers into a flat slice
+// of non-pointer StaticTags.
+//
+// Each tag's StaticTag is cached until the tag next changes,
+// so only those which changed since the last call are rebuilt.
+// The StaticTags share their StatsMaps with the cache and other snapshots,
+// so they must not be modified.
+func (tp *TagProcessor) Snapshot() []StaticTag {
+	res := make([]StaticTag, 0, len(tp.inventory))
+	for _, tag := range tp.inventory {
+		res = append(res, tp.cachedStaticTag(tag))
+	}
+	return res
+}
+
+// cachedStaticTag returns the tag's cached StaticTag, building it if needed.
+func (tp *TagProcessor) cachedStaticTag(tag *Tag) StaticTag {
+	if tag.static == nil {
+		static := tp.staticTag(tag)
+		tag.static = &static
+	}
+	return *tag.static
+}
+
+// markChanged records that the tag changed,
+// so it's included in the next TakeChanges and its cached StaticTag is rebuilt.
+func (tp *TagProcessor) markChanged(tag *Tag) {
+	tp.changed[tag.EPC] = true
+	tag.static = nil
+}
+
+// staticTag converts a Tag to a StaticTag.
+func (tp *TagProcessor) staticTag(tag *Tag) StaticTag {
+	staticTag := StaticTag{
+		EPC:           tag.EPC,
+		TID:           tag.TID,
+		Location:      tag.Location,
+		LocationAlias: tp.getAlias(tag.Location.String()),
+		LastRead:      tag.LastRead,
+		LastArrived:   tag.LastArrived,
+		LastDeparted:  tag.LastDeparted,
+		State:         tag.state,
+		StatsMap:      make(map[string]StaticTagStats, len(tag.statsMap)),
+
+		GatewayPosition: tag.GatewayPosition,
+		Position:        tag.Position,
+	}
+
+	// re-populate the stats map
+	for loc, stats := range tag.statsMap {
+		if stats.rssiCount() == 0 {
+			continue // skip empty
+		}
+		static := stats.asStatic()
+		if tp.config.persistRSSIHistory {
+			history := stats.rssiDbm.History()
+			static.RSSIHistory = &history
+		}
+		staticTag.StatsMap[loc] = static
+	}
+
+	return staticTag
+}
+
+// TakeChanges returns the tags which have been updated since the last call,
+// along with the EPCs of those which have been removed,
+// so they can be persisted incrementally.
+func (tp *TagProcessor) TakeChanges() (changed []StaticTag, removed []string) {
+	for epc := range tp.changed {
+		if tag, ok := tp.inventory[epc]; ok {
+			changed = append(changed, tp.cachedStaticTag(tag))
+		}
+	}
+	for epc := range tp.removed {
+		if _, ok := tp.inventory[epc]; !ok {
+			removed = append(removed, epc)
+		}
+	}
+
+	tp.changed = make(map[string]bool)
+	tp.removed = make(map[string]bool)
+	return changed, removed
+}
+
+// processData processes an incoming tag read and updates the tag information and
+// device stats data structures.
+//
+// The offset is added to the read's Timestamp,
+// and the reference timestamp is used to weigh the tag's location's RSSI by its age.
+func (tp *TagProcessor) processData(read *TagRead, referenceMillis int64) (event Event) {
+	tag, exists := tp.inventory[read.EPC]
+	if !exists {
+		tag = NewTag(read.EPC)
+		tp.inventory[read.EPC] = tag
+	}
+	tp.markChanged(tag)
+	prevState, prevLoc := tag.state, tag.Location
+
+	// Note: This must be deferred because the code following this defer block has many early-exit
+	// scenarios, however we need this deferred block to be run regardless. It is an anonymous
+	// function to allow usage of local variables via closure.
+	defer func() {
+		event = tp.transition(tag, prevState, prevLoc)
+	}()
+
+	if read.TID != "" {
+		tag.TID = read.TID
+	}
+
+	hasTimestamp := read.Timestamp != 0
+
+	var lastRead int64
+	if hasTimestamp {
+		lastRead = read.Timestamp
+
+		// only update last read if it is newer
+		if lastRead > tag.LastRead {
+			tag.LastRead = lastRead
+		}
+	}
+
+	if read.Antenna == nil {
+		// if we do not know the antenna id, we cannot compute the location
+		return
+	}
+
+	readLocation := NewLocation(read.DeviceName, *read.Antenna)
+	statsAtReadLoc := tag.getStats(readLocation.String())
+	statsAtReadLoc.countRead()
+
+	if read.RSSI != nil {
+		statsAtReadLoc.updateRSSI(*read.RSSI, lastRead, tp.config.rssiWindow)
+	}
+
+	if read.Phase != nil {
+		statsAtReadLoc.updatePhase(*read.Phase)
+	}
+
+	if read.Doppler != nil {
+		statsAtReadLoc.updateDoppler(*read.Doppler)
+	}
+
+	if read.Channel != nil {
+		statsAtReadLoc.updateChannel(*read.Channel)
+	}
+
+	if hasTimestamp {
+		statsAtReadLoc.updateLastRead(lastRead)
+		statsAtReadLoc.expireRSSI(lastRead, tp.config.rssiWindow)
+	}
+
+	if prevLoc.IsEmpty() || tag.Location.Equals(readLocation) {
+		tag.Location = readLocation
+		return
+	}
+
+	statsAtPrevLoc := tag.getStats(tag.Location.String())
+	if hasTimestamp {
+		statsAtPrevLoc.expireRSSI(lastRead, tp.config.rssiWindow)
+	}
+	if statsAtPrevLoc.rssiCount() == 0 {
+		// Its stats have been cleared or expired; update location.
+		tag.Location = readLocation
+		return
+	}
+
+	// if the incoming read's location has at least 2 data points, lets see if the tag should move
+	if statsAtReadLoc.rssiCount() >= 2 {
+		if tp.config.debugLogEnabled {
+			logReadTiming(tp, referenceMillis, statsAtPrevLoc, tag)
+		}
+
+		locationMean := statsAtPrevLoc.rssiDbm.Mean()
+		incomingMean := statsAtReadLoc.rssiDbm.Mean()
+
+		offset := tp.config.profile.computeOffset(referenceMillis, statsAtPrevLoc.lastRead)
+		if tp.config.debugLogEnabled {
+			logTagStats(tp, tag, readLocation.String(), incomingMean, locationMean, offset)
+		}
+
+		// Update the location if the mean RSSI at the new location
+		// is greater than the adjusted mean RSSI of the existing location.
+		// Note: This will generate a moved event.
+		if incomingMean > (locationMean + offset) {
+			tag.Location = readLocation
+		}
+	}
+
+	return
+}
+
+// transition updates a tag's state after processing a read of it
+// and returns the resulting event, if any.
+func (tp *TagProcessor) transition(tag *Tag, prevState TagState, prevLoc Location) Event {
+	switch prevState {
+	case Unknown, Departed:
+		tag.setState(Present)
+		return ArrivedEvent{
+			BaseEvent: BaseEvent{
+				EPC:       tag.EPC,
+				TID:       tag.TID,
+				Timestamp: tag.LastRead,
+			},
+			Location: tp.getAlias(tag.Location.String()),
+		}
+
+	case Present:
+		if prevLoc.IsEmpty() || prevLoc.Equals(tag.Location) {
+			break
+		}
+
+		prevAlias := tp.getAlias(prevLoc.String())
+		curAlias := tp.getAlias(tag.Location.String())
+		if prevAlias == curAlias {
+			break // do not send event if the two locations share the same alias
+		}
+		return MovedEvent{
+			BaseEvent: BaseEvent{
+				EPC:       tag.EPC,
+				TID:       tag.TID,
+				Timestamp: tag.LastRead,
+			},
+			OldLocation: prevAlias,
+			NewLocation: curAlias,
+		}
+	}
+
+	return nil
+}
+
+// gatewayTag returns the inventory's tag for a gateway report, adding it if it's new,
+// after updating its last read time from the report's microsecond timestamp.
+func (tp *TagProcessor) gatewayTag(epcBytes []byte, lastSeenMicros uint64, info ReportInfo) (tag *Tag, lastRead int64) {
+	epc := hex.EncodeToString(epcBytes)
+	tag, exists := tp.inventory[epc]
+	if !exists {
+		tag = NewTag(epc)
+		tp.inventory[epc] = tag
+	}
+	tp.markChanged(tag)
+
+	lastRead = (int64(lastSeenMicros) + info.offsetMicros) / 1000
+	if lastRead > tag.LastRead {
+		tag.LastRead = lastRead
+	}
+	return tag, lastRead
+}
+
+// processGatewayLocation updates a tag's position from an Impinj gateway location report.
+//
+// The Reader has already decided where the tag is, so the position is recorded as reported,
+// and its location is the antenna which last saw it, if the report says,
+// or else the Reader itself. If the tag has exited the Reader's field of view, it Departs.
+func (tp *TagProcessor) processGatewayLocation(loc llrp.ImpinjTagLocation, info ReportInfo) Event {
+	tag, lastRead := tp.gatewayTag(loc.EPC, loc.LastSeen, info)
+	prevState, prevLoc := tag.state, tag.Location
+
+	if tag.GatewayPosition == nil || lastRead >= tag.GatewayPosition.Timestamp {
+		tag.GatewayPosition = &GatewayPosition{
+			DeviceName:   info.DeviceName,
+			XCentimeters: loc.XCentimeters,
+			YCentimeters: loc.YCentimeters,
+			Timestamp:    lastRead,
+		}
+	}
+
+	readLocation := NewLocation(info.DeviceName, loc.AntennaID)
+	if loc.Type == llrp.ImpinjLocationExit {
+		return tp.gatewayExit(tag, prevState, readLocation, lastRead)
+	}
+	tag.getStats(readLocation.String()).updateLastRead(lastRead)
+	tag.Location = readLocation
+	return tp.transition(tag, prevState, prevLoc)
+}
+
+// processGatewayDirection updates a tag's location from an Impinj gateway direction report.
+//
+// The tag's location becomes the sector in which the Reader last saw it,
+// and if the tag has exited the Reader's field of view, it Departs.
+func (tp *TagProcessor) processGatewayDirection(dir llrp.ImpinjTagDirection, info ReportInfo) Event {
+	if dir.LastSeenSector == 0 {
+		return nil
+	}
+
+	tag, lastRead := tp.gatewayTag(dir.EPC, dir.LastSeen, info)
+	prevState, prevLoc := tag.state, tag.Location
+
+	readLocation := NewSectorLocation(info.DeviceName, dir.LastSeenSector)
+	if dir.Type == llrp.ImpinjDirectionExit {
+		return tp.gatewayExit(tag, prevState, readLocation, lastRead)
+	}
+	tag.getStats(readLocation.String()).updateLastRead(lastRead)
+	tag.Location = readLocation
+	return tp.transition(tag, prevState, prevLoc)
+}
+
+// gatewayExit handles a gateway Reader reporting that a tag has left its field of view
+// from the given location, so rather than waiting to notice it hasn't been read,
+// a Present tag Departs immediately. Other tags just record where they were last seen.
+func (tp *TagProcessor) gatewayExit(tag *Tag, prevState TagState, lastLoc Location, lastRead int64) Event {
+	tag.Location = lastLoc
+	if prevState != Present {
+		tag.setStateAt(Departed, lastRead)
+		return nil
+	}
+	return tp.depart(tag, lastRead)
+}
+
+func logTagStats(tp *TagProcessor, tag *Tag, readLocation string, incomingMean float64, existingMean float64, offset float64) {
+	tp.lc.Debug("tag stats",
+		"epc", tag.EPC,
+		"readLoc", readLocation,
+		"prevLoc", tag.Location,
+		"incomingAvg", fmt.Sprintf("%.2f", incomingMean),
+		"existingAvg", fmt.Sprintf("%.2f", existingMean),
+		"offset", fmt.Sprintf("%.2f", offset),
+		"existingAdjusted", fmt.Sprintf("%.2f", existingMean+offset),
+		// if stayFactor is positive, tag will stay, if negative, generates a moved event
+		"stayFactor", fmt.Sprintf("%.2f", (existingMean+offset)-incomingMean))
+}
+
+func logReadTiming(tp *TagProcessor, referenceTimestamp int64, locationStats *tagStats, tag *Tag) {
+	now := UnixMilliNow()
+	tp.lc.Debug("read timing",
+		"now", now,
+		"referenceTimestamp", referenceTimestamp,
+		"nowMinusRef", fmt.Sprintf("%v", time.Duration(now-referenceTimestamp)*time.Millisecond),
+		"locationLastRead", locationStats.lastRead,
+		"lastRead", tag.LastRead,
+		"diff", fmt.Sprintf("%v", time.Duration(tag.LastRead-locationStats.lastRead)*time.Millisecond))
+}
+
+// AgeOut is a cleanup method that will remove tag information from our in-memory
+// structures if it has not been seen in a long enough time. Only applies to
+// tags which are already Departed.
+func (tp *TagProcessor) AgeOut() int {
+	// subtract the ageOutHours to get the minimum allowed LastRead timestamp.
+	// anything older than that is considered aged-out.
+	minTimestamp := UnixMilli(time.Now().Add(time.Hour * -time.Duration(tp.config.ageOutHours)))
+
+	// developer note: Go allows us to remove from a map while iterating
+	var numRemoved int
+	for epc, tag := range tp.inventory {
+		if tag.state == Departed && tag.LastRead < minTimestamp {
+			numRemoved++
+			delete(tp.inventory, epc)
+			delete(tp.changed, epc)
+			tp.removed[epc] = true
+		}
+	}
+
+	if numRemoved > 0 {
+		tp.lc.Info(fmt.Sprintf("Inventory ageout removed %d tag(s).", numRemoved))
+		return numRemoved
+	}
+
+	tp.lc.Debug("No tags were aged-out.")
+	return 0
+}
+
+// AggregateDeparted loops through all tags and sees if any of them should be Departed
+// due to not being read in a long enough time.
+//
+// If suppressDepartedForOfflineReaders is enabled, tags last seen by a Reader
+// the watchdog considers offline are left alone, as are those seen by a Reader
+// that recovered less than departedThresholdSeconds ago,
+// giving it a chance to read them again.
+func (tp *TagProcessor) AggregateDeparted() (events []Event) {
+	now := time.Now()
+	nowMs := now.UnixNano() / 1e6
+	// subtract the departedThresholdSeconds to get the minimum allowed LastRead timestamp.
+	// anything older than that is considered departed.
+	minTimestamp := now.Add(-1*time.Duration(tp.config.departedThresholdSeconds)*time.Second).UnixNano() / 1e6
+	graceMs := int64(tp.config.departedThresholdSeconds) * 1000
+
+	for _, tag := range tp.inventory {
+		if tag.state == Present && tag.LastRead < minTimestamp {
+			if tp.config.suppressDepartedForOfflineReaders &&
+				tp.watchdog.suppressesDeparted(tag.Location.DeviceName, nowMs, graceMs) {
+				continue
+			}
+
+			tp.lc.Debug("Tag departed.", "epc", tag.EPC, "msSinceLastSeen", nowMs-tag.LastRead)
+			events = append(events, tp.depart(tag, nowMs))
+		}
+	}
+
+	return events
+}
+
+// depart sets the tag's state to Departed at the timestamp and returns its Departed event.
+func (tp *TagProcessor) depart(tag *Tag, timestamp int64) DepartedEvent {
+	tag.setStateAt(Departed, timestamp)
+	e := DepartedEvent{
+		BaseEvent: BaseEvent{
+			EPC:       tag.EPC,
+			TID:       tag.TID,
+			Timestamp: timestamp,
+		},
+		LastRead:          tag.LastRead,
+		LastKnownLocation: tp.getAlias(tag.Location.String()),
+	}
+
+	// reset the read stats and the position estimated from them,
+	// so if it arrives again it will start with fresh data
+	tag.resetStats()
+	tag.Position = nil
+	tag.reportedPosition = nil
+	tp.markChanged(tag)
+	return e
+}
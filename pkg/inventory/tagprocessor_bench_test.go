@@ -0,0 +1,153 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchTagCounts are the inventory sizes used by the benchmarks.
+var benchTagCounts = []int{10000, 100000}
+
+// benchReportsPerSecond is the report rate simulated by BenchmarkOneSecondOfReports.
+const benchReportsPerSecond = 1000
+
+// benchReport returns a report of a single read of epc.
+func benchReport(b *testing.B, epc string, deviceName string) (*llrp.ROAccessReport, ReportInfo) {
+	now := time.Now()
+	r := &llrp.ROAccessReport{
+		TagReportData: []llrp.TagReportData{tagReportData(b, epc, defaultAntenna, rssiWeak, now)},
+	}
+	return r, reportInfo(deviceName, now)
+}
+
+// newBenchProcessor returns a TagProcessor with an inventory of n tags
+// and reports which read each of them.
+func newBenchProcessor(b *testing.B, n int) (*TagProcessor, []*llrp.ROAccessReport, []ReportInfo) {
+	b.Helper()
+	ds := newTestDataset(NewConsulConfig(), n)
+	sensor := nextSensor()
+
+	reports := make([]*llrp.ROAccessReport, n)
+	infos := make([]ReportInfo, n)
+	for i, epc := range ds.epcs {
+		reports[i], infos[i] = benchReport(b, epc, sensor)
+		ds.tp.ProcessReport(reports[i], infos[i])
+	}
+	ds.tp.TakeChanges()
+	ds.tp.Snapshot()
+	return ds.tp, reports, infos
+}
+
+// invalidateAll discards every tag's cached StaticTag,
+// so the next Snapshot rebuilds the whole inventory
+// as it used to after every report.
+func (tp *TagProcessor) invalidateAll() {
+	for _, tag := range tp.inventory {
+		tag.static = nil
+	}
+}
+
+func BenchmarkProcessReport(b *testing.B) {
+	for _, n := range benchTagCounts {
+		b.Run(fmt.Sprintf("tags=%d", n), func(b *testing.B) {
+			tp, reports, infos := newBenchProcessor(b, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tp.ProcessReport(reports[i%n], infos[i%n])
+			}
+		})
+	}
+}
+
+// BenchmarkSnapshot compares a Snapshot after one tag changed
+// with rebuilding it from scratch, as every ProcessReport call once did.
+func BenchmarkSnapshot(b *testing.B) {
+	for _, n := range benchTagCounts {
+		b.Run(fmt.Sprintf("tags=%d/changed=1", n), func(b *testing.B) {
+			tp, reports, infos := newBenchProcessor(b, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tp.ProcessReport(reports[i%n], infos[i%n])
+				tp.Snapshot()
+			}
+		})
+
+		b.Run(fmt.Sprintf("tags=%d/rebuild", n), func(b *testing.B) {
+			tp, reports, infos := newBenchProcessor(b, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tp.ProcessReport(reports[i%n], infos[i%n])
+				tp.invalidateAll()
+				tp.Snapshot()
+			}
+		})
+	}
+}
+
+// BenchmarkOneSecondOfReports measures processing a second's worth of reports
+// at benchReportsPerSecond, taking the changes after each as the app does to persist them.
+// It has no rebuild variant, since rebuilding the snapshot after every report
+// takes far longer than a second at these inventory sizes (see BenchmarkSnapshot).
+func BenchmarkOneSecondOfReports(b *testing.B) {
+	for _, n := range benchTagCounts {
+		b.Run(fmt.Sprintf("tags=%d", n), func(b *testing.B) {
+			tp, reports, infos := newBenchProcessor(b, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < benchReportsPerSecond; j++ {
+					k := (i*benchReportsPerSecond + j) % n
+					tp.ProcessReport(reports[k], infos[k])
+					tp.TakeChanges()
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkShardedProcessor measures processing a second's worth of reports
+// from 30 Readers, each reading 20 of 10000 tags, with different numbers of shards.
+func BenchmarkShardedProcessor(b *testing.B) {
+	const readers, tagsPerReport, tags = 30, 20, 10000
+
+	for _, shards := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			ds := newTestDataset(NewConsulConfig(), tags)
+			sp := NewShardedProcessor(getTestingLogger(), NewConsulConfig(), nil, shards, 100)
+			defer sp.Close()
+
+			sensors := make([]string, readers)
+			for i := range sensors {
+				sensors[i] = nextSensor()
+			}
+
+			now := time.Now()
+			reports := make([]*llrp.ROAccessReport, benchReportsPerSecond)
+			infos := make([]ReportInfo, benchReportsPerSecond)
+			for i := range reports {
+				reports[i] = &llrp.ROAccessReport{}
+				for j := 0; j < tagsPerReport; j++ {
+					epc := ds.epcs[(i*tagsPerReport+j)%tags]
+					reports[i].TagReportData = append(reports[i].TagReportData,
+						tagReportData(b, epc, defaultAntenna, rssiWeak, now))
+				}
+				infos[i] = reportInfo(sensors[i%readers], now)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := range reports {
+					sp.Submit(NormalizeReport(reports[j], infos[j].DeviceName, nil), infos[j])
+				}
+				sp.Flush()
+				sp.TakeEvents()
+			}
+		})
+	}
+}
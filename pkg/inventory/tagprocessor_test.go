@@ -6,12 +6,16 @@
 package inventory
 
 import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -209,7 +213,7 @@ func TestAgeOutTask_RequireDepartedState(t *testing.T) {
 	assert.Equalf(t, len(ds.tp.inventory), ds.size(), "expected there to be %d items in the inventory, but there were %d.\ninventory: %#v", ds.size(), len(ds.tp.inventory), ds.tp.inventory)
 
 	// now we will flag the items as departed and run the ageout task again
-	_, _ = ds.tp.AggregateDeparted()
+	_ = ds.tp.AggregateDeparted()
 	if err := ds.verifyStateAll(Departed); err != nil {
 		t.Error(err)
 	}
@@ -263,7 +267,7 @@ func TestAgeOutThreshold(t *testing.T) {
 			}
 
 			// mark any potential tags as Departed
-			_, _ = ds.tp.AggregateDeparted()
+			_ = ds.tp.AggregateDeparted()
 			if err := ds.verifyStateAll(test.state); err != nil {
 				t.Error(err)
 			}
@@ -294,7 +298,7 @@ func TestAggregateDepartedTask(t *testing.T) {
 	})
 
 	// expect all tags to depart, and their stats to be set to Departed
-	events, _ = ds.tp.AggregateDeparted()
+	events = ds.tp.AggregateDeparted()
 	if err := ds.verifyEventPattern(events, ds.size(), DepartedType); err != nil {
 		t.Error(err)
 	}
@@ -321,7 +325,7 @@ func TestAggregateDepartedTask(t *testing.T) {
 
 	// run departed check again, however nothing should depart now because we are
 	// within the departed time limit
-	events, _ = ds.tp.AggregateDeparted()
+	events = ds.tp.AggregateDeparted()
 	if err := ds.verifyNoEvents(events); err != nil {
 		t.Error(err)
 	}
@@ -546,7 +550,7 @@ func TestEventLocationMatchesAlias(t *testing.T) {
 	}
 
 	// Generate departed events
-	events, _ = ds.tp.AggregateDeparted()
+	events = ds.tp.AggregateDeparted()
 	if err := ds.verifyEventPattern(events, ds.size(), DepartedType); err != nil {
 		t.Error(err)
 	}
@@ -557,3 +561,241 @@ func TestEventLocationMatchesAlias(t *testing.T) {
 
 	}
 }
+
+func TestProcessData_rfExtensions(t *testing.T) {
+	ds := newTestDataset(NewConsulConfig(), 1)
+	sensor := nextSensor()
+	epcBytes, err := hex.DecodeString(ds.epcs[0])
+	require.NoError(t, err)
+
+	rssi := llrp.PeakRSSI(rssiStrong)
+	ant := llrp.AntennaID(defaultAntenna)
+	channel := llrp.ChannelIndex(7)
+	report := &llrp.ROAccessReport{TagReportData: []llrp.TagReportData{{
+		EPC96:        llrp.EPC96{EPC: epcBytes},
+		PeakRSSI:     &rssi,
+		AntennaID:    &ant,
+		ChannelIndex: &channel,
+		Custom: []llrp.Custom{
+			{VendorID: uint32(llrp.PENImpinj), Subtype: llrp.ImpinjRFPhaseAngle, Data: []byte{0x08, 0x00}},
+			{VendorID: uint32(llrp.PENImpinj), Subtype: llrp.ImpinjRFDopplerFrequency, Data: []byte{0xff, 0xd8}},
+		},
+	}}}
+	_ = ds.tp.ProcessReport(report, ReportInfo{DeviceName: sensor})
+
+	snapshot := ds.tp.Snapshot()
+	require.Len(t, snapshot, 1)
+	stats, ok := snapshot[0].StatsMap[NewLocation(sensor, defaultAntenna).String()]
+	require.True(t, ok)
+	require.NotNil(t, stats.PhaseRadians)
+	assert.InDelta(t, math.Pi, *stats.PhaseRadians, 1e-9)
+	require.NotNil(t, stats.DopplerHz)
+	assert.Equal(t, -2.5, *stats.DopplerHz)
+	require.NotNil(t, stats.ChannelIndex)
+	assert.Equal(t, uint16(7), *stats.ChannelIndex)
+
+	// they survive a restore
+	restored := snapshot[0].asTagPtr().getStats(NewLocation(sensor, defaultAntenna).String()).asStatic()
+	assert.Equal(t, stats.PhaseRadians, restored.PhaseRadians)
+	assert.Equal(t, stats.DopplerHz, restored.DopplerHz)
+	assert.Equal(t, stats.ChannelIndex, restored.ChannelIndex)
+
+	// readers without the extensions don't report them
+	ds.readTag(t, ds.epcs[0], readParams{deviceName: sensor, antenna: defaultAntenna + 1})
+	stats = ds.tp.Snapshot()[0].StatsMap[NewLocation(sensor, defaultAntenna+1).String()]
+	assert.Nil(t, stats.PhaseRadians)
+	assert.Nil(t, stats.DopplerHz)
+	assert.Nil(t, stats.ChannelIndex)
+}
+
+// impinjGatewayReport returns a report with an ImpinjExtendedTagInformation parameter
+// for the EPC, with the report data of the given subtype.
+// The params are added between the EPC and the report data, e.g., an AntennaID.
+func impinjGatewayReport(epcBytes []byte, subtype llrp.ImpinjParamSubtype, value []byte, params ...byte) *llrp.ROAccessReport {
+	epcData := append([]byte{0x00, 0xf1, 0, byte(6 + len(epcBytes)), 0, byte(8 * len(epcBytes))}, epcBytes...)
+	sub := append([]byte{0x03, 0xff, 0, byte(12 + len(value)), 0, 0, 0x65, 0x1a, 0, 0, byte(subtype >> 8), byte(subtype)}, value...)
+	return &llrp.ROAccessReport{Custom: []llrp.Custom{{
+		VendorID: uint32(llrp.PENImpinj),
+		Subtype:  llrp.ImpinjExtendedTagInformation,
+		Data:     append(append(epcData, params...), sub...),
+	}}}
+}
+
+// microsBytes returns the big-endian encoding of a microsecond timestamp.
+func microsBytes(us uint64) []byte {
+	return []byte{byte(us >> 56), byte(us >> 48), byte(us >> 40), byte(us >> 32),
+		byte(us >> 24), byte(us >> 16), byte(us >> 8), byte(us)}
+}
+
+func TestProcessReport_impinjGateway(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AdjustLastReadOnByOrigin = false
+	ds := newTestDataset(cfg, 1)
+	sensor := nextSensor()
+	epcBytes, err := hex.DecodeString(ds.epcs[0])
+	require.NoError(t, err)
+
+	gatewayReport := func(subtype llrp.ImpinjParamSubtype, value []byte, params ...byte) *llrp.ROAccessReport {
+		return impinjGatewayReport(epcBytes, subtype, value, params...)
+	}
+	now := uint64(time.Now().UnixNano() / 1000)
+
+	// a direction entry report places the tag in the last seen sector
+	dirValue := append(append([]byte{byte(llrp.ImpinjDirectionEntry), 0, 2, 3}, microsBytes(now)...), microsBytes(now)...)
+	events := ds.tp.ProcessReport(gatewayReport(llrp.ImpinjDirectionReportData, dirValue), ReportInfo{DeviceName: sensor})
+	snapshot := ds.tp.Snapshot()
+	require.Len(t, events, 1)
+	assert.Equal(t, ArrivedEvent{
+		BaseEvent: BaseEvent{EPC: ds.epcs[0], Timestamp: int64(now / 1000)},
+		Location:  sensor + "_S3",
+	}, events[0])
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, NewSectorLocation(sensor, 3), snapshot[0].Location)
+
+	// moving to another sector moves it
+	dirValue = append(append([]byte{byte(llrp.ImpinjDirectionUpdate), 0, 2, 4}, microsBytes(now+1000)...), microsBytes(now+1000)...)
+	events = ds.tp.ProcessReport(gatewayReport(llrp.ImpinjDirectionReportData, dirValue), ReportInfo{DeviceName: sensor})
+	require.Len(t, events, 1)
+	assert.Equal(t, MovedEvent{
+		BaseEvent:   BaseEvent{EPC: ds.epcs[0], Timestamp: int64(now/1000) + 1},
+		OldLocation: sensor + "_S3",
+		NewLocation: sensor + "_S4",
+	}, events[0])
+
+	// a location report records its position
+	locValue := append(microsBytes(now+2000), 0xff, 0xff, 0xff, 0x9c, 0, 0, 0x01, 0x2c, byte(llrp.ImpinjLocationUpdate))
+	events = ds.tp.ProcessReport(gatewayReport(llrp.ImpinjLocationReportData, locValue), ReportInfo{DeviceName: sensor})
+	snapshot = ds.tp.Snapshot()
+	require.Len(t, events, 1)
+	assert.Equal(t, MovedType, events[0].OfType())
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, NewLocation(sensor, 0), snapshot[0].Location)
+	assert.Equal(t, &GatewayPosition{
+		DeviceName:   sensor,
+		XCentimeters: -100,
+		YCentimeters: 300,
+		Timestamp:    int64(now/1000) + 2,
+	}, snapshot[0].GatewayPosition)
+	assert.Equal(t, snapshot[0].GatewayPosition, snapshot[0].asTagPtr().GatewayPosition)
+
+	// and its location is the antenna which saw the tag, if the report has one
+	locValue = append(microsBytes(now+3000), 0, 0, 0, 0, 0, 0, 0, 0, byte(llrp.ImpinjLocationUpdate))
+	events = ds.tp.ProcessReport(gatewayReport(llrp.ImpinjLocationReportData, locValue, 0x81, 0, 2),
+		ReportInfo{DeviceName: sensor})
+	require.Len(t, events, 1)
+	assert.Equal(t, MovedEvent{
+		BaseEvent:   BaseEvent{EPC: ds.epcs[0], Timestamp: int64(now/1000) + 3},
+		OldLocation: sensor + "_0",
+		NewLocation: sensor + "_2",
+	}, events[0])
+
+	// a location exit report departs the tag
+	locValue = append(microsBytes(now+4000), 0, 0, 0, 0, 0, 0, 0, 0, byte(llrp.ImpinjLocationExit))
+	events = ds.tp.ProcessReport(gatewayReport(llrp.ImpinjLocationReportData, locValue, 0x81, 0, 2),
+		ReportInfo{DeviceName: sensor})
+	require.Len(t, events, 1)
+	assert.Equal(t, DepartedEvent{
+		BaseEvent:         BaseEvent{EPC: ds.epcs[0], Timestamp: int64(now/1000) + 4},
+		LastRead:          int64(now/1000) + 4,
+		LastKnownLocation: sensor + "_2",
+	}, events[0])
+	assert.NoError(t, ds.verifyStateOf(ds.epcs[0], Departed))
+}
+
+func TestProcessReport_impinjGatewayExit(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AdjustLastReadOnByOrigin = false
+	ds := newTestDataset(cfg, 1)
+	sensor := nextSensor()
+	epcBytes, err := hex.DecodeString(ds.epcs[0])
+	require.NoError(t, err)
+
+	directionReport := func(typ llrp.ImpinjDirectionType, sector uint8, lastSeenMicros uint64) *llrp.ROAccessReport {
+		value := append(append([]byte{byte(typ), 0, 1, sector}, microsBytes(lastSeenMicros)...), microsBytes(lastSeenMicros)...)
+		return impinjGatewayReport(epcBytes, llrp.ImpinjDirectionReportData, value)
+	}
+	now := uint64(time.Now().UnixNano() / 1000)
+	nowMs := int64(now / 1000)
+
+	// an exit before the tag was known doesn't generate an event
+	events := ds.tp.ProcessReport(directionReport(llrp.ImpinjDirectionExit, 1, now), ReportInfo{DeviceName: sensor})
+	assert.Empty(t, events)
+	assert.NoError(t, ds.verifyStateOf(ds.epcs[0], Departed))
+
+	// an entry Arrives, and an exit Departs immediately from the last seen sector
+	events = ds.tp.ProcessReport(directionReport(llrp.ImpinjDirectionEntry, 1, now+1000), ReportInfo{DeviceName: sensor})
+	require.Len(t, events, 1)
+	assert.Equal(t, ArrivedEvent{
+		BaseEvent: BaseEvent{EPC: ds.epcs[0], Timestamp: nowMs + 1},
+		Location:  sensor + "_S1",
+	}, events[0])
+	assert.NoError(t, ds.verifyStateOf(ds.epcs[0], Present))
+
+	events = ds.tp.ProcessReport(directionReport(llrp.ImpinjDirectionExit, 2, now+2000), ReportInfo{DeviceName: sensor})
+	require.Len(t, events, 1)
+	assert.Equal(t, DepartedEvent{
+		BaseEvent:         BaseEvent{EPC: ds.epcs[0], Timestamp: nowMs + 2},
+		LastRead:          nowMs + 2,
+		LastKnownLocation: sensor + "_S2",
+	}, events[0])
+	assert.NoError(t, ds.verifyStateOf(ds.epcs[0], Departed))
+	assert.Empty(t, ds.tp.AggregateDeparted())
+
+	// it Arrives again on its next entry
+	events = ds.tp.ProcessReport(directionReport(llrp.ImpinjDirectionEntry, 2, now+3000), ReportInfo{DeviceName: sensor})
+	require.Len(t, events, 1)
+	assert.Equal(t, ArrivedType, events[0].OfType())
+}
+
+func TestLocation_sector(t *testing.T) {
+	sector := NewSectorLocation("xArray", 2)
+	assert.Equal(t, "xArray_S2", sector.String())
+	assert.False(t, sector.IsEmpty())
+	assert.False(t, sector.Equals(NewLocation("xArray", 2)))
+	assert.True(t, sector.Equals(NewSectorLocation("xArray", 2)))
+	assert.Equal(t, "xArray_2", NewLocation("xArray", 2).String())
+}
+
+func TestRSSIWindow(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AdjustLastReadOnByOrigin = false
+	cfg.ApplicationSettings.RSSIWindowSize = 5
+	cfg.ApplicationSettings.RSSIWindowSeconds = 10
+	ds := newTestDataset(cfg, 1)
+	epc := ds.epcs[0]
+	front, back := nextSensor(), nextSensor()
+	start := time.Now()
+
+	for i := 0; i < 10; i++ {
+		ds.readTag(t, epc, readParams{deviceName: front, antenna: defaultAntenna,
+			rssi: rssiStrong - float64(i), lastSeen: start.Add(time.Duration(i) * 100 * time.Millisecond)})
+	}
+
+	stats := ds.tp.Snapshot()[0].StatsMap[NewLocation(front, defaultAntenna).String()]
+	assert.Equal(t, uint64(10), stats.ReadCount)
+	// only the last 5 RSSI values are kept
+	assert.Equal(t, rssiStrong-9, stats.MinRSSI)
+	assert.Equal(t, rssiStrong-5, stats.MaxRSSI)
+	assert.Equal(t, rssiStrong-7, stats.MedianRSSI)
+	assert.Equal(t, rssiStrong-7, stats.MeanRSSI)
+	assert.InDelta(t, math.Sqrt(2), stats.StdDevRSSI, 1e-9)
+	assert.InDelta(t, 10, stats.ReadsPerSecond, 0.01)
+
+	// strong reads from the front expire, so weaker reads from the back move the tag
+	later := start.Add(11 * time.Second)
+	events := ds.readTag(t, epc, readParams{deviceName: back, antenna: defaultAntenna,
+		rssi: rssiWeak, lastSeen: later, count: 2})
+	require.Len(t, events, 1)
+	assert.Equal(t, MovedType, events[0].OfType())
+
+	// stats without any RSSI values aren't included in the snapshot
+	assert.NotContains(t, ds.tp.Snapshot()[0].StatsMap, NewLocation(front, defaultAntenna).String())
+
+	// shrinking the window applies to existing stats on their next read
+	cfg.ApplicationSettings.RSSIWindowSize = 1
+	ds.tp.UpdateConfig(cfg)
+	ds.readTag(t, epc, readParams{deviceName: back, antenna: defaultAntenna, rssi: rssiStrong, lastSeen: later})
+	stats = ds.tp.Snapshot()[0].StatsMap[NewLocation(back, defaultAntenna).String()]
+	assert.Equal(t, uint64(3), stats.ReadCount)
+	assert.Equal(t, rssiStrong, stats.MeanRSSI)
+}
@@ -0,0 +1,160 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"encoding/hex"
+)
+
+// TagRead is a single read of a tag, independent of the protocol used to report it.
+// Programs which don't speak LLRP can build them directly and process them
+// with TagProcessor.ProcessReads; NewTagRead converts them from LLRP's TagReportData.
+//
+// Fields which a Reader may not report are pointers, which are nil when unknown.
+type TagRead struct {
+	// EPC is the tag's hex-encoded Electronic Product Code.
+	EPC string `json:"epc"`
+	// TID is the tag's hex-encoded Tag ID, if it was read.
+	TID string `json:"tid,omitempty"`
+
+	// DeviceName is the name of the Reader which read the tag.
+	DeviceName string `json:"device_name"`
+	// Antenna is the Reader's antenna which read the tag.
+	// Without it, the read updates the tag's LastRead, but not its Location.
+	Antenna *uint16 `json:"antenna,omitempty"`
+
+	// RSSI is the peak received signal strength, in dBm.
+	RSSI *float64 `json:"rssi,omitempty"`
+	// Phase is the RF phase angle, in radians in the range [0, 2π).
+	Phase *float64 `json:"phase,omitempty"`
+	// Doppler is the RF Doppler frequency, in Hz.
+	Doppler *float64 `json:"doppler,omitempty"`
+	// Channel is the index of the Reader's channel which read the tag.
+	Channel *uint16 `json:"channel,omitempty"`
+
+	// SeenCount is the number of times the Reader saw the tag
+	// in the reads it aggregated into this one.
+	// It's informational: each TagRead counts as a single read in a location's ReadCount.
+	SeenCount uint16 `json:"seen_count,omitempty"`
+	// Timestamp is when the tag was last seen, in milliseconds since the Unix epoch,
+	// or 0 if it's unknown.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	// lastSeenMicros is the Reader's timestamp for a read from an LLRP report,
+	// kept so its offset can be applied before converting it to milliseconds.
+	lastSeenMicros int64
+}
+
+// NewTagRead converts a TagReportData from the named Reader to a TagRead,
+// handling LLRP's various representations of its EPC, RSSI, and timestamp.
+//
+// The TagReportData must not have ambiguous nil parameters,
+// so it should first be normalized with the Reader's llrp.ReportNormalizer.
+func NewTagRead(deviceName string, rt *llrp.TagReportData) TagRead {
+	read := TagRead{EPC: epcOf(rt), DeviceName: deviceName, SeenCount: 1}
+
+	// todo: The following code assumes that if ReadDataAsHex returns ok, that the data contained
+	// 		 within is the TID (Tag ID). This is not always the case, but it is the only
+	//		 type we currently support for ReadOpSpec.
+	if tid, ok := rt.ReadDataAsHex(); ok {
+		read.TID = tid
+	}
+
+	if rt.AntennaID != nil {
+		antenna := uint16(*rt.AntennaID)
+		read.Antenna = &antenna
+	}
+	if rssi, ok := rt.ExtractRSSI(); ok {
+		read.RSSI = &rssi
+	}
+	if phase, ok := rt.ExtractPhaseAngle(); ok {
+		read.Phase = &phase
+	}
+	if doppler, ok := rt.ExtractDopplerFrequency(); ok {
+		read.Doppler = &doppler
+	}
+	if rt.ChannelIndex != nil {
+		channel := uint16(*rt.ChannelIndex)
+		read.Channel = &channel
+	}
+	if rt.TagSeenCount != nil {
+		read.SeenCount = uint16(*rt.TagSeenCount)
+	}
+	if rt.LastSeenUTC != nil {
+		// LLRP timestamps are in microseconds
+		read.lastSeenMicros = int64(*rt.LastSeenUTC)
+		read.Timestamp = read.lastSeenMicros / 1000
+	}
+	return read
+}
+
+// epcOf returns the hex-encoded EPC of rt.
+func epcOf(rt *llrp.TagReportData) string {
+	if len(rt.EPC96.EPC) > 0 {
+		return hex.EncodeToString(rt.EPC96.EPC)
+	}
+	return hex.EncodeToString(rt.EPCData.EPC)
+}
+
+// NormalizedReport holds the reads from an ROAccessReport,
+// extracted from its LLRP parameters in a single pass by NormalizeReport.
+type NormalizedReport struct {
+	reads []TagRead
+	locs  []llrp.ImpinjTagLocation
+	dirs  []llrp.ImpinjTagDirection
+
+	// epcs are the hex-encoded EPCs of the reads, then the locs, then the dirs.
+	epcs []string
+	// lastSeenMicros is the latest Reader timestamp in the report, or 0 if it has none.
+	lastSeenMicros int64
+}
+
+// NormalizeReport fills in the ambiguous nil parameters of r's TagReportData using n,
+// which must be the ReportNormalizer for the named Reader which sent it
+// (or nil if its reports needn't be normalized),
+// and extracts their reads, along with any Impinj gateway data.
+func NormalizeReport(r *llrp.ROAccessReport, deviceName string, n *llrp.ReportNormalizer) *NormalizedReport {
+	nr := &NormalizedReport{reads: make([]TagRead, len(r.TagReportData))}
+	nr.locs, nr.dirs = r.ExtractImpinjGatewayData()
+	nr.epcs = make([]string, 0, len(nr.reads)+len(nr.locs)+len(nr.dirs))
+
+	for i := range r.TagReportData {
+		rt := &r.TagReportData[i]
+		n.Normalize(rt)
+
+		nr.reads[i] = NewTagRead(deviceName, rt)
+		nr.epcs = append(nr.epcs, nr.reads[i].EPC)
+		if rt.LastSeenUTC != nil && int64(*rt.LastSeenUTC) > nr.lastSeenMicros {
+			nr.lastSeenMicros = int64(*rt.LastSeenUTC)
+		}
+	}
+	for _, loc := range nr.locs {
+		nr.epcs = append(nr.epcs, hex.EncodeToString(loc.EPC))
+		if int64(loc.LastSeen) > nr.lastSeenMicros {
+			nr.lastSeenMicros = int64(loc.LastSeen)
+		}
+	}
+	for _, dir := range nr.dirs {
+		nr.epcs = append(nr.epcs, hex.EncodeToString(dir.EPC))
+		if int64(dir.LastSeen) > nr.lastSeenMicros {
+			nr.lastSeenMicros = int64(dir.LastSeen)
+		}
+	}
+	return nr
+}
+
+// EPCs returns the hex-encoded EPCs of the report's reads, which may contain duplicates.
+// The returned slice must not be modified.
+func (nr *NormalizedReport) EPCs() []string {
+	return nr.epcs
+}
+
+// Reads returns the report's TagReads.
+// The returned slice must not be modified.
+func (nr *NormalizedReport) Reads() []TagRead {
+	return nr.reads
+}
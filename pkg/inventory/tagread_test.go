@@ -0,0 +1,136 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewTagRead(t *testing.T) {
+	now := time.Now()
+	rt := tagReportData(t, "0102", 2, rssiStrong, now)
+	channel, count := llrp.ChannelIndex(7), llrp.TagSeenCount(3)
+	rt.ChannelIndex, rt.TagSeenCount = &channel, &count
+
+	// Impinj's RSSI has more precision, so it takes priority
+	rt.Custom = []llrp.Custom{{VendorID: uint32(llrp.PENImpinj), Subtype: llrp.ImpinjPeakRSSI,
+		Data: []byte{0xEC, 0x78}}} // -50.00 dBm
+
+	read := NewTagRead("Reader", &rt)
+	require.NotNil(t, read.Antenna)
+	require.NotNil(t, read.RSSI)
+	require.NotNil(t, read.Channel)
+	assert.Equal(t, "0102", read.EPC)
+	assert.Equal(t, "Reader", read.DeviceName)
+	assert.Equal(t, uint16(2), *read.Antenna)
+	assert.Equal(t, -50.0, *read.RSSI)
+	assert.Equal(t, uint16(7), *read.Channel)
+	assert.Equal(t, uint16(3), read.SeenCount)
+	assert.Equal(t, UnixMilli(now), read.Timestamp)
+	assert.Nil(t, read.Phase)
+	assert.Nil(t, read.Doppler)
+
+	// EPCs which aren't 96 bits use EPCData, and missing parameters are unknown
+	read = NewTagRead("Reader", &llrp.TagReportData{
+		EPCData: llrp.EPCData{EPC: []byte{0x03}, EPCNumBits: 8}})
+	assert.Equal(t, TagRead{EPC: "03", DeviceName: "Reader", SeenCount: 1}, read)
+}
+
+func TestNormalizeReport(t *testing.T) {
+	now := time.Now()
+	first := tagReportData(t, "0102", 2, rssiStrong, now)
+
+	// the second read omits its antenna, since it hasn't changed
+	second := tagReportData(t, "03", 2, rssiWeak, now.Add(time.Second))
+	second.AntennaID = nil
+
+	r := &llrp.ROAccessReport{TagReportData: []llrp.TagReportData{first, second}}
+	n := llrp.NewReportNormalizer(llrp.TagReportContentSelector{EnableAntennaID: true})
+	nr := NormalizeReport(r, "Reader", n)
+
+	assert.Equal(t, []string{"0102", "03"}, nr.EPCs())
+	assert.Equal(t, now.Add(time.Second).UnixNano()/1000, nr.lastSeenMicros)
+	require.Len(t, nr.Reads(), 2)
+	assert.Equal(t, NewTagRead("Reader", &first), nr.Reads()[0])
+
+	read := nr.Reads()[1]
+	assert.Equal(t, "Reader", read.DeviceName)
+	require.NotNil(t, read.Antenna)
+	assert.Equal(t, uint16(2), *read.Antenna)
+
+	// without a normalizer, the antenna stays unknown
+	second.AntennaID = nil
+	nr = NormalizeReport(&llrp.ROAccessReport{TagReportData: []llrp.TagReportData{second}}, "Reader", nil)
+	assert.Nil(t, nr.Reads()[0].Antenna)
+}
+
+func TestProcessReads(t *testing.T) {
+	cfg := NewConsulConfig()
+	cfg.ApplicationSettings.AdjustLastReadOnByOrigin = false
+	ds := newTestDataset(cfg, 1)
+
+	now := time.Now()
+	epc, sensor := ds.epcs[0], nextSensor()
+	r := &llrp.ROAccessReport{TagReportData: []llrp.TagReportData{
+		tagReportData(t, epc, defaultAntenna, rssiWeak, now),
+	}}
+	reads := NormalizeReport(r, sensor, nil).Reads()
+
+	// reads processed directly have the same result as the report
+	events := ds.tp.ProcessReads(reads)
+	require.Len(t, events, 1)
+	assert.Equal(t, ArrivedType, events[0].OfType())
+
+	expected := newTestDataset(cfg, 0)
+	expected.tp.ProcessReport(r, reportInfo(sensor, now))
+	assert.Equal(t, expected.tp.Snapshot(), ds.tp.Snapshot())
+
+	// each read counts once toward the location's read count, regardless of its seen count
+	antenna := defaultAntenna
+	events = ds.tp.ProcessReads([]TagRead{{EPC: epc, DeviceName: sensor, Antenna: &antenna, SeenCount: 4}})
+	assert.Empty(t, events)
+
+	tag := ds.tp.inventory[epc]
+	assert.Equal(t, uint64(2), tag.statsMap[NewLocation(sensor, defaultAntenna).String()].readCount)
+}
+
+func TestTagProcessor_ProcessReads_noTimestamp(t *testing.T) {
+	ds := newTestDataset(NewConsulConfig(), 1)
+	epc, sensor := ds.epcs[0], nextSensor()
+	antenna := defaultAntenna
+
+	// a read without a timestamp happened now, so the tag isn't departed or aged out
+	before := UnixMilliNow()
+	reads := []TagRead{{EPC: epc, DeviceName: sensor, Antenna: &antenna}}
+	events := ds.tp.ProcessReads(reads)
+	require.Len(t, events, 1)
+	assert.Equal(t, ArrivedType, events[0].OfType())
+	assert.GreaterOrEqual(t, reads[0].Timestamp, before)
+	assert.Equal(t, reads[0].Timestamp, ds.tp.inventory[epc].LastRead)
+
+	assert.Empty(t, ds.tp.AggregateDeparted())
+	assert.Equal(t, 0, ds.tp.AgeOut())
+	assert.NoError(t, ds.verifyStateAll(Present))
+}
+
+func TestTagProcessor_processReads_offset(t *testing.T) {
+	ds := newTestDataset(NewConsulConfig(), 1)
+	sensor := nextSensor()
+	nowMicros := UnixMilliNow() * 1000
+
+	// the offset is applied before converting to milliseconds
+	rt := tagReportData(t, ds.epcs[0], defaultAntenna, rssiStrong, time.Now())
+	lastSeen := llrp.LastSeenUTC(nowMicros + 999)
+	rt.LastSeenUTC = &lastSeen
+	reads := []TagRead{NewTagRead(sensor, &rt)}
+	ds.tp.processReads(reads, nil, nil, ReportInfo{offsetMicros: 1, referenceTimestamp: nowMicros / 1000})
+	assert.Equal(t, nowMicros/1000+1, reads[0].Timestamp)
+	assert.Equal(t, nowMicros/1000+1, ds.tp.inventory[ds.epcs[0]].LastRead)
+}
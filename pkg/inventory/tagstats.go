@@ -0,0 +1,135 @@
+//
+// Copyright (C) 2020 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inventory
+
+const (
+	tagStatsWindowSize = 20
+)
+
+// rssiWindow determines which RSSI values a tagStats keeps.
+type rssiWindow struct {
+	// size is the maximum number of values to keep.
+	size int
+	// maxAgeMillis, if non-zero, is how long values are kept
+	// relative to the most recent read.
+	maxAgeMillis int64
+}
+
+// tagStats helps keep track of tag read rssi values over time
+type tagStats struct {
+	lastRead int64
+	// readCount is the total number of reads at the location,
+	// including those whose RSSI values have left the window.
+	readCount uint64
+	rssiDbm   *circularBuffer
+
+	// rf holds the RF values from the most recent read that reported them,
+	// which only Readers with extended reporting enabled do.
+	rf rfStats
+}
+
+// rfStats are RF values reported by some Readers for each tag read.
+type rfStats struct {
+	hasPhase   bool
+	phaseRad   float64
+	hasDoppler bool
+	dopplerHz  float64
+	hasChannel bool
+	channel    uint16
+}
+
+// newTagStats returns a new tagStats pointer with circular buffers initialized to the configured default window size
+func newTagStats() *tagStats {
+	return &tagStats{
+		rssiDbm: newCircularBuffer(tagStatsWindowSize),
+	}
+}
+
+// updateRSSI adds an RSSI value read at the given time (Unix Epoch milliseconds, or 0 if unknown),
+// first resizing the window if its size has been reconfigured.
+func (stats *tagStats) updateRSSI(rssi float64, timestamp int64, window rssiWindow) {
+	size := window.size
+	if size <= 0 {
+		size = tagStatsWindowSize
+	}
+	if stats.rssiDbm.Cap() != size {
+		stats.rssiDbm.Resize(size)
+	}
+	stats.rssiDbm.AddValueAt(rssi, timestamp)
+}
+
+// expireRSSI removes RSSI values that are older than the window allows
+// relative to the given time (Unix Epoch milliseconds).
+func (stats *tagStats) expireRSSI(now int64, window rssiWindow) {
+	if window.maxAgeMillis == 0 || now == 0 {
+		return
+	}
+	stats.rssiDbm.ExpireBefore(now - window.maxAgeMillis)
+}
+
+// countRead increments the number of times the tag has been read at this location.
+func (stats *tagStats) countRead() {
+	stats.readCount++
+}
+
+func (stats *tagStats) updatePhase(phaseRad float64) {
+	stats.rf.hasPhase = true
+	stats.rf.phaseRad = phaseRad
+}
+
+func (stats *tagStats) updateDoppler(dopplerHz float64) {
+	stats.rf.hasDoppler = true
+	stats.rf.dopplerHz = dopplerHz
+}
+
+func (stats *tagStats) updateChannel(channel uint16) {
+	stats.rf.hasChannel = true
+	stats.rf.channel = channel
+}
+
+func (stats *tagStats) updateLastRead(lastRead int64) {
+	// skip times that are at or before the current last read timestamp
+	if lastRead <= stats.lastRead {
+		return
+	}
+	stats.lastRead = lastRead
+}
+
+func (stats *tagStats) rssiCount() int {
+	return stats.rssiDbm.Len()
+}
+
+// asStatic returns the stats as a StaticTagStats.
+func (stats *tagStats) asStatic() StaticTagStats {
+	static := StaticTagStats{
+		LastRead:  stats.lastRead,
+		ReadCount: stats.readCount,
+	}
+
+	if n := stats.rssiCount(); n > 0 {
+		bs := stats.rssiDbm.Stats()
+		static.MeanRSSI = stats.rssiDbm.Mean()
+		static.MinRSSI = bs.min
+		static.MaxRSSI = bs.max
+		static.MedianRSSI = bs.median
+		static.StdDevRSSI = bs.stdDev
+		if bs.newest > bs.oldest {
+			static.ReadsPerSecond = float64(n-1) * 1000 / float64(bs.newest-bs.oldest)
+		}
+	}
+
+	rf := stats.rf
+	if rf.hasPhase {
+		static.PhaseRadians = &rf.phaseRad
+	}
+	if rf.hasDoppler {
+		static.DopplerHz = &rf.dopplerHz
+	}
+	if rf.hasChannel {
+		static.ChannelIndex = &rf.channel
+	}
+	return static
+}
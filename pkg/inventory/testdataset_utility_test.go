@@ -6,7 +6,7 @@
 package inventory
 
 import (
-	"edgexfoundry/app-rfid-llrp-inventory/internal/llrp"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -114,7 +114,7 @@ func (ds *testDataset) readTag(t *testing.T, epc string, params readParams) (eve
 			},
 		}
 
-		e, _ := ds.tp.ProcessReport(r, ReportInfo{
+		e := ds.tp.ProcessReport(r, ReportInfo{
 			DeviceName:         params.deviceName,
 			OriginNanos:        params.origin.UnixNano(),
 			offsetMicros:       0,
@@ -126,6 +126,28 @@ func (ds *testDataset) readTag(t *testing.T, epc string, params readParams) (eve
 	return events
 }
 
+// tagReportData returns the TagReportData for a single read of epc.
+func tagReportData(tb testing.TB, epc string, antenna uint16, rssi float64, lastSeen time.Time) llrp.TagReportData {
+	tb.Helper()
+	epcBytes, err := hex.DecodeString(epc)
+	require.NoError(tb, err)
+
+	rss := llrp.PeakRSSI(rssi)
+	ant := llrp.AntennaID(antenna)
+	seen := llrp.LastSeenUTC(lastSeen.UnixNano() / int64(time.Microsecond))
+	return llrp.TagReportData{
+		EPC96:       llrp.EPC96{EPC: epcBytes},
+		PeakRSSI:    &rss,
+		LastSeenUTC: &seen,
+		AntennaID:   &ant,
+	}
+}
+
+// reportInfo returns the ReportInfo for a report from deviceName that arrived at origin.
+func reportInfo(deviceName string, origin time.Time) ReportInfo {
+	return NewReportInfo(deviceName, origin.UnixNano())
+}
+
 func (ds *testDataset) readAll(t *testing.T, params readParams) (events []Event) {
 	for _, epc := range ds.epcs {
 		e := ds.readTag(t, epc, params)
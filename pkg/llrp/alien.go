@@ -0,0 +1,90 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+// AlienDevice embeds BasicDevice to provide some Alien-specific Behavior implementations.
+//
+// It corrects UHF Modes that report their Tari range backwards (min > max),
+// since the Tari we send must fall within the mode's range,
+// and it lists each antenna explicitly in AISpecs and AntennaConfigurations
+// rather than relying on AntennaID 0 to mean "all antennas".
+//
+// Its configuration uses only standard LLRP parameters;
+// Alien's RSSI and RF Phase extensions aren't publicly documented,
+// so they're not enabled.
+type AlienDevice struct {
+	BasicDevice
+	nAntennas uint16
+}
+
+func NewAlienDevice(c *GetReaderCapabilitiesResponse) (*AlienDevice, error) {
+	bd, err := NewBasicDevice(c)
+	if err != nil {
+		return nil, err
+	}
+
+	nAntennas := c.GeneralDeviceCapabilities.MaxSupportedAntennas
+	if nAntennas == 0 {
+		return nil, errMissingCapInfo("antenna count",
+			"GeneralDeviceCapabilities", "MaxSupportedAntennas")
+	}
+
+	bd.modes = fixAlienModes(bd.modes)
+	return &AlienDevice{BasicDevice: *bd, nAntennas: nAntennas}, nil
+}
+
+// fixAlienModes returns a corrected copy of an Alien Reader's mode table.
+func fixAlienModes(modes []UHFC1G2RFModeTableEntry) []UHFC1G2RFModeTableEntry {
+	fixed := make([]UHFC1G2RFModeTableEntry, len(modes))
+	for i, m := range modes {
+		if m.MinTariTime > m.MaxTariTime {
+			m.MinTariTime, m.MaxTariTime = m.MaxTariTime, m.MinTariTime
+		}
+		fixed[i] = m
+	}
+	return fixed
+}
+
+// NewROSpec returns a new llrp.ROSpec to achieve the Behavior within the Environment.
+//
+// It's based on the BasicDevice's ROSpec,
+// but its AISpecs and AntennaConfigurations name each antenna explicitly.
+func (d *AlienDevice) NewROSpec(b Behavior, e Environment) (*ROSpec, error) {
+	spec, err := d.BasicDevice.NewROSpec(b, e)
+	if err != nil {
+		return nil, err
+	}
+
+	antennas := make([]AntennaID, d.nAntennas)
+	for i := range antennas {
+		antennas[i] = AntennaID(i + 1)
+	}
+
+	for i := range spec.AISpecs {
+		ai := &spec.AISpecs[i]
+		ai.AntennaIDs = antennas
+
+		for j := range ai.InventoryParameterSpecs {
+			ips := &ai.InventoryParameterSpecs[j]
+			configs := make([]AntennaConfiguration, 0, len(antennas))
+			for _, ac := range ips.AntennaConfigurations {
+				if ac.AntennaID != 0 {
+					configs = append(configs, ac)
+					continue
+				}
+
+				for _, id := range antennas {
+					perAntenna := ac
+					perAntenna.AntennaID = id
+					configs = append(configs, perAntenna)
+				}
+			}
+			ips.AntennaConfigurations = configs
+		}
+	}
+
+	return spec, nil
+}
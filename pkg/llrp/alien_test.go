@@ -0,0 +1,362 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewAlienDevice(t *testing.T) {
+	caps := newCapsWithModes(t, PENAlienCap, func(modes []UHFC1G2RFModeTableEntry) []UHFC1G2RFModeTableEntry {
+		modes[1].MinTariTime, modes[1].MaxTariTime = 25000, 6250
+		return modes
+	})
+	d, err := NewAlienDevice(caps)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(4), d.nAntennas)
+
+	// the reversed Tari range should be corrected
+	assert.Equal(t, uint32(6250), d.modes[1].MinTariTime)
+	assert.Equal(t, uint32(25000), d.modes[1].MaxTariTime)
+
+	caps.GeneralDeviceCapabilities.MaxSupportedAntennas = 0
+	_, err = NewAlienDevice(caps)
+	assert.ErrorIs(t, err, ErrMissingCapInfo)
+}
+
+func TestAlienDevice_NewROSpec(t *testing.T) {
+	d, err := NewAlienDevice(newCapsWithModes(t, PENAlienCap, nil))
+	require.NoError(t, err)
+
+	spec, err := d.NewROSpec(Behavior{ScanType: ScanNormal, Power: PowerTarget{Max: 3000}}, Environment{})
+	require.NoError(t, err)
+	testROSpecProperties(t, spec)
+
+	// each antenna should be listed explicitly
+	expected := []AntennaID{1, 2, 3, 4}
+	for _, ai := range spec.AISpecs {
+		assert.Equal(t, expected, ai.AntennaIDs)
+		configs := ai.InventoryParameterSpecs[0].AntennaConfigurations
+		require.Len(t, configs, len(expected))
+		for i, ac := range configs {
+			assert.Equal(t, expected[i], ac.AntennaID)
+		}
+	}
+}
+
+func TestAlienDevice_ALRF800(t *testing.T) {
+	d, err := NewAlienDevice(newCapsWithModes(t, alienALRF800Cap, nil))
+	require.NoError(t, err)
+	assert.False(t, d.stateAware)
+	assert.Equal(t, uint16(4), d.nAntennas)
+
+	// the reversed Tari range should be corrected
+	require.Len(t, d.modes, 3)
+	assert.Equal(t, uint32(6250), d.modes[1].MinTariTime)
+	assert.Equal(t, uint32(25000), d.modes[1].MaxTariTime)
+
+	// power levels are reported max to min
+	for i := range d.pwrMinToMax[1:] {
+		assert.Less(t, d.pwrMinToMax[i].TransmitPowerValue, d.pwrMinToMax[i+1].TransmitPowerValue)
+	}
+
+	for _, st := range []ScanType{ScanFast, ScanNormal, ScanDeep} {
+		spec, err := d.NewROSpec(Behavior{ScanType: st, Power: PowerTarget{Max: 3000}}, Environment{})
+		require.NoError(t, err)
+		testROSpecProperties(t, spec)
+
+		// each antenna should be listed explicitly
+		expected := []AntennaID{1, 2, 3, 4}
+		require.Len(t, spec.AISpecs, 1)
+		assert.Equal(t, expected, spec.AISpecs[0].AntennaIDs)
+		configs := spec.AISpecs[0].InventoryParameterSpecs[0].AntennaConfigurations
+		require.Len(t, configs, len(expected))
+		for i, ac := range configs {
+			assert.Equal(t, expected[i], ac.AntennaID)
+			assert.NotNil(t, ac.C1G2InventoryCommand)
+			assert.NotNil(t, ac.RFTransmitter)
+		}
+	}
+
+	// it has no vendor extensions to enable
+	assert.Equal(t, d.BasicDevice.NewConfig(), d.NewConfig())
+}
+
+// alienALRF800Cap are capabilities modeled on an Alien ALR-F800's
+// (4 antennas, no state aware singulation, one InventoryParameterSpec per AISpec),
+// including a mode with a reversed Tari range.
+// They're built from the Reader's published specifications
+// rather than captured from one, so the mode table is abbreviated.
+const alienALRF800Cap = `{
+	"LLRPStatus": {
+		"Status": 0,
+		"ErrorDescription": "",
+		"FieldError": null,
+		"ParameterError": null
+	},
+	"GeneralDeviceCapabilities": {
+		"MaxSupportedAntennas": 4,
+		"CanSetAntennaProperties": false,
+		"HasUTCClock": true,
+		"DeviceManufacturer": 17996,
+		"Model": 800,
+		"FirmwareVersion": "19.02.11.00",
+		"ReceiveSensitivities": [
+			{
+				"Index": 1,
+				"ReceiveSensitivity": 0
+			}
+		],
+		"PerAntennaReceiveSensitivityRanges": null,
+		"GPIOCapabilities": {
+			"NumGPIs": 4,
+			"NumGPOs": 8
+		},
+		"PerAntennaAirProtocols": [
+			{
+				"AntennaID": 1,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 2,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 3,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 4,
+				"AirProtocolIDs": "AQ=="
+			}
+		],
+		"MaximumReceiveSensitivity": null
+	},
+	"LLRPCapabilities": {
+		"CanDoRFSurvey": false,
+		"CanReportBufferFillWarning": true,
+		"SupportsClientRequestOpSpec": false,
+		"CanDoTagInventoryStateAwareSingulation": false,
+		"SupportsEventsAndReportHolding": true,
+		"MaxPriorityLevelSupported": 0,
+		"ClientRequestedOpSpecTimeout": 0,
+		"MaxROSpecs": 1,
+		"MaxSpecsPerROSpec": 1,
+		"MaxInventoryParameterSpecsPerAISpec": 1,
+		"MaxAccessSpecs": 1,
+		"MaxOpSpecsPerAccessSpec": 8
+	},
+	"RegulatoryCapabilities": {
+		"CountryCode": 840,
+		"CommunicationsStandard": 1,
+		"UHFBandCapabilities": {
+			"TransmitPowerLevels": [
+				{
+					"Index": 1,
+					"TransmitPowerValue": 3000
+				},
+				{
+					"Index": 2,
+					"TransmitPowerValue": 2900
+				},
+				{
+					"Index": 3,
+					"TransmitPowerValue": 2800
+				},
+				{
+					"Index": 4,
+					"TransmitPowerValue": 2700
+				},
+				{
+					"Index": 5,
+					"TransmitPowerValue": 2600
+				},
+				{
+					"Index": 6,
+					"TransmitPowerValue": 2500
+				},
+				{
+					"Index": 7,
+					"TransmitPowerValue": 2400
+				},
+				{
+					"Index": 8,
+					"TransmitPowerValue": 2300
+				},
+				{
+					"Index": 9,
+					"TransmitPowerValue": 2200
+				},
+				{
+					"Index": 10,
+					"TransmitPowerValue": 2100
+				},
+				{
+					"Index": 11,
+					"TransmitPowerValue": 2000
+				},
+				{
+					"Index": 12,
+					"TransmitPowerValue": 1900
+				},
+				{
+					"Index": 13,
+					"TransmitPowerValue": 1800
+				},
+				{
+					"Index": 14,
+					"TransmitPowerValue": 1700
+				},
+				{
+					"Index": 15,
+					"TransmitPowerValue": 1600
+				},
+				{
+					"Index": 16,
+					"TransmitPowerValue": 1500
+				},
+				{
+					"Index": 17,
+					"TransmitPowerValue": 1400
+				},
+				{
+					"Index": 18,
+					"TransmitPowerValue": 1300
+				},
+				{
+					"Index": 19,
+					"TransmitPowerValue": 1200
+				},
+				{
+					"Index": 20,
+					"TransmitPowerValue": 1100
+				},
+				{
+					"Index": 21,
+					"TransmitPowerValue": 1000
+				}
+			],
+			"FrequencyInformation": {
+				"Hopping": true,
+				"FrequencyHopTables": [
+					{
+						"HopTableID": 1,
+						"Frequencies": [
+							902750,
+							906250,
+							909750,
+							913250,
+							916750,
+							920250,
+							923750,
+							927250,
+							903250,
+							906750,
+							910250,
+							913750,
+							917250,
+							920750,
+							924250,
+							903750,
+							907250,
+							910750,
+							914250,
+							917750,
+							921250,
+							924750,
+							904250,
+							907750,
+							911250,
+							914750,
+							918250,
+							921750,
+							925250,
+							904750,
+							908250,
+							911750,
+							915250,
+							918750,
+							922250,
+							925750,
+							905250,
+							908750,
+							912250,
+							915750,
+							919250,
+							922750,
+							926250,
+							905750,
+							909250,
+							912750,
+							916250,
+							919750,
+							923250,
+							926750
+						]
+					}
+				],
+				"FixedFrequencyTable": null
+			},
+			"C1G2RFModes": {
+				"UHFC1G2RFModeTableEntries": [
+					{
+						"ModeID": 0,
+						"DivideRatio": 0,
+						"IsEPCHagConformant": true,
+						"Modulation": 0,
+						"ForwardLinkModulation": 2,
+						"SpectralMask": 2,
+						"BackscatterDataRate": 640000,
+						"PIERatio": 1500,
+						"MinTariTime": 6250,
+						"MaxTariTime": 6250,
+						"StepTariTime": 0
+					},
+					{
+						"ModeID": 1,
+						"DivideRatio": 0,
+						"IsEPCHagConformant": true,
+						"Modulation": 2,
+						"ForwardLinkModulation": 2,
+						"SpectralMask": 3,
+						"BackscatterDataRate": 256000,
+						"PIERatio": 2000,
+						"MinTariTime": 25000,
+						"MaxTariTime": 6250,
+						"StepTariTime": 0
+					},
+					{
+						"ModeID": 2,
+						"DivideRatio": 1,
+						"IsEPCHagConformant": true,
+						"Modulation": 3,
+						"ForwardLinkModulation": 0,
+						"SpectralMask": 3,
+						"BackscatterDataRate": 64000,
+						"PIERatio": 2000,
+						"MinTariTime": 25000,
+						"MaxTariTime": 25000,
+						"StepTariTime": 0
+					}
+				]
+			},
+			"RFSurveyFrequencyCapabilities": null
+		},
+		"Custom": null
+	},
+	"C1G2LLRPCapabilities": {
+		"SupportsBlockErase": false,
+		"SupportsBlockWrite": true,
+		"SupportsBlockPermalock": false,
+		"SupportsTagRecommissioning": false,
+		"SupportsUMIMethod2": false,
+		"SupportsXPC": false,
+		"MaxSelectFiltersPerQuery": 1
+	},
+	"Custom": null
+}`
@@ -49,6 +49,19 @@ type ImpinjOptions struct {
 	// and thus will get re-inventoried every so often,
 	// regardless of movement in and out of antennas' Fields of View.
 	SuppressMonza bool `json:"suppressMonza"`
+
+	// ReportRFExtensions enables reporting each tag read's RF phase angle,
+	// RF Doppler frequency, and the index of the channel on which it was read.
+	//
+	// The phase angle and Doppler frequency depend on the tag's distance
+	// and velocity relative to the antenna, so they can hint at movement,
+	// but the phase angle is only comparable between reads on the same channel.
+	ReportRFExtensions bool `json:"reportRFExtensions"`
+
+	// OptimizedReadTID enables Impinj's "Optimized Read" feature
+	// to read tags' TIDs as part of inventory, rather than with a separate AccessSpec.
+	// This slows inventory a bit, since it requires more communication with each tag.
+	OptimizedReadTID bool `json:"optimizedReadTID"`
 }
 
 // PowerTarget specifies a target power for the Reader to push through the antenna.
@@ -99,8 +112,8 @@ type BasicDevice struct {
 	// LLRP has a data compression "feature" that allows Readers to omit some parameters
 	// if the value hasn't changed "since the last time it was sent".
 	report TagReportContentSelector
-	// lastData is the value of tag parameter the last time it was reported.
-	lastData TagReportData
+	// normalizer fills in those omitted parameters.
+	normalizer *ReportNormalizer
 
 	nGPIs, nFreqs uint16
 	nSpecsPerRO   uint32
@@ -229,7 +242,7 @@ func NewBasicDevice(c *GetReaderCapabilitiesResponse) (*BasicDevice, error) {
 		})
 	}
 
-	return &BasicDevice{
+	d := &BasicDevice{
 		modes:       copyModes,
 		pwrMinToMax: pwrLvls,
 		nFreqs:      nFreqs,
@@ -238,18 +251,9 @@ func NewBasicDevice(c *GetReaderCapabilitiesResponse) (*BasicDevice, error) {
 		allowsHop:   freqInfo.Hopping,
 		nSpecsPerRO: llrpCap.MaxSpecsPerROSpec,
 		stateAware:  llrpCap.CanDoTagInventoryStateAwareSingulation,
-		lastData: TagReportData{
-			ROSpecID:                 new(ROSpecID),
-			SpecIndex:                new(SpecIndex),
-			InventoryParameterSpecID: new(InventoryParameterSpecID),
-			AntennaID:                new(AntennaID),
-			PeakRSSI:                 new(PeakRSSI),
-			ChannelIndex:             new(ChannelIndex),
-			FirstSeenUTC:             new(FirstSeenUTC),
-			LastSeenUTC:              new(LastSeenUTC),
-			TagSeenCount:             new(TagSeenCount),
-		},
-	}, nil
+	}
+	d.normalizer = NewReportNormalizer(d.report)
+	return d, nil
 }
 
 func NewImpinjDevice(c *GetReaderCapabilitiesResponse) (*ImpinjDevice, error) {
@@ -258,9 +262,15 @@ func NewImpinjDevice(c *GetReaderCapabilitiesResponse) (*ImpinjDevice, error) {
 		return nil, err
 	}
 
-	// Correct Impinj's buggy mode table
-	fixed := make([]UHFC1G2RFModeTableEntry, 0, len(bd.modes)/2)
-	for _, m := range bd.modes {
+	bd.modes = fixImpinjModes(bd.modes)
+	return &ImpinjDevice{BasicDevice: *bd}, nil
+}
+
+// fixImpinjModes returns a corrected copy of an Impinj Reader's buggy mode table.
+// It drops the "Autoset" modes and converts the reported BLF to BDR.
+func fixImpinjModes(modes []UHFC1G2RFModeTableEntry) []UHFC1G2RFModeTableEntry {
+	fixed := make([]UHFC1G2RFModeTableEntry, 0, len(modes)/2)
+	for _, m := range modes {
 		if m.ModeID >= 1000 { // the values for these modes are meaningless
 			continue
 		}
@@ -269,9 +279,7 @@ func NewImpinjDevice(c *GetReaderCapabilitiesResponse) (*ImpinjDevice, error) {
 		m.BackscatterDataRate >>= m.Modulation
 		fixed = append(fixed, m)
 	}
-	bd.modes = fixed
-
-	return &ImpinjDevice{BasicDevice: *bd}, nil
+	return fixed
 }
 
 func (d *BasicDevice) NewConfig() *SetReaderConfig {
@@ -320,153 +328,16 @@ func impinjEnableBool16(subtype ImpinjParamSubtype) []byte {
 	}
 }
 
-// ProcessTagReport processes what it expects is the most recent TagReportData.
-//
-// Currently, it fills in ambiguous nil values in the report data,
-// according to LLRP's rules for "efficient" tag reporting.
-func (d *BasicDevice) ProcessTagReport(tags []TagReportData) {
-	d.fillAmbiguousNil(tags)
+// ReportNormalizer returns the ReportNormalizer for the Reader's TagReportData.
+func (d *BasicDevice) ReportNormalizer() *ReportNormalizer {
+	return d.normalizer
 }
 
-// ProcessTagReport does nothing for Impinj Readers,
+// ReportNormalizer returns nil for Impinj Readers,
 // as they say they always send all parameters in every report.
 // Hopefully that is true.
-func (d *ImpinjDevice) ProcessTagReport(_ []TagReportData) {}
-
-// fillAmbiguousNil handles the worst feature of LLRP: ambiguous nil parameters.
-//
-// Specifically, it fills in tag data parameters that weren't reported
-// because they match the last reported value of the same type.
-// This can only be done correctly if you know enough context,
-// so this method is assuming we're using a consistent set of reporting parameters,
-// and tag reports are processed in full, in order.
-// It also skips Uptimes and AccessSpecID parameters.
-//
-// LLRP allows Readers to use `nil` to mean both "not enabled" and "hasn't changed".
-// The Client "knows" which it is because they know if the value were enabled,
-// and they know the most recent value of each optional parameter ever received.
-//
-// You can't disable this behavior.
-// You can't even query a Reader to know if it's something the Reader supports.
-//
-// As a result, the Clients must track:
-// - the most-recently-received value of every optional parameter
-// - the reporting parameters of any ROSpec
-//   for which it might still receive tag reports;
-//   note that it's legal to delete an ROSpec before requesting its data
-// - the default reporting parameters at any point they were changed,
-//   if there was defined at that time an enabled ROSpec that used the defaults
-// - the ROSpecIDs and start/stop timestamps of any ROSpec
-//   for which it might still receive tag reports;
-//   since this is itself an optional parameter,
-//   there are several ways to configure an LLRP Reader
-//   such that it is impossible to disambiguate nil parameters.
-//
-// Here's a direct quote from the LLRP Spec explaining how it works:
-//
-//		This report parameter is generated per tag per accumulation scope[*].
-//		The only mandatory portion of this parameter is the EPCData parameter.
-//		If there was an access operation performed on the tag,
-//		the results of the OpSpecs are mandatory in the report.
-//		The other sub-parameters in this report are optional.
-//		LLRP provides three ways to make the tag reporting efficient:
-//
-//		(i) Allow parameters to be enabled or disabled via TagReportContentSelector in TagReportSpec.
-//		(ii) If an optional parameter is enabled, and is absent in the report,
-//		the Client SHALL assume that the value is identical
-//		to the last parameter of the same type received.
-//		For example, this allows the Readers to not send a parameter in the report
-//		whose value has not changed since the last time it was sent by the Reader.
-//
-// [*] This is just saying you get a TagReportData parameter
-//     for each EPC and unique combination of OpSpec result or matched IDs.
-//     Report accumulation also affects the reporting of
-//     timestamps, RSSI, the channel index, and number of observations.
-//
-func (d *BasicDevice) fillAmbiguousNil(tags []TagReportData) {
-	for i := range tags {
-		tag := &tags[i]
-		if d.report.EnableROSpecID {
-			if tag.ROSpecID == nil {
-				tag.ROSpecID = new(ROSpecID)
-				*tag.ROSpecID = *d.lastData.ROSpecID
-			} else {
-				*d.lastData.ROSpecID = *tag.ROSpecID
-			}
-		}
-
-		if d.report.EnableSpecIndex {
-			if tag.SpecIndex == nil {
-				tag.SpecIndex = new(SpecIndex)
-				*tag.SpecIndex = *d.lastData.SpecIndex
-			} else {
-				*d.lastData.SpecIndex = *tag.SpecIndex
-			}
-		}
-
-		if d.report.EnableInventoryParamSpecID {
-			if tag.InventoryParameterSpecID == nil {
-				tag.InventoryParameterSpecID = new(InventoryParameterSpecID)
-				*tag.InventoryParameterSpecID = *d.lastData.InventoryParameterSpecID
-			} else {
-				*d.lastData.InventoryParameterSpecID = *tag.InventoryParameterSpecID
-			}
-		}
-
-		if d.report.EnableAntennaID {
-			if tag.AntennaID == nil {
-				tag.AntennaID = new(AntennaID)
-				*tag.AntennaID = *d.lastData.AntennaID
-			} else {
-				*d.lastData.AntennaID = *tag.AntennaID
-			}
-		}
-
-		if d.report.EnablePeakRSSI {
-			if tag.PeakRSSI == nil {
-				tag.PeakRSSI = new(PeakRSSI)
-				*tag.PeakRSSI = *d.lastData.PeakRSSI
-			} else {
-				*d.lastData.PeakRSSI = *tag.PeakRSSI
-			}
-		}
-
-		if d.report.EnableChannelIndex {
-			if tag.ChannelIndex == nil {
-				tag.ChannelIndex = new(ChannelIndex)
-				*tag.ChannelIndex = *d.lastData.ChannelIndex
-			} else {
-				*d.lastData.ChannelIndex = *tag.ChannelIndex
-			}
-		}
-
-		if d.report.EnableFirstSeenTimestamp {
-			if tag.FirstSeenUTC == nil {
-				tag.FirstSeenUTC = new(FirstSeenUTC)
-				*tag.FirstSeenUTC = *d.lastData.FirstSeenUTC
-			} else {
-				*d.lastData.FirstSeenUTC = *tag.FirstSeenUTC
-			}
-		}
-
-		if d.report.EnableLastSeenTimestamp {
-			if tag.LastSeenUTC == nil {
-				tag.LastSeenUTC = new(LastSeenUTC)
-				*tag.LastSeenUTC = *d.lastData.LastSeenUTC
-			} else {
-				*d.lastData.LastSeenUTC = *tag.LastSeenUTC
-			}
-		}
-
-		if d.report.EnableTagSeenCount {
-			if tag.TagSeenCount == nil {
-				tag.TagSeenCount = new(TagSeenCount)
-				*tag.TagSeenCount = *d.lastData.TagSeenCount
-			} else {
-				*d.lastData.TagSeenCount = *tag.TagSeenCount
-			}
-		}
-	}
+func (d *ImpinjDevice) ReportNormalizer() *ReportNormalizer {
+	return nil
 }
 
 // Transmit returns a legal llrp.RFTransmitter value.
@@ -901,6 +772,16 @@ func (d *ImpinjDevice) NewROSpec(b Behavior, e Environment) (*ROSpec, error) {
 		}
 	}
 
+	invCustom := []Custom{{
+		VendorID: uint32(PENImpinj),
+		Subtype:  ImpinjSearchMode,
+		Data:     []byte{uint8(searchMode >> 8), uint8(searchMode & 0xFF)},
+	}}
+
+	if b.ImpinjOptions != nil && b.ImpinjOptions.OptimizedReadTID {
+		invCustom = append(invCustom, impinjOptimizedReadTID())
+	}
+
 	return &ROSpec{
 		ROSpecID:       1, // May be overridden, but better to ensure it's not 0.
 		ROBoundarySpec: b.Boundary(),
@@ -917,18 +798,73 @@ func (d *ImpinjDevice) NewROSpec(b Behavior, e Environment) (*ROSpec, error) {
 							RFModeID: uint16(best.ModeID),
 						},
 						SingulationControl: queryAction,
-						Custom: []Custom{{
-							VendorID: uint32(PENImpinj),
-							Subtype:  ImpinjSearchMode,
-							Data:     []byte{uint8(searchMode >> 8), uint8(searchMode & 0xFF)},
-						}},
+						Custom: invCustom,
 					},
 				}},
 			}},
 		}},
+		ROReportSpec: d.reportSpec(b.ImpinjOptions),
 	}, nil
 }
 
+// reportSpec returns an ROReportSpec that enables the extended reporting
+// requested by the ImpinjOptions, or nil if none is requested,
+// in which case the ROSpec uses the one set by the Reader's configuration.
+//
+// Since these options are part of the Behavior,
+// they're set on the ROSpec rather than the Reader's configuration.
+func (d *ImpinjDevice) reportSpec(opts *ImpinjOptions) *ROReportSpec {
+	if opts == nil || !opts.ReportRFExtensions {
+		return nil
+	}
+
+	rs := d.NewConfig().ROReportSpec
+	rs.TagReportContentSelector.EnableChannelIndex = true
+
+	var data []byte
+	for _, subtype := range []ImpinjParamSubtype{
+		ImpinjEnablePeakRSSI,
+		ImpinjEnableRFPhaseAngle,
+		ImpinjEnableRFDopplerFrequency,
+	} {
+		data = append(data, impinjEnableBool16(subtype)...)
+	}
+
+	rs.Custom = []Custom{{
+		VendorID: uint32(PENImpinj),
+		Subtype:  ImpinjTagReportContentSelector,
+		Data:     data,
+	}}
+	return rs
+}
+
+// impinjOptimizedReadTID returns a Custom parameter for a C1G2InventoryCommand
+// which enables Impinj's Optimized Read of the first 6 words of the TID memory bank,
+// enough for the 96-bit serialized TID most modern tags have.
+// The results are reported as a standard C1G2ReadOpSpecResult.
+func impinjOptimizedReadTID() Custom {
+	const (
+		c1g2Read    = 341
+		opSpecID    = 1
+		tidBank     = 2
+		tidWordsLen = 6
+	)
+
+	return Custom{
+		VendorID: uint32(PENImpinj),
+		Subtype:  ImpinjEnableOptimizedRead,
+		Data: []byte{
+			0, 1, // OptimizedReadMode: Enabled
+			uint8(c1g2Read >> 8), uint8(c1g2Read & 0xFF), 0, 15, // param type & length (incl. header)
+			0, opSpecID,
+			0, 0, 0, 0, // access password
+			tidBank << 6,
+			0, 0, // word pointer
+			0, tidWordsLen,
+		},
+	}
+}
+
 func (b Behavior) Boundary() ROBoundarySpec {
 	return ROBoundarySpec{
 		StartTrigger: b.StartTrigger(),
@@ -28,7 +28,8 @@ func testROSpecProperties(t *testing.T, spec *ROSpec) {
 	assert.NotZero(t, spec.ROSpecID)
 	assert.Equal(t, spec.ROSpecCurrentState, ROSpecStateDisabled)
 
-	// We're currently controlling reporting at the ReaderConfig level,
+	// We're controlling reporting at the ReaderConfig level
+	// unless the Behavior asks for vendor-specific report content,
 	// so we don't want to override it in the ROSpec itself.
 	assert.Nil(t, spec.ROReportSpec)
 	assert.NotZero(t, len(spec.AISpecs))
@@ -312,6 +313,47 @@ func TestImpinjDevice_NewConfig(t *testing.T) {
 	assert.NotNil(t, d.NewConfig())
 }
 
+func TestImpinjDevice_NewROSpec_options(t *testing.T) {
+	d, err := NewImpinjDevice(newImpinjCaps(t))
+	require.NoError(t, err)
+
+	b := Behavior{ScanType: ScanNormal, Power: PowerTarget{Max: 3000}}
+	spec, err := d.NewROSpec(b, Environment{})
+	require.NoError(t, err)
+	testROSpecProperties(t, spec)
+	invCmd := spec.AISpecs[0].InventoryParameterSpecs[0].AntennaConfigurations[0].C1G2InventoryCommand
+	require.Len(t, invCmd.Custom, 1)
+	assert.True(t, invCmd.Custom[0].Is(PENImpinj, ImpinjSearchMode))
+
+	b.ImpinjOptions = &ImpinjOptions{ReportRFExtensions: true, OptimizedReadTID: true}
+	spec, err = d.NewROSpec(b, Environment{})
+	require.NoError(t, err)
+
+	// the extended report content is set on the ROSpec
+	require.NotNil(t, spec.ROReportSpec)
+	assert.Equal(t, d.NewConfig().ROReportSpec.Trigger, spec.ROReportSpec.Trigger)
+	sel := spec.ROReportSpec.TagReportContentSelector
+	assert.True(t, sel.EnableChannelIndex)
+	assert.True(t, sel.EnablePeakRSSI)
+	require.Len(t, spec.ROReportSpec.Custom, 1)
+	c := spec.ROReportSpec.Custom[0]
+	assert.True(t, c.Is(PENImpinj, ImpinjTagReportContentSelector))
+	assert.Equal(t, append(append(impinjEnableBool16(ImpinjEnablePeakRSSI),
+		impinjEnableBool16(ImpinjEnableRFPhaseAngle)...),
+		impinjEnableBool16(ImpinjEnableRFDopplerFrequency)...), c.Data)
+
+	invCmd = spec.AISpecs[0].InventoryParameterSpecs[0].AntennaConfigurations[0].C1G2InventoryCommand
+	require.Len(t, invCmd.Custom, 2)
+	optRead := invCmd.Custom[1]
+	assert.True(t, optRead.Is(PENImpinj, ImpinjEnableOptimizedRead))
+	require.Len(t, optRead.Data, 17)
+	assert.Equal(t, uint16(1), binary.BigEndian.Uint16(optRead.Data))
+	assert.Equal(t, uint16(341), binary.BigEndian.Uint16(optRead.Data[2:]))
+	assert.Equal(t, uint16(15), binary.BigEndian.Uint16(optRead.Data[4:]))
+	assert.Equal(t, uint8(2<<6), optRead.Data[12])
+	assert.Equal(t, uint16(6), binary.BigEndian.Uint16(optRead.Data[15:]))
+}
+
 func TestBasicDevice_NewROSpec(t *testing.T) {
 	caps := newImpinjCaps(t)
 	d, err := NewBasicDevice(caps)
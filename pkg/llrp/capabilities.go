@@ -0,0 +1,289 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ReaderCapabilities is a normalized summary of a GetReaderCapabilitiesResponse.
+//
+// LLRP reports capabilities in a form that's convenient for a Client
+// building Reader commands, but not particularly convenient for a human.
+// This converts units (e.g., dBm x100 to dBm), looks up known names,
+// and applies the same vendor corrections the TagReader implementations use,
+// so what's reported matches what this service works with.
+type ReaderCapabilities struct {
+	Manufacturer    string `json:"manufacturer"`
+	ManufacturerPEN uint32 `json:"manufacturerPEN"`
+	Model           string `json:"model"`
+	ModelID         uint32 `json:"modelID"`
+	FirmwareVersion string `json:"firmwareVersion"`
+
+	// Gateway is true for Impinj's gateway Readers, such as the xArray and xSpan,
+	// which can report tags' locations or directions of travel.
+	Gateway bool `json:"gateway"`
+
+	NumAntennas uint16 `json:"numAntennas"`
+	NumGPIs     uint16 `json:"numGPIs"`
+	NumGPOs     uint16 `json:"numGPOs"`
+
+	RegulatoryRegion string          `json:"regulatoryRegion"`
+	CountryCode      CountryCodeType `json:"countryCode"`
+
+	PowerLevels []PowerLevel `json:"powerLevels"`
+	RFModes     []RFMode     `json:"rfModes"`
+
+	// Hopping is true if the Reader operates in a frequency hopping region,
+	// in which case HopTables lists its hop tables;
+	// otherwise, FixedFrequencies lists the frequencies it may use.
+	Hopping          bool        `json:"hopping"`
+	HopTables        []HopTable  `json:"hopTables,omitempty"`
+	FixedFrequencies []Kilohertz `json:"fixedFrequencies,omitempty"`
+}
+
+// PowerLevel is a transmit power table entry, converted to dBm.
+type PowerLevel struct {
+	Index uint16  `json:"index"`
+	DBm   float64 `json:"dBm"`
+}
+
+// RFMode is a UHF C1G2 RF mode table entry with its enumerations named.
+type RFMode struct {
+	ModeID                uint32     `json:"modeID"`
+	BackscatterDataRate   BitsPerSec `json:"backscatterDataRate"`
+	Modulation            string     `json:"modulation"`
+	ForwardLinkModulation string     `json:"forwardLinkModulation"`
+	SpectralMask          string     `json:"spectralMask"`
+	DivideRatio           string     `json:"divideRatio"`
+	PIERatio              float64    `json:"pieRatio"`
+	MinTariTime           Nanosecs32 `json:"minTariTime"`
+	MaxTariTime           Nanosecs32 `json:"maxTariTime"`
+	StepTariTime          Nanosecs32 `json:"stepTariTime"`
+	IsEPCHagConformant    bool       `json:"isEPCHagConformant"`
+}
+
+// HopTable is a frequency hop table.
+type HopTable struct {
+	ID          uint8       `json:"id"`
+	Frequencies []Kilohertz `json:"frequencies"`
+}
+
+// commStandards maps LLRP CommunicationsStandard values to names.
+var commStandards = [...]string{
+	0: "Unspecified",
+	1: "US FCC Part 15",
+	2: "ETSI 302 208",
+	3: "ETSI 300 220",
+	4: "Australia LIPD 1W",
+	5: "Australia LIPD 4W",
+	6: "Japan ARIB STD T89",
+	7: "Hong Kong OFTA 1049",
+	8: "Taiwan DGT LP0002",
+	9: "Korea MIC Article 5-2",
+}
+
+var (
+	modulationStrs = [...]string{
+		FM0:     "FM0",
+		Miller2: "Miller2",
+		Miller4: "Miller4",
+		Miller8: "Miller8",
+	}
+
+	fwdLinkModStrs = [...]string{
+		DoubleSidebandASK: "DSB-ASK",
+		SingleSidebandASK: "SSB-ASK",
+		PhaseReversalASK:  "PR-ASK",
+	}
+
+	spectralMaskStrs = [...]string{
+		SpectralMaskUnknown:            "Unknown",
+		SpectralMaskSingleInterrogator: "SingleInterrogator",
+		SpectralMaskMultiInterrogator:  "MultiInterrogator",
+		SpectralMaskDenseInterrogator:  "DenseInterrogator",
+	}
+
+	divideRatioStrs = [...]string{
+		DREightToOne:       "8",
+		DRSixtyFourToThree: "64/3",
+	}
+)
+
+// enumName returns names[i] if i is in range,
+// or the decimal representation of i otherwise.
+func enumName(names []string, i int) string {
+	if 0 <= i && i < len(names) {
+		return names[i]
+	}
+	return strconv.Itoa(i)
+}
+
+// NewReaderCapabilities returns a ReaderCapabilities summary of c.
+//
+// It returns an error wrapping ErrMissingCapInfo
+// under the same conditions NewBasicDevice does,
+// since a Reader missing that information can't be managed by this service anyway.
+func NewReaderCapabilities(c *GetReaderCapabilitiesResponse) (*ReaderCapabilities, error) {
+	if _, err := NewBasicDevice(c); err != nil {
+		return nil, err
+	}
+
+	genCap := c.GeneralDeviceCapabilities
+	regCap := c.RegulatoryCapabilities
+	uhfCap := regCap.UHFBandCapabilities
+	pen := VendorPEN(genCap.DeviceManufacturer)
+
+	rc := &ReaderCapabilities{
+		Manufacturer:     vendorName(pen),
+		ManufacturerPEN:  genCap.DeviceManufacturer,
+		Model:            modelName(pen, genCap.Model),
+		ModelID:          genCap.Model,
+		FirmwareVersion:  genCap.FirmwareVersion,
+		Gateway:          pen == PENImpinj && ImpinjModel(genCap.Model).IsGateway(),
+		NumAntennas:      genCap.MaxSupportedAntennas,
+		NumGPIs:          genCap.GPIOCapabilities.NumGPIs,
+		NumGPOs:          genCap.GPIOCapabilities.NumGPOs,
+		RegulatoryRegion: enumName(commStandards[:], int(regCap.CommunicationsStandard)),
+		CountryCode:      regCap.CountryCode,
+		PowerLevels:      make([]PowerLevel, len(uhfCap.TransmitPowerLevels)),
+		Hopping:          uhfCap.FrequencyInformation.Hopping,
+	}
+
+	for i, p := range uhfCap.TransmitPowerLevels {
+		rc.PowerLevels[i] = PowerLevel{
+			Index: p.Index,
+			DBm:   float64(p.TransmitPowerValue) / 100.0,
+		}
+	}
+
+	modes := uhfCap.C1G2RFModes.UHFC1G2RFModeTableEntries
+	switch pen {
+	case PENImpinj:
+		modes = fixImpinjModes(modes)
+	case PENZebra:
+		modes = fixZebraModes(modes)
+	case PENAlien:
+		modes = fixAlienModes(modes)
+	}
+
+	rc.RFModes = make([]RFMode, len(modes))
+	for i, m := range modes {
+		rc.RFModes[i] = RFMode{
+			ModeID:                m.ModeID,
+			BackscatterDataRate:   m.BackscatterDataRate,
+			Modulation:            enumName(modulationStrs[:], int(m.Modulation)),
+			ForwardLinkModulation: enumName(fwdLinkModStrs[:], int(m.ForwardLinkModulation)),
+			SpectralMask:          enumName(spectralMaskStrs[:], int(m.SpectralMask)),
+			DivideRatio:           enumName(divideRatioStrs[:], int(m.DivideRatio)),
+			PIERatio:              float64(m.PIERatio) / 1000.0,
+			MinTariTime:           m.MinTariTime,
+			MaxTariTime:           m.MaxTariTime,
+			StepTariTime:          m.StepTariTime,
+			IsEPCHagConformant:    m.IsEPCHagConformant,
+		}
+	}
+
+	freqInfo := uhfCap.FrequencyInformation
+	if freqInfo.Hopping {
+		rc.HopTables = make([]HopTable, len(freqInfo.FrequencyHopTables))
+		for i, ht := range freqInfo.FrequencyHopTables {
+			rc.HopTables[i] = HopTable{
+				ID:          ht.HopTableID,
+				Frequencies: append([]Kilohertz(nil), ht.Frequencies...),
+			}
+		}
+	} else {
+		rc.FixedFrequencies = append([]Kilohertz(nil),
+			freqInfo.FixedFrequencyTable.Frequencies...)
+	}
+
+	return rc, nil
+}
+
+// vendorName returns a known vendor's name, or "Unknown".
+func vendorName(pen VendorPEN) string {
+	switch pen {
+	case PENImpinj, PENAlien, PENZebra:
+		return strings.TrimPrefix(pen.String(), "PEN")
+	}
+	return "Unknown"
+}
+
+// modelName returns the name of a known model,
+// or the model number as a string for unknown models.
+func modelName(pen VendorPEN, model uint32) string {
+	if pen == PENImpinj {
+		name := ImpinjModel(model).String()
+		if !strings.HasPrefix(name, "ImpinjModel(") {
+			return name
+		}
+	}
+	return strconv.FormatUint(uint64(model), 10)
+}
+
+// CapabilityCache caches ReaderCapabilities by device name,
+// so that requests for them don't require a round trip to the Reader.
+//
+// Capabilities only change if the Reader's firmware or region changes,
+// which requires it to restart, so the cache should be invalidated
+// whenever the Reader connects or disconnects.
+type CapabilityCache struct {
+	mu   sync.RWMutex
+	caps map[string]*ReaderCapabilities
+	// gens counts each device's invalidations,
+	// so that a Get which was already requesting capabilities
+	// when they were invalidated doesn't cache its stale result.
+	gens map[string]uint64
+}
+
+// NewCapabilityCache returns a new, empty CapabilityCache.
+func NewCapabilityCache() *CapabilityCache {
+	return &CapabilityCache{
+		caps: map[string]*ReaderCapabilities{},
+		gens: map[string]uint64{},
+	}
+}
+
+// Get returns the cached ReaderCapabilities for the named device,
+// first requesting them from the DSClient if they aren't yet cached.
+func (cc *CapabilityCache) Get(ds DSClient, device string) (*ReaderCapabilities, error) {
+	cc.mu.RLock()
+	rc, ok := cc.caps[device]
+	gen := cc.gens[device]
+	cc.mu.RUnlock()
+	if ok {
+		return rc, nil
+	}
+
+	devCap, err := ds.GetCapabilities(device)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err = NewReaderCapabilities(devCap)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.mu.Lock()
+	if cc.gens[device] == gen {
+		cc.caps[device] = rc
+	}
+	cc.mu.Unlock()
+	return rc, nil
+}
+
+// Invalidate removes the named device's capabilities from the cache, if present,
+// and prevents any requests for them already in progress from being cached.
+func (cc *CapabilityCache) Invalidate(device string) {
+	cc.mu.Lock()
+	delete(cc.caps, device)
+	cc.gens[device]++
+	cc.mu.Unlock()
+}
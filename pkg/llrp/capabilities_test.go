@@ -0,0 +1,167 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewReaderCapabilities_impinj(t *testing.T) {
+	devCap := &GetReaderCapabilitiesResponse{}
+	require.NoError(t, json.Unmarshal([]byte(PENImpinjCap), devCap))
+
+	rc, err := NewReaderCapabilities(devCap)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Impinj", rc.Manufacturer)
+	assert.Equal(t, uint32(PENImpinj), rc.ManufacturerPEN)
+	assert.Equal(t, "SpeedwayR420", rc.Model)
+	assert.Equal(t, "5.14.0.240", rc.FirmwareVersion)
+	assert.Equal(t, uint16(4), rc.NumAntennas)
+	assert.Equal(t, uint16(4), rc.NumGPIs)
+	assert.Equal(t, uint16(4), rc.NumGPOs)
+	assert.Equal(t, "US FCC Part 15", rc.RegulatoryRegion)
+	assert.Equal(t, CountryCodeType(840), rc.CountryCode)
+	assert.Equal(t, []PowerLevel{{Index: 1, DBm: 10}}, rc.PowerLevels)
+
+	assert.True(t, rc.Hopping)
+	require.Len(t, rc.HopTables, 1)
+	assert.Equal(t, uint8(1), rc.HopTables[0].ID)
+	assert.Len(t, rc.HopTables[0].Frequencies, 4)
+	assert.Empty(t, rc.FixedFrequencies)
+
+	// Impinj reports BLF instead of BDR, so these should be corrected.
+	require.Len(t, rc.RFModes, 3)
+	assert.Equal(t, BitsPerSec(640000), rc.RFModes[0].BackscatterDataRate)
+	assert.Equal(t, BitsPerSec(320000), rc.RFModes[1].BackscatterDataRate)
+	assert.Equal(t, BitsPerSec(68500), rc.RFModes[2].BackscatterDataRate)
+	assert.Equal(t, "Miller4", rc.RFModes[2].Modulation)
+	assert.Equal(t, "DSB-ASK", rc.RFModes[2].ForwardLinkModulation)
+	assert.Equal(t, "DenseInterrogator", rc.RFModes[2].SpectralMask)
+	assert.Equal(t, "64/3", rc.RFModes[2].DivideRatio)
+	assert.Equal(t, 2.0, rc.RFModes[2].PIERatio)
+}
+
+func TestNewReaderCapabilities_notImpinj(t *testing.T) {
+	devCap := &GetReaderCapabilitiesResponse{}
+	require.NoError(t, json.Unmarshal([]byte(PENZebraCap), devCap))
+
+	rc, err := NewReaderCapabilities(devCap)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Zebra", rc.Manufacturer)
+	// The model number is only looked up for Impinj Readers.
+	assert.Equal(t, "2001002", rc.Model)
+
+	// Other vendors' mode tables are reported as-is.
+	require.Len(t, rc.RFModes, 3)
+	assert.Equal(t, BitsPerSec(640000), rc.RFModes[1].BackscatterDataRate)
+}
+
+func TestNewReaderCapabilities_missing(t *testing.T) {
+	_, err := NewReaderCapabilities(&GetReaderCapabilitiesResponse{})
+	assert.ErrorIs(t, err, ErrMissingCapInfo)
+}
+
+func TestCapabilityCache(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		type Reading struct {
+			Name, Value string
+		}
+		type edgexResp struct {
+			Readings []Reading
+		}
+		data, err := json.Marshal(edgexResp{Readings: []Reading{{Name: capReadingName, Value: PENImpinjCap}}})
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	actualURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	ds := NewDSClient(actualURL, ts.Client(), getTestingLogger())
+
+	cc := NewCapabilityCache()
+	first, err := cc.Get(ds, "test")
+	require.NoError(t, err)
+	second, err := cc.Get(ds, "test")
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	cc.Invalidate("test")
+	third, err := cc.Get(ds, "test")
+	require.NoError(t, err)
+	assert.NotSame(t, first, third)
+	assert.Equal(t, first, third)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestCapabilityCache_invalidateDuringGet(t *testing.T) {
+	var requests int32
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			started <- struct{}{}
+			<-release
+		}
+		type Reading struct {
+			Name, Value string
+		}
+		type edgexResp struct {
+			Readings []Reading
+		}
+		data, err := json.Marshal(edgexResp{Readings: []Reading{{Name: capReadingName, Value: PENImpinjCap}}})
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	actualURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	ds := NewDSClient(actualURL, ts.Client(), getTestingLogger())
+
+	cc := NewCapabilityCache()
+	type result struct {
+		rc  *ReaderCapabilities
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		rc, err := cc.Get(ds, "test")
+		done <- result{rc, err}
+	}()
+
+	// the Reader reconnects while the first request is in progress,
+	// so its result may be stale and mustn't be cached
+	<-started
+	cc.Invalidate("test")
+	close(release)
+	first := <-done
+	require.NoError(t, first.err)
+
+	second, err := cc.Get(ds, "test")
+	require.NoError(t, err)
+	assert.NotSame(t, first.rc, second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	third, err := cc.Get(ds, "test")
+	require.NoError(t, err)
+	assert.Same(t, second, third)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
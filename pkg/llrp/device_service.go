@@ -72,6 +72,32 @@ func NewDSClient(host *url.URL, c *http.Client, lc logger.LoggingClient) DSClien
 
 // GetDevices return a list of device names known to the EdgeX Metadata service.
 func GetDevices(metadataDevicesURL string, client *http.Client) ([]string, error) {
+	devices, err := GetDeviceInfo(metadataDevicesURL, client)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceList := make([]string, len(devices))
+	for i, dev := range devices {
+		deviceList[i] = dev.Name
+	}
+	return deviceList, nil
+}
+
+// DeviceInfo is the subset of an EdgeX Metadata device record
+// that determines how the Device Service communicates with a Reader.
+type DeviceInfo struct {
+	Name    string
+	Profile struct {
+		Name string
+	}
+	// Protocols holds the Reader's addressing information,
+	// e.g. {"tcp": {"host": "192.0.2.1", "port": "5084"}}.
+	Protocols map[string]map[string]string
+}
+
+// GetDeviceInfo returns the devices known to the EdgeX Metadata service.
+func GetDeviceInfo(metadataDevicesURL string, client *http.Client) ([]DeviceInfo, error) {
 	req, err := http.NewRequest(http.MethodGet, metadataDevicesURL, nil)
 	if err != nil {
 		return nil, err
@@ -91,16 +117,11 @@ func GetDevices(metadataDevicesURL string, client *http.Client) ([]string, error
 		return nil, err
 	}
 
-	ds := &[]struct{ Name string }{}
-	if err := json.Unmarshal(respBody, ds); err != nil {
+	var devices []DeviceInfo
+	if err := json.Unmarshal(respBody, &devices); err != nil {
 		return nil, errors.Wrap(err, "failed to parse EdgeX device list")
 	}
-
-	deviceList := make([]string, len(*ds))
-	for i, dev := range *ds {
-		deviceList[i] = dev.Name
-	}
-	return deviceList, nil
+	return devices, nil
 }
 
 // NewReader returns a TagReader instance for the given device name
@@ -109,13 +130,19 @@ func GetDevices(metadataDevicesURL string, client *http.Client) ([]string, error
 // If the Device Service isn't tracking a device with the given name,
 // then this returns an error.
 func (ds DSClient) NewReader(device string) (TagReader, error) {
+	tr, _, err := ds.newReader(device)
+	return tr, err
+}
+
+// newReader is like NewReader, but also returns the capabilities it used.
+func (ds DSClient) newReader(device string) (TagReader, *GetReaderCapabilitiesResponse, error) {
 	devCap, err := ds.GetCapabilities(device)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if devCap.GeneralDeviceCapabilities == nil {
-		return nil, errors.Errorf("missing general capabilities for %q", device)
+		return nil, nil, errors.Errorf("missing general capabilities for %q", device)
 	}
 
 	var tr TagReader
@@ -123,32 +150,54 @@ func (ds DSClient) NewReader(device string) (TagReader, error) {
 	case PENImpinj:
 		impDev, err := NewImpinjDevice(devCap)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		if err := impDev.EnableCustomExt(device, ds); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		if err := ds.SetConfig(device, impDev.NewConfig()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		tr = impDev
+	case PENZebra:
+		zebraDev, err := NewZebraDevice(devCap)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := ds.SetConfig(device, zebraDev.NewConfig()); err != nil {
+			return nil, nil, err
+		}
+
+		tr = zebraDev
+	case PENAlien:
+		alienDev, err := NewAlienDevice(devCap)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := ds.SetConfig(device, alienDev.NewConfig()); err != nil {
+			return nil, nil, err
+		}
+
+		tr = alienDev
 	default:
 		basic, err := NewBasicDevice(devCap)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		if err := ds.SetConfig(device, basic.NewConfig()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		tr = basic
 	}
 
-	return tr, nil
+	return tr, devCap, nil
 }
 
 // GetCapabilities queries the device service for a device's capabilities.
@@ -128,7 +128,7 @@ func TestNewReader(t *testing.T) {
 			capabilities: PENImpinjCap,
 		},
 		{
-			testCaseName: "Test New Reader Type for Device of Type PENImpinj",
+			testCaseName: "Test New Reader Type for Device of Type PENAlien",
 			deviceName:   "SpeedwayR-19-FE-16",
 			respCode:     http.StatusOK,
 			capabilities: PENAlienCap,
@@ -175,6 +175,12 @@ func TestNewReader(t *testing.T) {
 			case PENImpinj:
 				deviceType, err = NewImpinjDevice(getReaderCapabilitiesResponse)
 				require.NoError(t, err)
+			case PENZebra:
+				deviceType, err = NewZebraDevice(getReaderCapabilitiesResponse)
+				require.NoError(t, err)
+			case PENAlien:
+				deviceType, err = NewAlienDevice(getReaderCapabilitiesResponse)
+				require.NoError(t, err)
 			default:
 				deviceType, err = NewBasicDevice(getReaderCapabilitiesResponse)
 				require.NoError(t, err)
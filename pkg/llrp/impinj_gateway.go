@@ -0,0 +1,178 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import "encoding/binary"
+
+// ImpinjTagLocation is a tag's position as estimated by an Impinj gateway Reader
+// operating in its Location role.
+type ImpinjTagLocation struct {
+	EPC  []byte
+	Type ImpinjLocationType
+	// AntennaID is the antenna which last saw the tag,
+	// if the report has an AntennaID parameter, or 0 if it doesn't.
+	AntennaID uint16
+	// LastSeen is when the tag was last seen, in microseconds since the Unix epoch.
+	LastSeen uint64
+	// XCentimeters and YCentimeters are relative to the Reader,
+	// using the coordinate system configured on it.
+	XCentimeters int32
+	YCentimeters int32
+}
+
+// ImpinjLocationType describes the event which triggered a location report.
+type ImpinjLocationType uint8
+
+const (
+	ImpinjLocationEntry  = ImpinjLocationType(0)
+	ImpinjLocationUpdate = ImpinjLocationType(1)
+	ImpinjLocationExit   = ImpinjLocationType(2)
+)
+
+// ImpinjDirectionType describes the event which triggered a direction report.
+type ImpinjDirectionType uint8
+
+const (
+	ImpinjDirectionEntry  = ImpinjDirectionType(0)
+	ImpinjDirectionUpdate = ImpinjDirectionType(1)
+	ImpinjDirectionExit   = ImpinjDirectionType(2)
+)
+
+// ImpinjTagDirection is a tag's movement between sectors
+// as reported by an Impinj gateway Reader operating in its Direction role.
+type ImpinjTagDirection struct {
+	EPC  []byte
+	Type ImpinjDirectionType
+	// FirstSeenSector and LastSeenSector are the IDs of the Reader's sectors
+	// in which the tag was first and most recently seen.
+	FirstSeenSector uint8
+	LastSeenSector  uint8
+	// FirstSeen and LastSeen are in microseconds since the Unix epoch.
+	FirstSeen uint64
+	LastSeen  uint64
+}
+
+const (
+	paramEPCData   = 241
+	paramEPC96     = 13
+	paramAntennaID = 1
+	paramCustom    = 1023
+
+	impinjLocationDataLen  = 8 + 4 + 4 + 1
+	impinjDirectionDataLen = 4 + 8 + 8
+)
+
+// ExtractImpinjGatewayData returns the tag locations and directions
+// in the report's ImpinjExtendedTagInformation Custom parameters.
+//
+// Gateway Readers only send these when operating in their Location or Direction roles,
+// in which case they're sent instead of TagReportData.
+// Malformed parameters are skipped.
+func (r *ROAccessReport) ExtractImpinjGatewayData() (locs []ImpinjTagLocation, dirs []ImpinjTagDirection) {
+	for _, c := range r.Custom {
+		if !c.Is(PENImpinj, ImpinjExtendedTagInformation) {
+			continue
+		}
+
+		var epc []byte
+		var antennaID uint16
+		var loc *ImpinjTagLocation
+		var dir *ImpinjTagDirection
+
+		forEachParam(c.Data, func(typ uint16, data []byte) {
+			switch typ {
+			case paramEPCData:
+				if len(data) < 2 {
+					return
+				}
+				nBytes := (int(binary.BigEndian.Uint16(data)) + 7) / 8
+				if len(data)-2 >= nBytes {
+					epc = data[2 : 2+nBytes]
+				}
+			case paramEPC96:
+				epc = data
+			case paramAntennaID:
+				antennaID = binary.BigEndian.Uint16(data)
+			case paramCustom:
+				if len(data) < 8 || VendorPEN(binary.BigEndian.Uint32(data)) != PENImpinj {
+					return
+				}
+
+				subtype, value := binary.BigEndian.Uint32(data[4:]), data[8:]
+				switch {
+				case subtype == ImpinjLocationReportData && len(value) >= impinjLocationDataLen:
+					loc = &ImpinjTagLocation{
+						LastSeen:     binary.BigEndian.Uint64(value),
+						XCentimeters: int32(binary.BigEndian.Uint32(value[8:])),
+						YCentimeters: int32(binary.BigEndian.Uint32(value[12:])),
+						Type:         ImpinjLocationType(value[16]),
+					}
+				case subtype == ImpinjDirectionReportData && len(value) >= impinjDirectionDataLen:
+					dir = &ImpinjTagDirection{
+						Type:            ImpinjDirectionType(value[0]),
+						FirstSeenSector: value[2],
+						LastSeenSector:  value[3],
+						FirstSeen:       binary.BigEndian.Uint64(value[4:]),
+						LastSeen:        binary.BigEndian.Uint64(value[12:]),
+					}
+				}
+			}
+		})
+
+		if len(epc) == 0 {
+			continue
+		}
+		if loc != nil {
+			loc.EPC = epc
+			loc.AntennaID = antennaID
+			locs = append(locs, *loc)
+		}
+		if dir != nil {
+			dir.EPC = epc
+			dirs = append(dirs, *dir)
+		}
+	}
+
+	return locs, dirs
+}
+
+// forEachParam calls f with the type and value of each LLRP parameter in data.
+//
+// Of the TV-encoded parameters, only EPC-96 and AntennaID are supported,
+// since the others' lengths aren't known here;
+// if it encounters any other, or a malformed TLV parameter, it stops.
+func forEachParam(data []byte, f func(typ uint16, value []byte)) {
+	for len(data) > 0 {
+		if data[0]&0x80 != 0 {
+			var tvLen int
+			switch data[0] & 0x7F {
+			case paramEPC96:
+				tvLen = 1 + 12
+			case paramAntennaID:
+				tvLen = 1 + 2
+			default:
+				return
+			}
+			if len(data) < tvLen {
+				return
+			}
+			f(uint16(data[0]&0x7F), data[1:tvLen])
+			data = data[tvLen:]
+			continue
+		}
+
+		if len(data) < 4 {
+			return
+		}
+		typ := binary.BigEndian.Uint16(data) & 0x3FF
+		length := int(binary.BigEndian.Uint16(data[2:]))
+		if length < 4 || length > len(data) {
+			return
+		}
+		f(typ, data[4:length])
+		data = data[length:]
+	}
+}
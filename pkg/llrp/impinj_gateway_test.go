@@ -0,0 +1,112 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// tlv returns the encoding of an LLRP TLV parameter.
+func tlv(typ uint16, value ...[]byte) []byte {
+	var data []byte
+	for _, v := range value {
+		data = append(data, v...)
+	}
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint16(header, typ)
+	binary.BigEndian.PutUint16(header[2:], uint16(4+len(data)))
+	return append(header, data...)
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func be64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func impinjSubparam(subtype ImpinjParamSubtype, value ...[]byte) []byte {
+	return tlv(paramCustom, append([][]byte{be32(uint32(PENImpinj)), be32(subtype)}, value...)...)
+}
+
+func extendedTagInfo(params ...[]byte) Custom {
+	var data []byte
+	for _, p := range params {
+		data = append(data, p...)
+	}
+	return Custom{VendorID: uint32(PENImpinj), Subtype: ImpinjExtendedTagInformation, Data: data}
+}
+
+func TestImpinjModel_IsGateway(t *testing.T) {
+	assert.True(t, XArray.IsGateway())
+	assert.True(t, XSpan.IsGateway())
+	assert.True(t, XPortal.IsGateway())
+	assert.False(t, SpeedwayR420.IsGateway())
+	assert.False(t, R700.IsGateway())
+}
+
+func TestExtractImpinjGatewayData(t *testing.T) {
+	epc := []byte{0x30, 0x14, 0x36, 0x39, 0xf8, 0x41, 0x91, 0x45, 0xdb, 0x60, 0x21, 0x54}
+	x, y := int32(-150), int32(320)
+
+	r := ROAccessReport{Custom: []Custom{
+		// a location report using EPCData, with the antenna which saw the tag
+		extendedTagInfo(
+			tlv(paramEPCData, be16(96), epc),
+			[]byte{0x80 | paramAntennaID, 0, 7},
+			impinjSubparam(ImpinjLocationReportData,
+				be64(1000000), be32(uint32(x)), be32(uint32(y)), []byte{byte(ImpinjLocationExit)}),
+		),
+		// a direction report using a TV-encoded EPC-96
+		extendedTagInfo(
+			append([]byte{0x80 | paramEPC96}, epc...),
+			impinjSubparam(ImpinjDirectionReportData,
+				[]byte{byte(ImpinjDirectionUpdate), 0, 2, 5}, be64(2000000), be64(3000000)),
+		),
+		// other Custom parameters are ignored
+		{VendorID: uint32(PENImpinj), Subtype: ImpinjPeakRSSI, Data: be16(1)},
+		// as are reports without an EPC
+		extendedTagInfo(impinjSubparam(ImpinjLocationReportData,
+			be64(1000000), be32(0), be32(0), []byte{0})),
+		// and those that are truncated
+		extendedTagInfo(
+			tlv(paramEPCData, be16(96), epc),
+			impinjSubparam(ImpinjDirectionReportData, []byte{0, 0, 2, 5}),
+		),
+		extendedTagInfo(tlv(paramEPCData, be16(96), epc)[:10]),
+	}}
+
+	locs, dirs := r.ExtractImpinjGatewayData()
+	assert.Equal(t, []ImpinjTagLocation{{
+		EPC:          epc,
+		Type:         ImpinjLocationExit,
+		AntennaID:    7,
+		LastSeen:     1000000,
+		XCentimeters: x,
+		YCentimeters: y,
+	}}, locs)
+	assert.Equal(t, []ImpinjTagDirection{{
+		EPC:             epc,
+		Type:            ImpinjDirectionUpdate,
+		FirstSeenSector: 2,
+		LastSeenSector:  5,
+		FirstSeen:       2000000,
+		LastSeen:        3000000,
+	}}, dirs)
+}
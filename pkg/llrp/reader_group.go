@@ -12,9 +12,15 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
-const defaultROSpecID = 1
+const (
+	defaultROSpecID = 1
+
+	// DefaultGroupName is the name of the ReaderGroup returned by NewReaderGroup.
+	DefaultGroupName = "default"
+)
 
 // ROGenerator generates a new ROSpec from a Behavior and Environment,
 // or returns an error if it cannot produce an ROSpec to satisfy the constraints.
@@ -22,12 +28,15 @@ type ROGenerator interface {
 	NewROSpec(b Behavior, e Environment) (*ROSpec, error)
 }
 
-// ReportProcessor is anything that can accept a list of TagReportData.
-type ReportProcessor interface {
-	ProcessTagReport(tags []TagReportData)
+// ReportNormalizerSource is anything that provides a ReportNormalizer
+// for the TagReportData it reports.
+type ReportNormalizerSource interface {
+	// ReportNormalizer returns the ReportNormalizer for the TagReportData,
+	// or nil if it needn't be normalized.
+	ReportNormalizer() *ReportNormalizer
 }
 
-// TagReader is something which can process TagReportData
+// TagReader is something which can normalize TagReportData
 // generated as a result of executing any ROSpec it generates.
 //
 // It can and should assume that TagReportData resulted from an ROSpec it generated,
@@ -35,21 +44,41 @@ type ReportProcessor interface {
 // is up to (and should be specified by) the particular TagReader implementation.
 type TagReader interface {
 	ROGenerator
-	ReportProcessor
+	ReportNormalizerSource
 }
 
 // A ReaderGroup unites a collection of named TagReader instances
 // with a single, specific Behavior and Environment.
+//
+// It tracks the health of the Readers it manages
+// via the operations it performs on them and the reports they send.
 type ReaderGroup struct {
 	mu       sync.RWMutex
+	name     string
 	readers  map[string]TagReader
 	env      Environment
 	behavior Behavior
+	health   *HealthTracker
+
+	// normalizers holds a map[string]*ReportNormalizer of each TagReader's ReportNormalizer.
+	// It's replaced, rather than modified, whenever readers changes,
+	// so reports can be normalized without taking the lock.
+	normalizers atomic.Value
+
+	// reading is true between calls to StartAll and StopAll,
+	// so that Readers added in between can be started, too.
+	reading bool
+
+	// replaceFailed, if set, is called with the name of each Reader
+	// whose ROSpec SetBehavior fails to replace, so it can be retried later.
+	replaceFailed func(name string)
 }
 
 func NewReaderGroup() *ReaderGroup {
 	return &ReaderGroup{
+		name:    DefaultGroupName,
 		readers: map[string]TagReader{},
+		health:  NewHealthTracker(),
 		env:     Environment{},
 		behavior: Behavior{
 			ImpinjOptions: &ImpinjOptions{SuppressMonza: false},
@@ -70,36 +99,68 @@ func (rg *ReaderGroup) Behavior() Behavior {
 	return b
 }
 
-// WriteReaders writes to w a JSON-formatted list of readers in this group.
+// Health returns the HealthTracker the ReaderGroup uses to track its Readers.
+func (rg *ReaderGroup) Health() *HealthTracker {
+	return rg.health
+}
+
+// WriteReaders writes to w a JSON-formatted list of ReaderStatus values
+// for readers in this group, as well as those that have left it
+// but are still known to its HealthTracker.
+// The Group of the latter is empty.
 func (rg *ReaderGroup) WriteReaders(w io.Writer) error {
 	rg.mu.RLock()
 	defer rg.mu.RUnlock()
 
-	s := struct{ Readers []string }{Readers: make([]string, 0, len(rg.readers))}
-	for r := range rg.readers {
-		s.Readers = append(s.Readers, r)
+	s := struct{ Readers []ReaderStatus }{Readers: make([]ReaderStatus, 0, len(rg.readers))}
+	known := map[string]bool{}
+	for _, status := range rg.health.Statuses() {
+		known[status.Name] = true
+		if _, ok := rg.readers[status.Name]; ok {
+			status.Group = rg.name
+		}
+		s.Readers = append(s.Readers, status)
+	}
+
+	for name := range rg.readers {
+		if !known[name] {
+			s.Readers = append(s.Readers, ReaderStatus{Name: name, Group: rg.name})
+		}
 	}
 
 	return json.NewEncoder(w).Encode(s)
 }
 
-// ProcessTagReport uses the named TagReader
-// to process the list of TagReportData.
-//
-// If no Reader in the ReaderGroup matches the given name,
-// this method returns false.
-// Otherwise, it returns true.
-func (rg *ReaderGroup) ProcessTagReport(name string, tags []TagReportData) bool {
+// HasReader returns true if the ReaderGroup manages a TagReader with the given name.
+func (rg *ReaderGroup) HasReader(name string) bool {
 	rg.mu.RLock()
-	tr, ok := rg.readers[name]
+	_, ok := rg.readers[name]
 	rg.mu.RUnlock()
+	return ok
+}
 
-	if !ok {
-		return false
-	}
+// ReportNormalizer returns the ReportNormalizer for the named Reader's TagReportData,
+// which is nil if its reports needn't be normalized,
+// or false if no Reader in the ReaderGroup matches the given name.
+//
+// It doesn't take the ReaderGroup's lock,
+// so it's cheap enough to call for every report.
+// The ReportNormalizer is replaced when the Reader is (re)added,
+// so callers shouldn't hold on to it.
+func (rg *ReaderGroup) ReportNormalizer(name string) (*ReportNormalizer, bool) {
+	normalizers, _ := rg.normalizers.Load().(map[string]*ReportNormalizer)
+	n, ok := normalizers[name]
+	return n, ok
+}
 
-	tr.ProcessTagReport(tags)
-	return true
+// updateNormalizers replaces the normalizers after a change to readers.
+// The caller must hold the write lock.
+func (rg *ReaderGroup) updateNormalizers() {
+	normalizers := make(map[string]*ReportNormalizer, len(rg.readers))
+	for name, tr := range rg.readers {
+		normalizers[name] = tr.ReportNormalizer()
+	}
+	rg.normalizers.Store(normalizers)
 }
 
 // RemoveReader removes the named Reader from the ReaderGroup, if present.
@@ -107,6 +168,7 @@ func (rg *ReaderGroup) ProcessTagReport(name string, tags []TagReportData) bool
 func (rg *ReaderGroup) RemoveReader(name string) {
 	rg.mu.Lock()
 	delete(rg.readers, name)
+	rg.updateNormalizers()
 	rg.mu.Unlock()
 }
 
@@ -123,11 +185,14 @@ func (rg *ReaderGroup) RemoveReader(name string) {
 // and ReaderGroup will send appropriate commands to the TagReader
 // in response to calls to StartAll or StopAll.
 //
-// On failure, the ReaderGroup rejects the TagReader and returns an error.
+// On failure, the ReaderGroup rejects the TagReader and returns an error,
+// which it also records as the Reader's last error.
 // Because part of this process attempts to replace the device's ROSpec,
 // it's possible that device's ROSpec is deleted without a new one replacing it.
-func (rg *ReaderGroup) AddReader(ds DSClient, name string) error {
-	r, err := ds.NewReader(name)
+func (rg *ReaderGroup) AddReader(ds DSClient, name string) (err error) {
+	defer func() { rg.health.RecordError(name, err) }()
+
+	r, devCap, err := ds.newReader(name)
 	if err != nil {
 		return err
 	}
@@ -149,8 +214,14 @@ func (rg *ReaderGroup) AddReader(ds DSClient, name string) error {
 
 	rg.mu.Lock()
 	rg.readers[name] = r
+	rg.updateNormalizers()
 	rg.mu.Unlock()
 
+	pen := VendorPEN(devCap.GeneralDeviceCapabilities.DeviceManufacturer)
+	rg.health.setDevice(name, vendorName(pen), modelName(pen, devCap.GeneralDeviceCapabilities.Model))
+	rg.health.ensureConnected(name)
+	rg.health.setROSpecState(name, false, false)
+
 	ds.lc.Info(fmt.Sprintf("Successfully added device %s to default group.", name))
 
 	return nil
@@ -187,7 +258,8 @@ func replaceRO(ds DSClient, name string, spec *ROSpec) error {
 // which is returned after the last update call completes.
 // A failure to set one TagReader's ROSpec does not have an impact on others.
 // The ReaderGroup has still accepted the new Behavior
-// and will continue to use it in future calls.
+// and will continue to use it in future calls;
+// if a Reconciler manages the ReaderGroup, it retries the failed TagReaders.
 //
 // It is safe to call SetBehavior multiple times with the same Behavior,
 // although doing so will reapply it to every TagReader in the ReaderGroup.
@@ -210,6 +282,7 @@ func (rg *ReaderGroup) SetBehavior(ds DSClient, b Behavior) error {
 	rg.behavior = b
 
 	// Replace each reader's ROSpec.
+	replaceFailed := rg.replaceFailed
 	errs := make(chan error, len(specs))
 	wg := sync.WaitGroup{}
 	wg.Add(len(specs))
@@ -217,8 +290,14 @@ func (rg *ReaderGroup) SetBehavior(ds DSClient, b Behavior) error {
 		go func(name string, s *ROSpec) {
 			defer wg.Done()
 			if err := replaceRO(ds, name, s); err != nil {
+				rg.health.RecordError(name, err)
+				if replaceFailed != nil {
+					replaceFailed(name)
+				}
 				errs <- errors.WithMessagef(err, "failed to replace ROSpec for %q", name)
+				return
 			}
+			rg.health.setROSpecState(name, false, false)
 		}(d, s)
 	}
 
@@ -255,24 +334,66 @@ func (me MultiErr) Error() string {
 }
 
 // StartAll uses the DSClient to start all TagReaders in the ReaderGroup.
+//
+// Until StopAll is called, Readers later added to the ReaderGroup
+// by a Reconciler are started, too.
 func (rg *ReaderGroup) StartAll(ds DSClient) error {
+	rg.mu.Lock()
+	rg.reading = true
+	rg.mu.Unlock()
+
 	rg.mu.RLock()
 	defer rg.mu.RUnlock()
 
+	trigger := rg.behavior.StartTrigger().Trigger
+
 	var errs []error
 	for name := range rg.readers {
-		if err := ds.EnableROSpec(name, 1); err != nil {
+		errs = append(errs, rg.startReader(ds, name, trigger)...)
+	}
+
+	if errs != nil {
+		return MultiErr(errs)
+	}
+	return nil
+}
+
+// startReader enables the named Reader's ROSpec,
+// and starts it if the trigger requires an explicit start.
+// It returns any errors the DSClient returned.
+func (rg *ReaderGroup) startReader(ds DSClient, name string, trigger ROSpecStartTriggerType) (errs []error) {
+	if err := ds.EnableROSpec(name, 1); err != nil {
+		errs = append(errs, err)
+		rg.health.RecordError(name, err)
+	} else {
+		rg.health.setROSpecState(name, true, trigger == ROStartTriggerImmediate)
+	}
+
+	if trigger == ROStartTriggerNone {
+		if err := ds.StartROSpec(name, 1); err != nil {
 			errs = append(errs, err)
+			rg.health.RecordError(name, err)
+		} else {
+			rg.health.setROSpecState(name, true, true)
 		}
+	}
 
-		if rg.behavior.StartTrigger().Trigger == ROStartTriggerNone {
-			if err := ds.StartROSpec(name, 1); err != nil {
-				errs = append(errs, err)
-			}
-		}
+	return errs
+}
+
+// resume starts the named Reader if the ReaderGroup is reading,
+// e.g., because the Reader rebooted while the rest of the group was running.
+func (rg *ReaderGroup) resume(ds DSClient, name string) error {
+	rg.mu.RLock()
+	reading := rg.reading
+	trigger := rg.behavior.StartTrigger().Trigger
+	rg.mu.RUnlock()
+
+	if !reading {
+		return nil
 	}
 
-	if errs != nil {
+	if errs := rg.startReader(ds, name, trigger); errs != nil {
 		return MultiErr(errs)
 	}
 	return nil
@@ -280,6 +401,10 @@ func (rg *ReaderGroup) StartAll(ds DSClient) error {
 
 // StopAll uses the DSClient to stop all TagReaders in the ReaderGroup.
 func (rg *ReaderGroup) StopAll(ds DSClient) error {
+	rg.mu.Lock()
+	rg.reading = false
+	rg.mu.Unlock()
+
 	rg.mu.RLock()
 	defer rg.mu.RUnlock()
 
@@ -288,11 +413,17 @@ func (rg *ReaderGroup) StopAll(ds DSClient) error {
 		if rg.behavior.StartTrigger().Trigger == ROStartTriggerNone {
 			if err := ds.StopROSpec(name, 1); err != nil {
 				errs = append(errs, err)
+				rg.health.RecordError(name, err)
+			} else {
+				rg.health.setROSpecState(name, true, false)
 			}
 		}
 
 		if err := ds.DisableROSpec(name, 1); err != nil {
 			errs = append(errs, err)
+			rg.health.RecordError(name, err)
+		} else {
+			rg.health.setROSpecState(name, false, false)
 		}
 	}
 
@@ -14,7 +14,7 @@ import (
 )
 
 func readerGroupHelper() *ReaderGroup {
-	return &ReaderGroup{mu: sync.RWMutex{}, readers: map[string]TagReader{}, env: Environment{}, behavior: Behavior{
+	return &ReaderGroup{mu: sync.RWMutex{}, name: DefaultGroupName, readers: map[string]TagReader{}, health: NewHealthTracker(), env: Environment{}, behavior: Behavior{
 		GPITrigger: nil, ImpinjOptions: &ImpinjOptions{SuppressMonza: false}, ScanType: ScanNormal, Duration: 0, Power: PowerTarget{Max: 3000}, Frequencies: nil}}
 }
 
@@ -128,48 +128,56 @@ func TestWriteReaders(t *testing.T) {
 	}
 }
 
-func TestProcessTagReport(t *testing.T) {
+func TestWriteReaders_status(t *testing.T) {
 	rg, _, tsClose := addReaderHelper(t)
 	defer tsClose()
 
-	type fields struct {
-		readers  map[string]TagReader
-		env      Environment
-		behavior Behavior
-	}
-	type args struct {
-		name string
-		tags []TagReportData
-	}
-	tests := []struct {
-		name   string
-		fields fields
-		args   args
-		want   bool
-	}{
-		{
-			name:   "OK - Reader found",
-			fields: fields{readers: map[string]TagReader{"test": rg.readers["test"]}, env: Environment{}, behavior: Behavior{}},
-			args:   args{name: "test", tags: []TagReportData{}},
-			want:   true,
-		},
-		{
-			name:   "OK - Reader not found",
-			fields: fields{readers: map[string]TagReader{}, env: Environment{}, behavior: Behavior{}},
-			args:   args{name: "test", tags: []TagReportData{}},
-			want:   false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			rg := &ReaderGroup{
-				readers:  tt.fields.readers,
-				env:      tt.fields.env,
-				behavior: tt.fields.behavior,
-			}
-			assert.Equal(t, rg.ProcessTagReport(tt.args.name, tt.args.tags), tt.want)
-		})
+	readStatuses := func() []ReaderStatus {
+		w := &bytes.Buffer{}
+		require.NoError(t, rg.WriteReaders(w))
+		var s struct{ Readers []ReaderStatus }
+		require.NoError(t, json.Unmarshal(w.Bytes(), &s))
+		return s.Readers
 	}
+
+	statuses := readStatuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "test", statuses[0].Name)
+	assert.Equal(t, DefaultGroupName, statuses[0].Group)
+	assert.Equal(t, "Impinj", statuses[0].Manufacturer)
+	assert.True(t, statuses[0].Connected)
+	assert.NotZero(t, statuses[0].ConnectedSince)
+	assert.False(t, statuses[0].ROSpecEnabled)
+	assert.Empty(t, statuses[0].LastError)
+
+	rg.RemoveReader("test")
+	rg.Health().Disconnected("test")
+
+	statuses = readStatuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "test", statuses[0].Name)
+	assert.Empty(t, statuses[0].Group)
+	assert.False(t, statuses[0].Connected)
+}
+
+func TestReportNormalizer(t *testing.T) {
+	rg, _, tsClose := addReaderHelper(t)
+	defer tsClose()
+
+	n, ok := rg.ReportNormalizer("test")
+	assert.True(t, ok)
+	assert.Same(t, rg.readers["test"].ReportNormalizer(), n)
+
+	_, ok = rg.ReportNormalizer("unknown")
+	assert.False(t, ok)
+
+	rg.RemoveReader("test")
+	_, ok = rg.ReportNormalizer("test")
+	assert.False(t, ok)
+
+	// a ReaderGroup without Readers has no normalizers
+	_, ok = NewReaderGroup().ReportNormalizer("test")
+	assert.False(t, ok)
 }
 
 func TestRemoveReader(t *testing.T) {
@@ -213,6 +221,17 @@ func TestRemoveReader(t *testing.T) {
 	}
 }
 
+func TestHasReader(t *testing.T) {
+	rg, _, tsClose := addReaderHelper(t)
+	defer tsClose()
+
+	assert.True(t, rg.HasReader("test"))
+	assert.False(t, rg.HasReader("unknown"))
+
+	rg.RemoveReader("test")
+	assert.False(t, rg.HasReader("test"))
+}
+
 func TestAddReader(t *testing.T) {
 	_, dsClient, tsClose := addReaderHelper(t)
 	defer tsClose()
@@ -0,0 +1,429 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// rateWindowSecs is the width of the sliding window
+// used to calculate report rates and count recently seen tags.
+const rateWindowSecs = 60
+
+// ReaderStatus is a point-in-time view of a Reader's operational status.
+//
+// Timestamps are Unix Epoch milliseconds, and are 0 if the event never happened.
+type ReaderStatus struct {
+	Name         string `json:"name"`
+	Group        string `json:"group"`
+	Manufacturer string `json:"manufacturer"`
+	Model        string `json:"model"`
+
+	Connected      bool  `json:"connected"`
+	ConnectedSince int64 `json:"connectedSince"`
+
+	// ROSpecEnabled and ROSpecRunning reflect the last commands
+	// successfully sent to the Reader,
+	// updated by any ROSpec events it reports.
+	ROSpecEnabled bool `json:"roSpecEnabled"`
+	ROSpecRunning bool `json:"roSpecRunning"`
+	// ROSpecRunningSince is when the ROSpec started running.
+	ROSpecRunningSince int64 `json:"roSpecRunningSince"`
+
+	LastReport int64 `json:"lastReport"`
+	// ReportRate is the mean number of ROAccessReports per second
+	// received from the Reader over the last minute.
+	ReportRate float64 `json:"reportRate"`
+	// TagsSeenLastMinute is the number of unique EPCs
+	// the Reader reported over the last minute.
+	TagsSeenLastMinute int `json:"tagsSeenLastMinute"`
+
+	LastError     string `json:"lastError,omitempty"`
+	LastErrorTime int64  `json:"lastErrorTime,omitempty"`
+
+	// Reconcile is the Reader's state in the Reconciler, if it's managed by one.
+	Reconcile *ReconcileStatus `json:"reconcile,omitempty"`
+
+	// The remaining fields are updated from ReaderEventNotifications,
+	// so they're only as accurate as the events the Reader is configured to send.
+
+	// DisconnectedAntennas lists antennas the Reader reported as disconnected,
+	// sorted by ID.
+	DisconnectedAntennas []AntennaID `json:"disconnectedAntennas,omitempty"`
+	// GPIState maps GPI ports to the last state the Reader reported for them.
+	GPIState map[uint16]bool `json:"gpiState,omitempty"`
+
+	LastException     string `json:"lastException,omitempty"`
+	LastExceptionTime int64  `json:"lastExceptionTime,omitempty"`
+
+	// ReportBufferWarningLevel is the buffer fill percentage
+	// the Reader reported with its last buffer warning.
+	ReportBufferWarningLevel uint8 `json:"reportBufferWarningLevel,omitempty"`
+	ReportBufferWarningTime  int64 `json:"reportBufferWarningTime,omitempty"`
+	ReportBufferOverflowTime int64 `json:"reportBufferOverflowTime,omitempty"`
+
+	LastAISpecEnd int64 `json:"lastAISpecEnd,omitempty"`
+}
+
+// HealthTracker tracks the operational status of a collection of Readers.
+//
+// It's safe for concurrent use.
+// All of its methods are safe to call on a nil *HealthTracker,
+// in which case they do nothing and report no Readers.
+type HealthTracker struct {
+	mu      sync.Mutex
+	readers map[string]*readerHealth
+}
+
+// readerHealth holds the tracked values for a single Reader.
+type readerHealth struct {
+	status  ReaderStatus
+	reports rateWindow
+
+	// epcs maps EPCs to the Unix second they were last reported.
+	epcs      map[string]int64
+	lastPrune int64
+}
+
+// rateWindow counts events in one second buckets over a sliding window.
+type rateWindow struct {
+	buckets [rateWindowSecs]uint32
+	lastSec int64
+}
+
+// advance zeroes any buckets that fell out of the window since the last update.
+func (w *rateWindow) advance(sec int64) {
+	if sec <= w.lastSec {
+		return
+	}
+
+	if sec-w.lastSec >= rateWindowSecs {
+		w.buckets = [rateWindowSecs]uint32{}
+	} else {
+		for s := w.lastSec + 1; s <= sec; s++ {
+			w.buckets[s%rateWindowSecs] = 0
+		}
+	}
+	w.lastSec = sec
+}
+
+func (w *rateWindow) add(sec int64, n uint32) {
+	w.advance(sec)
+	w.buckets[sec%rateWindowSecs] += n
+}
+
+func (w *rateWindow) sum(sec int64) (total uint32) {
+	w.advance(sec)
+	for _, n := range w.buckets {
+		total += n
+	}
+	return total
+}
+
+// NewHealthTracker returns a new HealthTracker tracking no Readers.
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{readers: map[string]*readerHealth{}}
+}
+
+// get returns the readerHealth for the named Reader, adding it if necessary.
+// The caller must hold the lock.
+func (ht *HealthTracker) get(name string) *readerHealth {
+	rh, ok := ht.readers[name]
+	if !ok {
+		rh = &readerHealth{
+			status: ReaderStatus{Name: name},
+			epcs:   map[string]int64{},
+		}
+		ht.readers[name] = rh
+	}
+	return rh
+}
+
+// update calls f with the named Reader's readerHealth while holding the lock.
+func (ht *HealthTracker) update(name string, f func(rh *readerHealth)) {
+	if ht == nil {
+		return
+	}
+
+	ht.mu.Lock()
+	f(ht.get(name))
+	ht.mu.Unlock()
+}
+
+// Connected records that the named Reader connected to the Device Service.
+// The Reader may have been rewired while it was disconnected,
+// so this forgets its antenna and GPI state.
+func (ht *HealthTracker) Connected(name string) {
+	now := unixMillis(time.Now())
+	ht.update(name, func(rh *readerHealth) {
+		rh.status.Connected = true
+		rh.status.ConnectedSince = now
+		rh.status.DisconnectedAntennas = nil
+		rh.status.GPIState = nil
+	})
+}
+
+// ensureConnected marks the Reader connected if it isn't already,
+// leaving the time it connected unchanged if it is.
+func (ht *HealthTracker) ensureConnected(name string) {
+	now := unixMillis(time.Now())
+	ht.update(name, func(rh *readerHealth) {
+		if !rh.status.Connected {
+			rh.status.Connected = true
+			rh.status.ConnectedSince = now
+		}
+	})
+}
+
+// Disconnected records that the named Reader disconnected from the Device Service.
+// A Reader loses its ROSpecs when it disconnects,
+// so this also marks its ROSpec disabled.
+func (ht *HealthTracker) Disconnected(name string) {
+	ht.update(name, func(rh *readerHealth) {
+		rh.status.Connected = false
+		rh.status.ConnectedSince = 0
+		rh.setROSpecState(false, false, 0)
+	})
+}
+
+// RecordError records an error resulting from an operation on the named Reader.
+// It does nothing if err is nil.
+func (ht *HealthTracker) RecordError(name string, err error) {
+	if err == nil {
+		return
+	}
+
+	now := unixMillis(time.Now())
+	ht.update(name, func(rh *readerHealth) {
+		rh.status.LastError = err.Error()
+		rh.status.LastErrorTime = now
+	})
+}
+
+// setDevice records the Reader's manufacturer and model.
+func (ht *HealthTracker) setDevice(name, manufacturer, model string) {
+	ht.update(name, func(rh *readerHealth) {
+		rh.status.Manufacturer = manufacturer
+		rh.status.Model = model
+	})
+}
+
+// setReconcileStatus records the Reader's ReconcileStatus.
+func (ht *HealthTracker) setReconcileStatus(name string, rs ReconcileStatus) {
+	ht.update(name, func(rh *readerHealth) {
+		// Snapshots share the pointer, so this must not modify the existing value.
+		rh.status.Reconcile = &rs
+	})
+}
+
+// clearReconcileStatus removes the Reader's ReconcileStatus.
+func (ht *HealthTracker) clearReconcileStatus(name string) {
+	ht.update(name, func(rh *readerHealth) {
+		rh.status.Reconcile = nil
+	})
+}
+
+// setROSpecState records the state of the Reader's ROSpec.
+func (ht *HealthTracker) setROSpecState(name string, enabled, running bool) {
+	now := unixMillis(time.Now())
+	ht.update(name, func(rh *readerHealth) {
+		rh.setROSpecState(enabled, running, now)
+	})
+}
+
+// setROSpecState updates the ROSpec state,
+// tracking when it started running if it wasn't already.
+func (rh *readerHealth) setROSpecState(enabled, running bool, now int64) {
+	switch {
+	case !running:
+		rh.status.ROSpecRunningSince = 0
+	case !rh.status.ROSpecRunning:
+		rh.status.ROSpecRunningSince = now
+	}
+
+	rh.status.ROSpecEnabled = enabled
+	rh.status.ROSpecRunning = running
+}
+
+// HandleNotification updates the named Reader's status
+// from the events in a ReaderEventNotification.
+//
+// ROSpecEvents only update the ROSpec state if they're for the ROSpec
+// ReaderGroups manage, since the Reader may have others.
+// Connection events are not handled here;
+// use Connected and Disconnected for those.
+func (ht *HealthTracker) HandleNotification(name string, data *ReaderEventNotificationData) {
+	now := unixMillis(time.Now())
+	ht.update(name, func(rh *readerHealth) {
+		s := &rh.status
+
+		if e := data.AntennaEvent; e != nil {
+			s.DisconnectedAntennas = setAntennaDisconnected(s.DisconnectedAntennas,
+				e.AntennaID, e.Event == AntennaDisconnected)
+		}
+
+		if e := data.GPIEvent; e != nil {
+			if s.GPIState == nil {
+				s.GPIState = map[uint16]bool{}
+			}
+			s.GPIState[e.Port] = e.Event
+		}
+
+		if e := data.ROSpecEvent; e != nil && e.ROSpecID == defaultROSpecID {
+			switch e.Event {
+			case ROSpecStarted:
+				rh.setROSpecState(true, true, now)
+			case ROSpecEnded, ROSpecPreempted:
+				rh.setROSpecState(s.ROSpecEnabled, false, now)
+			}
+		}
+
+		if e := data.AISpecEvent; e != nil && e.Event == AISpecEnded {
+			s.LastAISpecEnd = now
+		}
+
+		if e := data.ReaderExceptionEvent; e != nil {
+			s.LastException = e.Message
+			s.LastExceptionTime = now
+		}
+
+		if e := data.ReportBufferLevelWarningEvent; e != nil {
+			s.ReportBufferWarningLevel = uint8(*e)
+			s.ReportBufferWarningTime = now
+		}
+
+		if data.ReportBufferOverflowErrorEvent != nil {
+			s.ReportBufferOverflowTime = now
+		}
+	})
+}
+
+// setAntennaDisconnected adds or removes id from the sorted ids slice,
+// returning the updated slice.
+//
+// The slice is always copied, since it may be shared with a previous snapshot.
+func setAntennaDisconnected(ids []AntennaID, id AntennaID, disconnected bool) []AntennaID {
+	updated := make([]AntennaID, 0, len(ids)+1)
+	for _, other := range ids {
+		if other != id {
+			updated = append(updated, other)
+		}
+	}
+
+	if disconnected {
+		updated = append(updated, id)
+		sort.Slice(updated, func(i, j int) bool { return updated[i] < updated[j] })
+	}
+
+	if len(updated) == 0 {
+		return nil
+	}
+	return updated
+}
+
+// ReportReceived records the receipt of a tag report from the named Reader
+// with the given (hex-encoded) EPCs.
+func (ht *HealthTracker) ReportReceived(name string, epcs []string) {
+	ht.reportReceivedAt(name, epcs, time.Now())
+}
+
+func (ht *HealthTracker) reportReceivedAt(name string, epcs []string, t time.Time) {
+	sec := t.Unix()
+	ht.update(name, func(rh *readerHealth) {
+		rh.status.LastReport = unixMillis(t)
+		rh.reports.add(sec, 1)
+
+		for _, epc := range epcs {
+			rh.epcs[epc] = sec
+		}
+
+		if sec-rh.lastPrune >= rateWindowSecs {
+			rh.pruneEPCs(sec)
+		}
+	})
+}
+
+// pruneEPCs removes EPCs that haven't been seen within the rate window.
+func (rh *readerHealth) pruneEPCs(sec int64) {
+	for epc, seen := range rh.epcs {
+		if sec-seen >= rateWindowSecs {
+			delete(rh.epcs, epc)
+		}
+	}
+	rh.lastPrune = sec
+}
+
+// Status returns the named Reader's current status,
+// or false if the HealthTracker doesn't know about the Reader.
+func (ht *HealthTracker) Status(name string) (ReaderStatus, bool) {
+	return ht.statusAt(name, time.Now())
+}
+
+func (ht *HealthTracker) statusAt(name string, t time.Time) (ReaderStatus, bool) {
+	if ht == nil {
+		return ReaderStatus{}, false
+	}
+
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	rh, ok := ht.readers[name]
+	if !ok {
+		return ReaderStatus{}, false
+	}
+	return rh.snapshot(t.Unix()), true
+}
+
+// Statuses returns the current status of every Reader the HealthTracker knows about,
+// sorted by name.
+func (ht *HealthTracker) Statuses() []ReaderStatus {
+	return ht.statusesAt(time.Now())
+}
+
+func (ht *HealthTracker) statusesAt(t time.Time) []ReaderStatus {
+	if ht == nil {
+		return nil
+	}
+
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	sec := t.Unix()
+	statuses := make([]ReaderStatus, 0, len(ht.readers))
+	for _, rh := range ht.readers {
+		statuses = append(statuses, rh.snapshot(sec))
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// snapshot returns a copy of the readerHealth's status with its computed values filled in.
+// The caller must hold the HealthTracker's lock.
+func (rh *readerHealth) snapshot(sec int64) ReaderStatus {
+	rh.pruneEPCs(sec)
+	s := rh.status
+	s.ReportRate = float64(rh.reports.sum(sec)) / rateWindowSecs
+	s.TagsSeenLastMinute = len(rh.epcs)
+
+	// DisconnectedAntennas is copied on write, so only the map needs copying.
+	if rh.status.GPIState != nil {
+		s.GPIState = make(map[uint16]bool, len(rh.status.GPIState))
+		for port, state := range rh.status.GPIState {
+			s.GPIState[port] = state
+		}
+	}
+	return s
+}
+
+// unixMillis returns t as milliseconds since the Unix Epoch.
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,171 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRateWindow(t *testing.T) {
+	var w rateWindow
+	const start = int64(1000)
+
+	w.add(start, 1)
+	w.add(start, 2)
+	w.add(start+1, 3)
+	assert.Equal(t, uint32(6), w.sum(start+1))
+
+	// the first bucket falls out of the window
+	assert.Equal(t, uint32(3), w.sum(start+rateWindowSecs))
+	// and then everything does
+	assert.Equal(t, uint32(0), w.sum(start+rateWindowSecs+1))
+
+	w.add(start+rateWindowSecs*5, 4)
+	assert.Equal(t, uint32(4), w.sum(start+rateWindowSecs*5))
+}
+
+func TestHealthTracker_reports(t *testing.T) {
+	ht := NewHealthTracker()
+	now := time.Now()
+
+	tags := []string{"01", "02", "01"}
+
+	for i := 0; i < 30; i++ {
+		ht.reportReceivedAt("r1", tags, now)
+	}
+	ht.reportReceivedAt("r1", []string{"03"}, now.Add(time.Second))
+
+	s, ok := ht.statusAt("r1", now.Add(time.Second))
+	require.True(t, ok)
+	assert.Equal(t, "r1", s.Name)
+	assert.Equal(t, unixMillis(now.Add(time.Second)), s.LastReport)
+	assert.Equal(t, 31.0/rateWindowSecs, s.ReportRate)
+	assert.Equal(t, 3, s.TagsSeenLastMinute)
+
+	s, ok = ht.statusAt("r1", now.Add(rateWindowSecs*time.Second))
+	require.True(t, ok)
+	assert.Equal(t, 1.0/rateWindowSecs, s.ReportRate)
+	assert.Equal(t, 1, s.TagsSeenLastMinute)
+
+	s, ok = ht.statusAt("r1", now.Add(2*rateWindowSecs*time.Second))
+	require.True(t, ok)
+	assert.Zero(t, s.ReportRate)
+	assert.Zero(t, s.TagsSeenLastMinute)
+
+	_, ok = ht.Status("unknown")
+	assert.False(t, ok)
+}
+
+func TestHealthTracker_state(t *testing.T) {
+	ht := NewHealthTracker()
+
+	ht.Connected("b")
+	ht.setROSpecState("b", true, true)
+	ht.RecordError("b", nil)
+	ht.ensureConnected("a")
+
+	statuses := ht.Statuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "a", statuses[0].Name)
+	assert.Equal(t, "b", statuses[1].Name)
+	assert.True(t, statuses[1].Connected)
+	assert.True(t, statuses[1].ROSpecEnabled)
+	assert.True(t, statuses[1].ROSpecRunning)
+	assert.NotZero(t, statuses[1].ROSpecRunningSince)
+	assert.Empty(t, statuses[1].LastError)
+
+	runningSince := statuses[1].ROSpecRunningSince
+	ht.setROSpecState("b", true, true)
+	s, _ := ht.Status("b")
+	assert.Equal(t, runningSince, s.ROSpecRunningSince)
+
+	since := statuses[1].ConnectedSince
+	ht.ensureConnected("b")
+	s, _ = ht.Status("b")
+	assert.Equal(t, since, s.ConnectedSince)
+
+	ht.RecordError("b", errors.New("oops"))
+	ht.Disconnected("b")
+	s, _ = ht.Status("b")
+	assert.False(t, s.Connected)
+	assert.Zero(t, s.ConnectedSince)
+	assert.False(t, s.ROSpecEnabled)
+	assert.False(t, s.ROSpecRunning)
+	assert.Zero(t, s.ROSpecRunningSince)
+	assert.Equal(t, "oops", s.LastError)
+	assert.NotZero(t, s.LastErrorTime)
+}
+
+func TestHealthTracker_nil(t *testing.T) {
+	var ht *HealthTracker
+	ht.Connected("a")
+	ht.Disconnected("a")
+	ht.RecordError("a", errors.New("oops"))
+	ht.ReportReceived("a", nil)
+
+	_, ok := ht.Status("a")
+	assert.False(t, ok)
+	assert.Empty(t, ht.Statuses())
+}
+
+func TestHealthTracker_HandleNotification(t *testing.T) {
+	ht := NewHealthTracker()
+	ht.Connected("r")
+
+	bufferLevel := ReportBufferLevelWarningEvent(85)
+	notifications := []ReaderEventNotificationData{
+		{AntennaEvent: &AntennaEvent{Event: AntennaDisconnected, AntennaID: 3}},
+		{AntennaEvent: &AntennaEvent{Event: AntennaDisconnected, AntennaID: 1}},
+		{AntennaEvent: &AntennaEvent{Event: AntennaDisconnected, AntennaID: 3}},
+		{GPIEvent: &GPIEvent{Port: 2, Event: true}},
+		{ROSpecEvent: &ROSpecEvent{Event: ROSpecStarted, ROSpecID: defaultROSpecID}},
+		// events for ROSpecs we don't manage are ignored
+		{ROSpecEvent: &ROSpecEvent{Event: ROSpecEnded, ROSpecID: defaultROSpecID + 1}},
+		{AISpecEvent: &AISpecEvent{Event: AISpecEnded, ROSpecID: defaultROSpecID}},
+		{ReaderExceptionEvent: &ReaderExceptionEvent{Message: "antenna fault"}},
+		{ReportBufferLevelWarningEvent: &bufferLevel},
+		{ReportBufferOverflowErrorEvent: &ReportBufferOverflowErrorEvent{}},
+	}
+	for i := range notifications {
+		ht.HandleNotification("r", &notifications[i])
+	}
+
+	s, ok := ht.Status("r")
+	require.True(t, ok)
+	assert.Equal(t, []AntennaID{1, 3}, s.DisconnectedAntennas)
+	assert.Equal(t, map[uint16]bool{2: true}, s.GPIState)
+	assert.True(t, s.ROSpecEnabled)
+	assert.True(t, s.ROSpecRunning)
+	assert.NotZero(t, s.LastAISpecEnd)
+	assert.Equal(t, "antenna fault", s.LastException)
+	assert.NotZero(t, s.LastExceptionTime)
+	assert.Equal(t, uint8(85), s.ReportBufferWarningLevel)
+	assert.NotZero(t, s.ReportBufferWarningTime)
+	assert.NotZero(t, s.ReportBufferOverflowTime)
+
+	// snapshots don't share state with the tracker
+	s.GPIState[2] = false
+	ht.HandleNotification("r", &ReaderEventNotificationData{
+		AntennaEvent: &AntennaEvent{Event: AntennaConnected, AntennaID: 1},
+		ROSpecEvent:  &ROSpecEvent{Event: ROSpecEnded, ROSpecID: defaultROSpecID},
+	})
+
+	after, _ := ht.Status("r")
+	assert.Equal(t, []AntennaID{3}, after.DisconnectedAntennas)
+	assert.Equal(t, []AntennaID{1, 3}, s.DisconnectedAntennas)
+	assert.Equal(t, map[uint16]bool{2: true}, after.GPIState)
+	assert.True(t, after.ROSpecEnabled)
+	assert.False(t, after.ROSpecRunning)
+
+	ht.Connected("r")
+	after, _ = ht.Status("r")
+	assert.Empty(t, after.DisconnectedAntennas)
+	assert.Empty(t, after.GPIState)
+}
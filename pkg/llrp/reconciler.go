@@ -0,0 +1,354 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 5 * time.Minute
+)
+
+// ReconcileState describes a Reader's progress toward membership in a ReaderGroup.
+type ReconcileState string
+
+const (
+	// ReconcilePending means the Reader hasn't yet been added to the group.
+	ReconcilePending ReconcileState = "Pending"
+	// ReconcileRetrying means the last attempt to add the Reader failed,
+	// and another is scheduled.
+	ReconcileRetrying ReconcileState = "Retrying"
+	// ReconcileDone means the Reader was added to the group
+	// and, if the group is reading, started.
+	ReconcileDone ReconcileState = "Reconciled"
+)
+
+// ReconcileStatus is a Reader's reconciliation state.
+// Errors from failed attempts are recorded as the Reader's LastError.
+type ReconcileStatus struct {
+	State ReconcileState `json:"state"`
+	// Attempts counts attempts since the Reader was last requested.
+	Attempts    int   `json:"attempts"`
+	LastAttempt int64 `json:"lastAttempt"`
+	NextAttempt int64 `json:"nextAttempt,omitempty"`
+}
+
+// Reconciler adds Readers to a ReaderGroup in the background,
+// retrying failed attempts with exponential backoff
+// so that one misbehaving Reader doesn't prevent managing the others.
+//
+// The Readers it's asked to add form a desired state:
+// a Reader is added with the group's current Behavior
+// each time it's requested via Want,
+// such as after it reconnects following a reboot,
+// and if the group is reading, the Reader is started, too.
+// Readers whose ROSpecs the group fails to replace when its Behavior changes
+// are retried, too.
+type Reconciler struct {
+	rg *ReaderGroup
+	ds DSClient
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	readers map[string]*reconcileEntry
+	wake    chan struct{}
+
+	// known maps devices seen by Sync to a fingerprint of their DeviceInfo.
+	known map[string]string
+}
+
+type reconcileEntry struct {
+	status ReconcileStatus
+	// gen increments each time the Reader is requested,
+	// so an attempt can tell if it's been superseded.
+	gen      uint64
+	inFlight bool
+	backoff  time.Duration
+	next     time.Time
+}
+
+// NewReconciler returns a Reconciler that adds Readers to rg via ds.
+// It does nothing until Run is called.
+func NewReconciler(rg *ReaderGroup, ds DSClient) *Reconciler {
+	rc := &Reconciler{
+		rg:         rg,
+		ds:         ds,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+		readers:    map[string]*reconcileEntry{},
+		wake:       make(chan struct{}, 1),
+		known:      map[string]string{},
+	}
+
+	rg.mu.Lock()
+	rg.replaceFailed = rc.retry
+	rg.mu.Unlock()
+	return rc
+}
+
+// SyncResult lists the names of devices added, removed, or changed by a call to Sync.
+type SyncResult struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+// Sync updates the desired state to match the devices known to EdgeX Metadata.
+//
+// Devices not seen by a previous Sync are requested via Want,
+// as are devices whose profile or protocol information changed,
+// since the Device Service may now be talking to a different Reader.
+// Devices seen by a previous Sync but missing from this one were deleted,
+// so they're forgotten.
+//
+// Devices requested only via Want are never removed by Sync,
+// since the Device Service may know about a device before Metadata lists it.
+func (rc *Reconciler) Sync(devices []DeviceInfo) SyncResult {
+	res := SyncResult{Added: []string{}, Removed: []string{}, Changed: []string{}}
+
+	current := make(map[string]string, len(devices))
+	for _, d := range devices {
+		current[d.Name] = d.fingerprint()
+	}
+
+	rc.mu.Lock()
+	for name, fp := range current {
+		prev, ok := rc.known[name]
+		switch {
+		case !ok:
+			res.Added = append(res.Added, name)
+		case prev != fp:
+			res.Changed = append(res.Changed, name)
+		}
+		rc.known[name] = fp
+	}
+
+	for name := range rc.known {
+		if _, ok := current[name]; !ok {
+			res.Removed = append(res.Removed, name)
+			delete(rc.known, name)
+		}
+	}
+	rc.mu.Unlock()
+
+	sort.Strings(res.Added)
+	sort.Strings(res.Removed)
+	sort.Strings(res.Changed)
+
+	for _, name := range res.Added {
+		rc.Want(name)
+	}
+	for _, name := range res.Changed {
+		rc.Want(name)
+	}
+	for _, name := range res.Removed {
+		rc.Forget(name)
+	}
+
+	return res
+}
+
+// fingerprint returns a string which differs if the device's profile or protocols do.
+func (d DeviceInfo) fingerprint() string {
+	// Marshaling sorts the map keys, so equal values produce equal strings.
+	data, _ := json.Marshal(struct {
+		Profile   string
+		Protocols map[string]map[string]string
+	}{d.Profile.Name, d.Protocols})
+	return string(data)
+}
+
+// Want schedules an immediate attempt to add the named Reader to the ReaderGroup,
+// resetting its backoff if it was already scheduled.
+// If an attempt is in progress, another follows it.
+func (rc *Reconciler) Want(name string) {
+	rc.mu.Lock()
+	e, ok := rc.readers[name]
+	if !ok {
+		e = &reconcileEntry{}
+		rc.readers[name] = e
+	}
+	e.gen++
+	e.backoff = 0
+	e.next = time.Time{}
+	e.status.State = ReconcilePending
+	e.status.Attempts = 0
+	e.status.NextAttempt = 0
+	status := e.status
+	rc.mu.Unlock()
+
+	rc.rg.health.setReconcileStatus(name, status)
+	rc.notify()
+}
+
+// retry schedules another attempt to add the named Reader after its backoff,
+// as if an attempt to add it had just failed.
+// If an attempt is in progress, another follows it.
+func (rc *Reconciler) retry(name string) {
+	rc.mu.Lock()
+	e, ok := rc.readers[name]
+	if !ok {
+		e = &reconcileEntry{}
+		rc.readers[name] = e
+	}
+	e.gen++
+	rc.backOff(e, time.Now())
+	status := e.status
+	rc.mu.Unlock()
+
+	rc.rg.health.setReconcileStatus(name, status)
+	rc.notify()
+}
+
+// Forget removes the named Reader from the desired state and from the ReaderGroup.
+func (rc *Reconciler) Forget(name string) {
+	rc.mu.Lock()
+	delete(rc.readers, name)
+	rc.mu.Unlock()
+
+	rc.rg.RemoveReader(name)
+	rc.rg.health.clearReconcileStatus(name)
+}
+
+func (rc *Reconciler) notify() {
+	select {
+	case rc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run attempts to add Readers as they're due until the context is canceled,
+// then waits for any attempts in progress to complete.
+func (rc *Reconciler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		due, wait := rc.due(time.Now())
+		for name, gen := range due {
+			wg.Add(1)
+			go func(name string, gen uint64) {
+				defer wg.Done()
+				rc.attempt(name, gen)
+			}(name, gen)
+		}
+
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-rc.wake:
+		case <-timeout:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// due marks Readers whose next attempt is at or before now as in flight,
+// returning them mapped to their generation,
+// along with the time until the next scheduled attempt, or 0 if none are scheduled.
+func (rc *Reconciler) due(now time.Time) (due map[string]uint64, wait time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	due = map[string]uint64{}
+	for name, e := range rc.readers {
+		if e.inFlight || e.status.State == ReconcileDone {
+			continue
+		}
+
+		if !e.next.After(now) {
+			e.inFlight = true
+			due[name] = e.gen
+			continue
+		}
+
+		if until := e.next.Sub(now); wait == 0 || until < wait {
+			wait = until
+		}
+	}
+
+	return due, wait
+}
+
+// attempt adds the named Reader to the ReaderGroup, starting it if the group is reading,
+// then records the result.
+func (rc *Reconciler) attempt(name string, gen uint64) {
+	err := rc.rg.AddReader(rc.ds, name)
+	if err == nil {
+		err = rc.rg.resume(rc.ds, name)
+	}
+
+	now := time.Now()
+	rc.mu.Lock()
+	e, ok := rc.readers[name]
+	if !ok {
+		// It was forgotten while the attempt was in progress.
+		rc.mu.Unlock()
+		rc.rg.RemoveReader(name)
+		return
+	}
+
+	e.inFlight = false
+	e.status.Attempts++
+	e.status.LastAttempt = unixMillis(now)
+
+	switch {
+	case e.gen != gen:
+		// It was requested again while the attempt was in progress,
+		// so leave it pending for another attempt.
+	case err == nil:
+		e.backoff = 0
+		e.status.State = ReconcileDone
+		e.status.NextAttempt = 0
+	default:
+		rc.backOff(e, now)
+	}
+	status := e.status
+	rc.mu.Unlock()
+
+	rc.rg.health.setReconcileStatus(name, status)
+	if err != nil {
+		rc.ds.lc.Warn("Failed to reconcile Reader.", "device", name,
+			"error", err.Error(), "attempts", status.Attempts, "nextAttempt", status.NextAttempt)
+	}
+	rc.notify()
+}
+
+// backOff doubles the entry's backoff, within the Reconciler's limits,
+// and schedules its next attempt that long after now.
+// The caller must hold the Reconciler's lock.
+func (rc *Reconciler) backOff(e *reconcileEntry, now time.Time) {
+	e.backoff *= 2
+	if e.backoff < rc.minBackoff {
+		e.backoff = rc.minBackoff
+	} else if e.backoff > rc.maxBackoff {
+		e.backoff = rc.maxBackoff
+	}
+	e.next = now.Add(e.backoff)
+	e.status.State = ReconcileRetrying
+	e.status.NextAttempt = unixMillis(e.next)
+}
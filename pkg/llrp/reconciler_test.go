@@ -0,0 +1,235 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// reconcilerHelper returns a Reconciler using a device service
+// that fails the first failPuts PUT requests,
+// along with a count of requests for enableROSpec.
+func reconcilerHelper(t *testing.T, failPuts int32) (*Reconciler, *int32, func()) {
+	t.Helper()
+	return failingReconcilerHelper(t, &failPuts)
+}
+
+// failingReconcilerHelper is like reconcilerHelper,
+// but its device service fails PUT requests while *failPuts is positive,
+// decrementing it each time, so more failures can be added later.
+func failingReconcilerHelper(t *testing.T, failPuts *int32) (*Reconciler, *int32, func()) {
+	t.Helper()
+	var enables int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if atomic.AddInt32(failPuts, -1) >= 0 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if strings.HasSuffix(r.URL.Path, enableCmd) {
+				atomic.AddInt32(&enables, 1)
+			}
+			return
+		}
+
+		type Reading struct {
+			Name, Value string
+		}
+		type edgexResp struct {
+			Readings []Reading
+		}
+		data, err := json.Marshal(edgexResp{Readings: []Reading{{Name: capReadingName, Value: capabilities}}})
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}))
+
+	actualURL, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	ds := NewDSClient(actualURL, ts.Client(), getTestingLogger())
+
+	rc := NewReconciler(readerGroupHelper(), ds)
+	rc.minBackoff = 10 * time.Millisecond
+	rc.maxBackoff = 20 * time.Millisecond
+	return rc, &enables, ts.Close
+}
+
+func reconcileState(rc *Reconciler, name string) ReconcileStatus {
+	s, ok := rc.rg.health.Status(name)
+	if !ok || s.Reconcile == nil {
+		return ReconcileStatus{}
+	}
+	return *s.Reconcile
+}
+
+func TestReconciler_retry(t *testing.T) {
+	rc, enables, tsClose := reconcilerHelper(t, 2)
+	defer tsClose()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rc.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	rc.Want("r")
+	require.Eventually(t, func() bool {
+		return reconcileState(rc, "r").State == ReconcileDone
+	}, time.Second, 5*time.Millisecond)
+
+	assert.True(t, rc.rg.HasReader("r"))
+	assert.Equal(t, 3, reconcileState(rc, "r").Attempts)
+	assert.Zero(t, reconcileState(rc, "r").NextAttempt)
+	// the group isn't reading, so it shouldn't be started
+	assert.Zero(t, atomic.LoadInt32(enables))
+
+	rc.Forget("r")
+	assert.False(t, rc.rg.HasReader("r"))
+	s, _ := rc.rg.health.Status("r")
+	assert.Nil(t, s.Reconcile)
+}
+
+func TestReconciler_resume(t *testing.T) {
+	rc, enables, tsClose := reconcilerHelper(t, 0)
+	defer tsClose()
+	require.NoError(t, rc.rg.StartAll(rc.ds))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rc.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	rc.Want("r")
+	require.Eventually(t, func() bool {
+		return reconcileState(rc, "r").State == ReconcileDone
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(enables))
+
+	s, _ := rc.rg.health.Status("r")
+	assert.True(t, s.ROSpecEnabled)
+}
+
+func TestReconciler_backoff(t *testing.T) {
+	rc, _, tsClose := reconcilerHelper(t, 1<<30)
+	defer tsClose()
+	rc.minBackoff = time.Second
+	rc.maxBackoff = 3 * time.Second
+
+	rc.Want("r")
+	now := time.Now()
+	for _, expected := range []time.Duration{1, 2, 3, 3} {
+		due, _ := rc.due(now)
+		require.Contains(t, due, "r")
+		rc.attempt("r", due["r"])
+
+		e := rc.readers["r"]
+		assert.Equal(t, expected*time.Second, e.backoff)
+		assert.Equal(t, ReconcileRetrying, e.status.State)
+
+		due, wait := rc.due(now)
+		assert.Empty(t, due)
+		assert.Greater(t, int64(wait), int64(0))
+		now = e.next
+	}
+
+	// requesting it again resets its backoff
+	rc.Want("r")
+	due, _ := rc.due(time.Now())
+	assert.Contains(t, due, "r")
+	assert.Zero(t, rc.readers["r"].backoff)
+}
+
+func TestReconciler_setBehaviorRetry(t *testing.T) {
+	var failPuts int32
+	rc, _, tsClose := failingReconcilerHelper(t, &failPuts)
+	defer tsClose()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rc.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	rc.Want("r")
+	require.Eventually(t, func() bool {
+		return reconcileState(rc, "r").State == ReconcileDone
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, reconcileState(rc, "r").Attempts)
+
+	// failing to replace the ROSpec queues the Reader for a retry after its backoff
+	rc.mu.Lock()
+	rc.minBackoff = 200 * time.Millisecond
+	rc.mu.Unlock()
+	atomic.StoreInt32(&failPuts, 1)
+	b := rc.rg.Behavior()
+	b.Power.Max = 2500
+	assert.Error(t, rc.rg.SetBehavior(rc.ds, b))
+
+	status := reconcileState(rc, "r")
+	assert.Equal(t, ReconcileRetrying, status.State)
+	assert.NotZero(t, status.NextAttempt)
+
+	// which reapplies the group's Behavior
+	require.Eventually(t, func() bool {
+		return reconcileState(rc, "r").State == ReconcileDone
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, reconcileState(rc, "r").Attempts)
+	assert.True(t, rc.rg.HasReader("r"))
+	assert.Equal(t, b, rc.rg.Behavior())
+}
+
+func TestReconciler_Sync(t *testing.T) {
+	rc := NewReconciler(readerGroupHelper(), DSClient{})
+
+	device := func(name, host string) DeviceInfo {
+		d := DeviceInfo{Name: name, Protocols: map[string]map[string]string{"tcp": {"host": host, "port": "5084"}}}
+		d.Profile.Name = "Device.LLRP.Profile"
+		return d
+	}
+
+	res := rc.Sync([]DeviceInfo{device("b", "10.0.0.2"), device("a", "10.0.0.1")})
+	assert.Equal(t, SyncResult{Added: []string{"a", "b"}, Removed: []string{}, Changed: []string{}}, res)
+	assert.Equal(t, ReconcilePending, reconcileState(rc, "a").State)
+	assert.Equal(t, ReconcilePending, reconcileState(rc, "b").State)
+
+	// Readers requested outside of Sync are left alone.
+	rc.Want("c")
+
+	res = rc.Sync([]DeviceInfo{device("a", "10.0.0.1"), device("b", "10.0.0.3")})
+	assert.Equal(t, SyncResult{Added: []string{}, Removed: []string{}, Changed: []string{"b"}}, res)
+
+	res = rc.Sync([]DeviceInfo{device("b", "10.0.0.3")})
+	assert.Equal(t, SyncResult{Added: []string{}, Removed: []string{"a"}, Changed: []string{}}, res)
+	assert.NotContains(t, rc.readers, "a")
+	assert.Contains(t, rc.readers, "b")
+	assert.Contains(t, rc.readers, "c")
+}
@@ -0,0 +1,176 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+// ReportNormalizer handles the worst feature of LLRP: ambiguous nil parameters.
+//
+// Specifically, it fills in tag data parameters that weren't reported
+// because they match the last reported value of the same type.
+// This can only be done correctly if you know enough context,
+// so it assumes we're using a consistent set of reporting parameters,
+// and tag reports are processed in full, in order.
+// It also skips Uptimes and AccessSpecID parameters.
+//
+// LLRP allows Readers to use `nil` to mean both "not enabled" and "hasn't changed".
+// The Client "knows" which it is because they know if the value were enabled,
+// and they know the most recent value of each optional parameter ever received.
+//
+// You can't disable this behavior.
+// You can't even query a Reader to know if it's something the Reader supports.
+//
+// As a result, the Clients must track:
+// - the most-recently-received value of every optional parameter
+// - the reporting parameters of any ROSpec
+//   for which it might still receive tag reports;
+//   note that it's legal to delete an ROSpec before requesting its data
+// - the default reporting parameters at any point they were changed,
+//   if there was defined at that time an enabled ROSpec that used the defaults
+// - the ROSpecIDs and start/stop timestamps of any ROSpec
+//   for which it might still receive tag reports;
+//   since this is itself an optional parameter,
+//   there are several ways to configure an LLRP Reader
+//   such that it is impossible to disambiguate nil parameters.
+//
+// Here's a direct quote from the LLRP Spec explaining how it works:
+//
+//		This report parameter is generated per tag per accumulation scope[*].
+//		The only mandatory portion of this parameter is the EPCData parameter.
+//		If there was an access operation performed on the tag,
+//		the results of the OpSpecs are mandatory in the report.
+//		The other sub-parameters in this report are optional.
+//		LLRP provides three ways to make the tag reporting efficient:
+//
+//		(i) Allow parameters to be enabled or disabled via TagReportContentSelector in TagReportSpec.
+//		(ii) If an optional parameter is enabled, and is absent in the report,
+//		the Client SHALL assume that the value is identical
+//		to the last parameter of the same type received.
+//		For example, this allows the Readers to not send a parameter in the report
+//		whose value has not changed since the last time it was sent by the Reader.
+//
+// [*] This is just saying you get a TagReportData parameter
+//     for each EPC and unique combination of OpSpec result or matched IDs.
+//     Report accumulation also affects the reporting of
+//     timestamps, RSSI, the channel index, and number of observations.
+//
+// A ReportNormalizer holds a single Reader's most recently reported values,
+// so it must be given all of that Reader's TagReportData, in order.
+// It's not safe for concurrent use.
+type ReportNormalizer struct {
+	// report is the collection of information the Reader is expected to report.
+	report TagReportContentSelector
+	// lastData is the value of tag parameter the last time it was reported.
+	lastData TagReportData
+}
+
+// NewReportNormalizer returns a ReportNormalizer for a Reader
+// which reports the parameters enabled in the selector.
+func NewReportNormalizer(report TagReportContentSelector) *ReportNormalizer {
+	return &ReportNormalizer{
+		report: report,
+		lastData: TagReportData{
+			ROSpecID:                 new(ROSpecID),
+			SpecIndex:                new(SpecIndex),
+			InventoryParameterSpecID: new(InventoryParameterSpecID),
+			AntennaID:                new(AntennaID),
+			PeakRSSI:                 new(PeakRSSI),
+			ChannelIndex:             new(ChannelIndex),
+			FirstSeenUTC:             new(FirstSeenUTC),
+			LastSeenUTC:              new(LastSeenUTC),
+			TagSeenCount:             new(TagSeenCount),
+		},
+	}
+}
+
+// Normalize fills in the tag's ambiguous nil parameters
+// and records its other parameters' values for the next tag.
+// A nil ReportNormalizer does nothing,
+// for Readers which always send every parameter.
+func (n *ReportNormalizer) Normalize(tag *TagReportData) {
+	if n == nil {
+		return
+	}
+
+	if n.report.EnableROSpecID {
+		if tag.ROSpecID == nil {
+			tag.ROSpecID = new(ROSpecID)
+			*tag.ROSpecID = *n.lastData.ROSpecID
+		} else {
+			*n.lastData.ROSpecID = *tag.ROSpecID
+		}
+	}
+
+	if n.report.EnableSpecIndex {
+		if tag.SpecIndex == nil {
+			tag.SpecIndex = new(SpecIndex)
+			*tag.SpecIndex = *n.lastData.SpecIndex
+		} else {
+			*n.lastData.SpecIndex = *tag.SpecIndex
+		}
+	}
+
+	if n.report.EnableInventoryParamSpecID {
+		if tag.InventoryParameterSpecID == nil {
+			tag.InventoryParameterSpecID = new(InventoryParameterSpecID)
+			*tag.InventoryParameterSpecID = *n.lastData.InventoryParameterSpecID
+		} else {
+			*n.lastData.InventoryParameterSpecID = *tag.InventoryParameterSpecID
+		}
+	}
+
+	if n.report.EnableAntennaID {
+		if tag.AntennaID == nil {
+			tag.AntennaID = new(AntennaID)
+			*tag.AntennaID = *n.lastData.AntennaID
+		} else {
+			*n.lastData.AntennaID = *tag.AntennaID
+		}
+	}
+
+	if n.report.EnablePeakRSSI {
+		if tag.PeakRSSI == nil {
+			tag.PeakRSSI = new(PeakRSSI)
+			*tag.PeakRSSI = *n.lastData.PeakRSSI
+		} else {
+			*n.lastData.PeakRSSI = *tag.PeakRSSI
+		}
+	}
+
+	if n.report.EnableChannelIndex {
+		if tag.ChannelIndex == nil {
+			tag.ChannelIndex = new(ChannelIndex)
+			*tag.ChannelIndex = *n.lastData.ChannelIndex
+		} else {
+			*n.lastData.ChannelIndex = *tag.ChannelIndex
+		}
+	}
+
+	if n.report.EnableFirstSeenTimestamp {
+		if tag.FirstSeenUTC == nil {
+			tag.FirstSeenUTC = new(FirstSeenUTC)
+			*tag.FirstSeenUTC = *n.lastData.FirstSeenUTC
+		} else {
+			*n.lastData.FirstSeenUTC = *tag.FirstSeenUTC
+		}
+	}
+
+	if n.report.EnableLastSeenTimestamp {
+		if tag.LastSeenUTC == nil {
+			tag.LastSeenUTC = new(LastSeenUTC)
+			*tag.LastSeenUTC = *n.lastData.LastSeenUTC
+		} else {
+			*n.lastData.LastSeenUTC = *tag.LastSeenUTC
+		}
+	}
+
+	if n.report.EnableTagSeenCount {
+		if tag.TagSeenCount == nil {
+			tag.TagSeenCount = new(TagSeenCount)
+			*tag.TagSeenCount = *n.lastData.TagSeenCount
+		} else {
+			*n.lastData.TagSeenCount = *tag.TagSeenCount
+		}
+	}
+}
@@ -0,0 +1,52 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestReportNormalizer_Normalize(t *testing.T) {
+	n := NewReportNormalizer(TagReportContentSelector{
+		EnableAntennaID:         true,
+		EnablePeakRSSI:          true,
+		EnableLastSeenTimestamp: true,
+	})
+
+	ant, rssi, seen := AntennaID(2), PeakRSSI(-50), LastSeenUTC(1000)
+	channel := ChannelIndex(3)
+	first := TagReportData{AntennaID: &ant, PeakRSSI: &rssi, LastSeenUTC: &seen, ChannelIndex: &channel}
+	n.Normalize(&first)
+	assert.Equal(t, AntennaID(2), *first.AntennaID)
+
+	// omitted parameters are the same as the last ones reported
+	newRSSI := PeakRSSI(-60)
+	second := TagReportData{PeakRSSI: &newRSSI}
+	n.Normalize(&second)
+	require.NotNil(t, second.AntennaID)
+	require.NotNil(t, second.LastSeenUTC)
+	assert.Equal(t, AntennaID(2), *second.AntennaID)
+	assert.Equal(t, LastSeenUTC(1000), *second.LastSeenUTC)
+	assert.Equal(t, PeakRSSI(-60), *second.PeakRSSI)
+	// but parameters which aren't enabled are left alone
+	assert.Nil(t, second.ChannelIndex)
+
+	// filled values don't alias the normalizer's
+	*second.AntennaID = 4
+	third := TagReportData{}
+	n.Normalize(&third)
+	assert.Equal(t, AntennaID(2), *third.AntennaID)
+	assert.Equal(t, PeakRSSI(-60), *third.PeakRSSI)
+}
+
+func TestReportNormalizer_nil(t *testing.T) {
+	var n *ReportNormalizer
+	tag := TagReportData{}
+	n.Normalize(&tag)
+	assert.Equal(t, TagReportData{}, tag)
+}
@@ -5,7 +5,10 @@
 
 package llrp
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"math"
+)
 
 const hexChars = "0123456789abcdef"
 
@@ -46,6 +49,35 @@ func (rt *TagReportData) ExtractRSSI() (float64, bool) {
 	return 0, false
 }
 
+// ExtractPhaseAngle returns the RF phase angle from TagReportData, if present.
+//
+// Only Impinj Readers report it, as a Custom parameter,
+// and only if it's enabled via ImpinjOptions.ReportRFExtensions.
+// The reported value is in units of 2π/4096 radians;
+// this converts it to radians in the range [0, 2π).
+func (rt *TagReportData) ExtractPhaseAngle() (float64, bool) {
+	for _, c := range rt.Custom {
+		if c.Is(PENImpinj, ImpinjRFPhaseAngle) && len(c.Data) == 2 {
+			return float64(binary.BigEndian.Uint16(c.Data)&0xFFF) * 2 * math.Pi / 4096, true
+		}
+	}
+	return 0, false
+}
+
+// ExtractDopplerFrequency returns the RF Doppler frequency from TagReportData, if present.
+//
+// Only Impinj Readers report it, as a Custom parameter,
+// and only if it's enabled via ImpinjOptions.ReportRFExtensions.
+// The reported value is in units of 1/16 Hz; this converts it to Hz.
+func (rt *TagReportData) ExtractDopplerFrequency() (float64, bool) {
+	for _, c := range rt.Custom {
+		if c.Is(PENImpinj, ImpinjRFDopplerFrequency) && len(c.Data) == 2 {
+			return float64(int16(binary.BigEndian.Uint16(c.Data))) / 16.0, true
+		}
+	}
+	return 0, false
+}
+
 // ReadDataAsHex returns a hex string representation of a ReadOpSpecResult
 // if the TagReportData has one and its result type indicates success.
 func (rt *TagReportData) ReadDataAsHex() (data string, ok bool) {
@@ -8,6 +8,7 @@ package llrp
 import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"math"
 	"testing"
 )
 
@@ -110,6 +111,64 @@ func TestExtractRSSI(t *testing.T) {
 	}
 }
 
+func TestExtractPhaseAngle(t *testing.T) {
+	phase := func(data []byte) TagReportData {
+		return TagReportData{Custom: []Custom{
+			makeCustomRSSI(int16ToBytes(-4750)),
+			{VendorID: uint32(PENImpinj), Subtype: ImpinjRFPhaseAngle, Data: data},
+		}}
+	}
+
+	_, ok := (&TagReportData{}).ExtractPhaseAngle()
+	assert.False(t, ok)
+
+	td := phase(int16ToBytes(0))
+	p, ok := td.ExtractPhaseAngle()
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, p)
+
+	td = phase(int16ToBytes(1024))
+	p, ok = td.ExtractPhaseAngle()
+	assert.True(t, ok)
+	assert.InDelta(t, math.Pi/2, p, 1e-9)
+
+	td = phase(int16ToBytes(4095))
+	p, ok = td.ExtractPhaseAngle()
+	assert.True(t, ok)
+	assert.Less(t, p, 2*math.Pi)
+
+	td = phase([]byte{1})
+	_, ok = td.ExtractPhaseAngle()
+	assert.False(t, ok)
+}
+
+func TestExtractDopplerFrequency(t *testing.T) {
+	doppler := func(data []byte) TagReportData {
+		return TagReportData{Custom: []Custom{
+			{VendorID: uint32(PENImpinj), Subtype: ImpinjRFDopplerFrequency, Data: data},
+		}}
+	}
+
+	_, ok := (&TagReportData{}).ExtractDopplerFrequency()
+	assert.False(t, ok)
+
+	td := doppler(int16ToBytes(-40))
+	d, ok := td.ExtractDopplerFrequency()
+	assert.True(t, ok)
+	assert.Equal(t, -2.5, d)
+
+	td = doppler(int16ToBytes(32))
+	d, ok = td.ExtractDopplerFrequency()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, d)
+
+	// the wrong vendor
+	td = doppler(int16ToBytes(32))
+	td.Custom[0].VendorID = uint32(PENZebra)
+	_, ok = td.ExtractDopplerFrequency()
+	assert.False(t, ok)
+}
+
 func TestWordsToHex(t *testing.T) {
 	var tests = []struct {
 		name  string
@@ -31,6 +31,17 @@ const (
 	R700         = ImpinjModel(2001052)
 )
 
+// IsGateway returns true if the model is one of Impinj's gateway Readers,
+// which have integrated, beam-steered antenna arrays
+// and can report tags' locations or directions of travel.
+func (m ImpinjModel) IsGateway() bool {
+	switch m {
+	case XPortal, XArrayWM, XArrayEAP, XArray, XSpan:
+		return true
+	}
+	return false
+}
+
 // CustomParamSubtype is a base type for all custom param subtypes
 type CustomParamSubtype = uint32
 
@@ -40,8 +51,16 @@ type ImpinjParamSubtype = CustomParamSubtype
 const (
 	ImpinjEnablePeakRSSI           = ImpinjParamSubtype(53)
 	ImpinjPeakRSSI                 = ImpinjParamSubtype(57)
+	ImpinjEnableRFPhaseAngle       = ImpinjParamSubtype(52)
+	ImpinjRFPhaseAngle             = ImpinjParamSubtype(56)
+	ImpinjEnableRFDopplerFrequency = ImpinjParamSubtype(67)
+	ImpinjRFDopplerFrequency       = ImpinjParamSubtype(68)
+	ImpinjEnableOptimizedRead      = ImpinjParamSubtype(65)
 	ImpinjTagReportContentSelector = ImpinjParamSubtype(50)
 	ImpinjSearchMode               = ImpinjParamSubtype(23)
+	ImpinjExtendedTagInformation   = ImpinjParamSubtype(1542)
+	ImpinjLocationReportData       = ImpinjParamSubtype(1543)
+	ImpinjDirectionReportData      = ImpinjParamSubtype(1544)
 )
 
 // impinjSearchMode is like a really limited version of standard state-aware filtering
@@ -0,0 +1,87 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+// ZebraDevice embeds BasicDevice to provide some Zebra-specific Behavior implementations.
+//
+// Zebra's UHF Mode tables may include entries which differ only by ModeID,
+// as well as placeholder entries with a BDR or Tari of 0.
+// Since modes are chosen by their parameters, the placeholders are dropped,
+// and only the first of any set of identical entries is kept,
+// so the chosen ModeID is stable across firmware versions.
+//
+// Its configuration uses only standard LLRP parameters;
+// Zebra's RSSI and RF Phase extensions aren't publicly documented,
+// so they're not enabled.
+type ZebraDevice struct {
+	BasicDevice
+}
+
+func NewZebraDevice(c *GetReaderCapabilitiesResponse) (*ZebraDevice, error) {
+	bd, err := NewBasicDevice(c)
+	if err != nil {
+		return nil, err
+	}
+
+	bd.modes = fixZebraModes(bd.modes)
+	if len(bd.modes) == 0 {
+		return nil, errMissingCapInfo("usable RF modes",
+			"RegulatoryCapabilities", "UHFBandCapabilities",
+			"C1G2RFModes", "UHFC1G2RFModeTableEntries")
+	}
+	return &ZebraDevice{BasicDevice: *bd}, nil
+}
+
+// fixZebraModes returns a copy of a Zebra Reader's mode table
+// without its placeholder and duplicate entries.
+func fixZebraModes(modes []UHFC1G2RFModeTableEntry) []UHFC1G2RFModeTableEntry {
+	fixed := make([]UHFC1G2RFModeTableEntry, 0, len(modes))
+	seen := make(map[UHFC1G2RFModeTableEntry]bool, len(modes))
+	for _, m := range modes {
+		if m.BackscatterDataRate == 0 || m.MaxTariTime == 0 {
+			continue
+		}
+
+		key := m
+		key.ModeID = 0
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		fixed = append(fixed, m)
+	}
+	return fixed
+}
+
+// NewROSpec returns a new llrp.ROSpec to achieve the Behavior within the Environment.
+//
+// Unlike Impinj's, Zebra's UHF Modes usually permit a range of Tari values.
+// The BasicDevice always uses the minimum, which maximizes the forward link rate,
+// but when the environment calls for a Dense Interrogator mode,
+// this uses the maximum instead, which narrows the forward link's occupied bandwidth
+// and so reduces interference with neighboring Readers.
+func (d *ZebraDevice) NewROSpec(b Behavior, e Environment) (*ROSpec, error) {
+	spec, err := d.BasicDevice.NewROSpec(b, e)
+	if err != nil {
+		return nil, err
+	}
+
+	_, best := d.findBestMode(e.NumNearbyReaders)
+	if best.SpectralMask != SpectralMaskDenseInterrogator || best.MaxTariTime <= best.MinTariTime {
+		return spec, nil
+	}
+
+	for _, ai := range spec.AISpecs {
+		for _, ips := range ai.InventoryParameterSpecs {
+			for _, ac := range ips.AntennaConfigurations {
+				if ac.C1G2InventoryCommand != nil && ac.C1G2InventoryCommand.RFControl != nil {
+					ac.C1G2InventoryCommand.RFControl.Tari = uint16(best.MaxTariTime)
+				}
+			}
+		}
+	}
+	return spec, nil
+}
@@ -0,0 +1,528 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package llrp
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// newCapsWithModes returns the capabilities in capJSON,
+// with the mode table adjusted by updateModes, if it's not nil.
+func newCapsWithModes(t *testing.T, capJSON string,
+	updateModes func([]UHFC1G2RFModeTableEntry) []UHFC1G2RFModeTableEntry) *GetReaderCapabilitiesResponse {
+	t.Helper()
+	devCap := &GetReaderCapabilitiesResponse{}
+	require.NoError(t, json.Unmarshal([]byte(capJSON), devCap))
+	if updateModes != nil {
+		rfModes := &devCap.RegulatoryCapabilities.UHFBandCapabilities.C1G2RFModes
+		rfModes.UHFC1G2RFModeTableEntries = updateModes(rfModes.UHFC1G2RFModeTableEntries)
+	}
+	return devCap
+}
+
+func TestNewZebraDevice(t *testing.T) {
+	caps := newCapsWithModes(t, PENZebraCap, func(modes []UHFC1G2RFModeTableEntry) []UHFC1G2RFModeTableEntry {
+		placeholder := modes[0]
+		placeholder.ModeID = 10
+		placeholder.BackscatterDataRate = 0
+		duplicate := modes[2]
+		duplicate.ModeID = 11
+		return append(modes, placeholder, duplicate)
+	})
+
+	d, err := NewZebraDevice(caps)
+	require.NoError(t, err)
+	for _, m := range d.modes {
+		assert.Less(t, m.ModeID, uint32(10))
+	}
+
+	caps = newCapsWithModes(t, PENZebraCap, func([]UHFC1G2RFModeTableEntry) []UHFC1G2RFModeTableEntry {
+		return []UHFC1G2RFModeTableEntry{{ModeID: 23}}
+	})
+	_, err = NewZebraDevice(caps)
+	assert.ErrorIs(t, err, ErrMissingCapInfo)
+}
+
+func TestZebraDevice_NewROSpec(t *testing.T) {
+	// give the Dense Interrogator mode a range of Tari values
+	caps := newCapsWithModes(t, PENZebraCap, func(modes []UHFC1G2RFModeTableEntry) []UHFC1G2RFModeTableEntry {
+		modes[2].MinTariTime = 6250
+		modes[2].MaxTariTime = 25000
+		return modes
+	})
+	d, err := NewZebraDevice(caps)
+	require.NoError(t, err)
+
+	b := Behavior{ScanType: ScanNormal, Power: PowerTarget{Max: 3000}}
+	spec, err := d.NewROSpec(b, Environment{NumNearbyReaders: 50})
+	require.NoError(t, err)
+	testROSpecProperties(t, spec)
+	for _, ai := range spec.AISpecs {
+		rfc := ai.InventoryParameterSpecs[0].AntennaConfigurations[0].C1G2InventoryCommand.RFControl
+		assert.Equal(t, uint16(2), rfc.RFModeID)
+		assert.Equal(t, uint16(25000), rfc.Tari)
+	}
+
+	// otherwise, it's the same as the BasicDevice's
+	spec, err = d.NewROSpec(b, Environment{})
+	require.NoError(t, err)
+	basic, err := d.BasicDevice.NewROSpec(b, Environment{})
+	require.NoError(t, err)
+	assert.Equal(t, basic, spec)
+}
+
+func TestZebraDevice_FX9600(t *testing.T) {
+	d, err := NewZebraDevice(newCapsWithModes(t, zebraFX9600Cap, nil))
+	require.NoError(t, err)
+	assert.True(t, d.stateAware)
+	assert.Equal(t, uint16(50), d.nFreqs)
+
+	// the placeholder and duplicate modes should be dropped
+	ids := make([]uint32, len(d.modes))
+	for i, m := range d.modes {
+		ids[i] = m.ModeID
+	}
+	assert.Equal(t, []uint32{0, 1, 2, 3}, ids)
+
+	tari := func(spec *ROSpec) uint16 {
+		return spec.AISpecs[0].InventoryParameterSpecs[0].AntennaConfigurations[0].
+			C1G2InventoryCommand.RFControl.Tari
+	}
+
+	for _, st := range []ScanType{ScanFast, ScanNormal, ScanDeep} {
+		b := Behavior{ScanType: st, Power: PowerTarget{Max: 3000}}
+
+		spec, err := d.NewROSpec(b, Environment{})
+		require.NoError(t, err)
+		testROSpecProperties(t, spec)
+		assert.Equal(t, uint16(6250), tari(spec))
+
+		// in a dense environment, it uses the longest Tari the mode allows
+		spec, err = d.NewROSpec(b, Environment{NumNearbyReaders: 50})
+		require.NoError(t, err)
+		testROSpecProperties(t, spec)
+		for _, ai := range spec.AISpecs {
+			ac := ai.InventoryParameterSpecs[0].AntennaConfigurations[0]
+			assert.Equal(t, uint16(2), ac.C1G2InventoryCommand.RFControl.RFModeID)
+			assert.Equal(t, uint16(25000), ac.C1G2InventoryCommand.RFControl.Tari)
+		}
+	}
+
+	// it has no vendor extensions to enable
+	assert.Equal(t, d.BasicDevice.NewConfig(), d.NewConfig())
+}
+
+// zebraFX9600Cap are capabilities modeled on a Zebra FX9600's
+// (8 antennas, 50 channels, state aware singulation),
+// including the placeholder and duplicate modes fixZebraModes drops.
+// They're built from the Reader's published specifications
+// rather than captured from one, so the mode table is abbreviated.
+const zebraFX9600Cap = `{
+	"LLRPStatus": {
+		"Status": 0,
+		"ErrorDescription": "",
+		"FieldError": null,
+		"ParameterError": null
+	},
+	"GeneralDeviceCapabilities": {
+		"MaxSupportedAntennas": 8,
+		"CanSetAntennaProperties": false,
+		"HasUTCClock": true,
+		"DeviceManufacturer": 10642,
+		"Model": 9600,
+		"FirmwareVersion": "3.10.30",
+		"ReceiveSensitivities": [
+			{
+				"Index": 1,
+				"ReceiveSensitivity": 0
+			}
+		],
+		"PerAntennaReceiveSensitivityRanges": null,
+		"GPIOCapabilities": {
+			"NumGPIs": 4,
+			"NumGPOs": 4
+		},
+		"PerAntennaAirProtocols": [
+			{
+				"AntennaID": 1,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 2,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 3,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 4,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 5,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 6,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 7,
+				"AirProtocolIDs": "AQ=="
+			},
+			{
+				"AntennaID": 8,
+				"AirProtocolIDs": "AQ=="
+			}
+		],
+		"MaximumReceiveSensitivity": null
+	},
+	"LLRPCapabilities": {
+		"CanDoRFSurvey": false,
+		"CanReportBufferFillWarning": true,
+		"SupportsClientRequestOpSpec": false,
+		"CanDoTagInventoryStateAwareSingulation": true,
+		"SupportsEventsAndReportHolding": true,
+		"MaxPriorityLevelSupported": 0,
+		"ClientRequestedOpSpecTimeout": 0,
+		"MaxROSpecs": 1,
+		"MaxSpecsPerROSpec": 32,
+		"MaxInventoryParameterSpecsPerAISpec": 1,
+		"MaxAccessSpecs": 1,
+		"MaxOpSpecsPerAccessSpec": 8
+	},
+	"RegulatoryCapabilities": {
+		"CountryCode": 840,
+		"CommunicationsStandard": 1,
+		"UHFBandCapabilities": {
+			"TransmitPowerLevels": [
+				{
+					"Index": 1,
+					"TransmitPowerValue": 1000
+				},
+				{
+					"Index": 2,
+					"TransmitPowerValue": 1050
+				},
+				{
+					"Index": 3,
+					"TransmitPowerValue": 1100
+				},
+				{
+					"Index": 4,
+					"TransmitPowerValue": 1150
+				},
+				{
+					"Index": 5,
+					"TransmitPowerValue": 1200
+				},
+				{
+					"Index": 6,
+					"TransmitPowerValue": 1250
+				},
+				{
+					"Index": 7,
+					"TransmitPowerValue": 1300
+				},
+				{
+					"Index": 8,
+					"TransmitPowerValue": 1350
+				},
+				{
+					"Index": 9,
+					"TransmitPowerValue": 1400
+				},
+				{
+					"Index": 10,
+					"TransmitPowerValue": 1450
+				},
+				{
+					"Index": 11,
+					"TransmitPowerValue": 1500
+				},
+				{
+					"Index": 12,
+					"TransmitPowerValue": 1550
+				},
+				{
+					"Index": 13,
+					"TransmitPowerValue": 1600
+				},
+				{
+					"Index": 14,
+					"TransmitPowerValue": 1650
+				},
+				{
+					"Index": 15,
+					"TransmitPowerValue": 1700
+				},
+				{
+					"Index": 16,
+					"TransmitPowerValue": 1750
+				},
+				{
+					"Index": 17,
+					"TransmitPowerValue": 1800
+				},
+				{
+					"Index": 18,
+					"TransmitPowerValue": 1850
+				},
+				{
+					"Index": 19,
+					"TransmitPowerValue": 1900
+				},
+				{
+					"Index": 20,
+					"TransmitPowerValue": 1950
+				},
+				{
+					"Index": 21,
+					"TransmitPowerValue": 2000
+				},
+				{
+					"Index": 22,
+					"TransmitPowerValue": 2050
+				},
+				{
+					"Index": 23,
+					"TransmitPowerValue": 2100
+				},
+				{
+					"Index": 24,
+					"TransmitPowerValue": 2150
+				},
+				{
+					"Index": 25,
+					"TransmitPowerValue": 2200
+				},
+				{
+					"Index": 26,
+					"TransmitPowerValue": 2250
+				},
+				{
+					"Index": 27,
+					"TransmitPowerValue": 2300
+				},
+				{
+					"Index": 28,
+					"TransmitPowerValue": 2350
+				},
+				{
+					"Index": 29,
+					"TransmitPowerValue": 2400
+				},
+				{
+					"Index": 30,
+					"TransmitPowerValue": 2450
+				},
+				{
+					"Index": 31,
+					"TransmitPowerValue": 2500
+				},
+				{
+					"Index": 32,
+					"TransmitPowerValue": 2550
+				},
+				{
+					"Index": 33,
+					"TransmitPowerValue": 2600
+				},
+				{
+					"Index": 34,
+					"TransmitPowerValue": 2650
+				},
+				{
+					"Index": 35,
+					"TransmitPowerValue": 2700
+				},
+				{
+					"Index": 36,
+					"TransmitPowerValue": 2750
+				},
+				{
+					"Index": 37,
+					"TransmitPowerValue": 2800
+				},
+				{
+					"Index": 38,
+					"TransmitPowerValue": 2850
+				},
+				{
+					"Index": 39,
+					"TransmitPowerValue": 2900
+				},
+				{
+					"Index": 40,
+					"TransmitPowerValue": 2950
+				},
+				{
+					"Index": 41,
+					"TransmitPowerValue": 3000
+				}
+			],
+			"FrequencyInformation": {
+				"Hopping": true,
+				"FrequencyHopTables": [
+					{
+						"HopTableID": 1,
+						"Frequencies": [
+							902750,
+							906250,
+							909750,
+							913250,
+							916750,
+							920250,
+							923750,
+							927250,
+							903250,
+							906750,
+							910250,
+							913750,
+							917250,
+							920750,
+							924250,
+							903750,
+							907250,
+							910750,
+							914250,
+							917750,
+							921250,
+							924750,
+							904250,
+							907750,
+							911250,
+							914750,
+							918250,
+							921750,
+							925250,
+							904750,
+							908250,
+							911750,
+							915250,
+							918750,
+							922250,
+							925750,
+							905250,
+							908750,
+							912250,
+							915750,
+							919250,
+							922750,
+							926250,
+							905750,
+							909250,
+							912750,
+							916250,
+							919750,
+							923250,
+							926750
+						]
+					}
+				],
+				"FixedFrequencyTable": null
+			},
+			"C1G2RFModes": {
+				"UHFC1G2RFModeTableEntries": [
+					{
+						"ModeID": 0,
+						"DivideRatio": 0,
+						"IsEPCHagConformant": true,
+						"Modulation": 0,
+						"ForwardLinkModulation": 0,
+						"SpectralMask": 2,
+						"BackscatterDataRate": 640000,
+						"PIERatio": 1500,
+						"MinTariTime": 6250,
+						"MaxTariTime": 6250,
+						"StepTariTime": 0
+					},
+					{
+						"ModeID": 1,
+						"DivideRatio": 0,
+						"IsEPCHagConformant": true,
+						"Modulation": 1,
+						"ForwardLinkModulation": 2,
+						"SpectralMask": 2,
+						"BackscatterDataRate": 320000,
+						"PIERatio": 1500,
+						"MinTariTime": 6250,
+						"MaxTariTime": 6250,
+						"StepTariTime": 0
+					},
+					{
+						"ModeID": 2,
+						"DivideRatio": 0,
+						"IsEPCHagConformant": true,
+						"Modulation": 2,
+						"ForwardLinkModulation": 2,
+						"SpectralMask": 3,
+						"BackscatterDataRate": 160000,
+						"PIERatio": 2000,
+						"MinTariTime": 12500,
+						"MaxTariTime": 25000,
+						"StepTariTime": 6250
+					},
+					{
+						"ModeID": 3,
+						"DivideRatio": 0,
+						"IsEPCHagConformant": true,
+						"Modulation": 3,
+						"ForwardLinkModulation": 2,
+						"SpectralMask": 3,
+						"BackscatterDataRate": 80000,
+						"PIERatio": 2000,
+						"MinTariTime": 12500,
+						"MaxTariTime": 25000,
+						"StepTariTime": 6250
+					},
+					{
+						"ModeID": 4,
+						"DivideRatio": 0,
+						"IsEPCHagConformant": true,
+						"Modulation": 2,
+						"ForwardLinkModulation": 2,
+						"SpectralMask": 3,
+						"BackscatterDataRate": 160000,
+						"PIERatio": 2000,
+						"MinTariTime": 12500,
+						"MaxTariTime": 25000,
+						"StepTariTime": 6250
+					},
+					{
+						"ModeID": 23,
+						"DivideRatio": 0,
+						"IsEPCHagConformant": true,
+						"Modulation": 0,
+						"ForwardLinkModulation": 0,
+						"SpectralMask": 0,
+						"BackscatterDataRate": 0,
+						"PIERatio": 0,
+						"MinTariTime": 0,
+						"MaxTariTime": 0,
+						"StepTariTime": 0
+					}
+				]
+			},
+			"RFSurveyFrequencyCapabilities": null
+		},
+		"Custom": null
+	},
+	"C1G2LLRPCapabilities": {
+		"SupportsBlockErase": false,
+		"SupportsBlockWrite": true,
+		"SupportsBlockPermalock": false,
+		"SupportsTagRecommissioning": false,
+		"SupportsUMIMethod2": false,
+		"SupportsXPC": false,
+		"MaxSelectFiltersPerQuery": 32
+	},
+	"Custom": null
+}`
@@ -0,0 +1,115 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sink
+
+import (
+	"context"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"encoding/json"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// MessagePublisher publishes messages to a message bus topic.
+// The EdgeX messaging.MessageClient satisfies it.
+type MessagePublisher interface {
+	Publish(message types.MessageEnvelope, topic string) error
+}
+
+// MessageBusConfig configures a MessageBus sink.
+type MessageBusConfig struct {
+	// Topic is the base topic, such as the service's [Binding] PublishTopic.
+	// Events are published to "{Topic}/{eventType}/{alias}",
+	// where alias is the event's location alias, or its Reader's name for Reader events,
+	// or "unknown" if it has neither.
+	Topic string
+	// DeviceName is the Device of the EdgeX Events.
+	DeviceName string
+	// ReadingPrefix starts the name of each Reading, which ends with the inventory event's type.
+	ReadingPrefix string
+}
+
+// MessageBus publishes inventory events to an EdgeX message bus as EdgeX Events,
+// each of which has the Readings for a single topic,
+// so subscribers can filter events by type and location without reading them.
+type MessageBus struct {
+	lc     logger.LoggingClient
+	pub    MessagePublisher
+	config MessageBusConfig
+}
+
+// NewMessageBus returns a new MessageBus sink which publishes with pub.
+func NewMessageBus(lc logger.LoggingClient, pub MessagePublisher, cfg MessageBusConfig) (*MessageBus, error) {
+	if cfg.Topic == "" {
+		return nil, errors.New("missing message bus topic")
+	}
+	return &MessageBus{lc: lc, pub: pub, config: cfg}, nil
+}
+
+// Send publishes the events, grouped by topic in the order of each topic's first event.
+// It's an inventory.EventHandler, except that it returns an error.
+func (mb *MessageBus) Send(_ context.Context, events []inventory.Event) error {
+	now := time.Now().UnixNano()
+
+	var topics []string
+	byTopic := map[string]*models.Event{}
+	var errs []error
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "error marshalling event"))
+			continue
+		}
+
+		topic := mb.topic(event)
+		edgeXEvent, ok := byTopic[topic]
+		if !ok {
+			edgeXEvent = &models.Event{Device: mb.config.DeviceName, Origin: now}
+			byTopic[topic] = edgeXEvent
+			topics = append(topics, topic)
+		}
+		edgeXEvent.Readings = append(edgeXEvent.Readings, models.Reading{
+			Value:  string(payload),
+			Origin: now,
+			Device: mb.config.DeviceName,
+			Name:   mb.config.ReadingPrefix + string(event.OfType()),
+		})
+	}
+
+	for _, topic := range topics {
+		payload, err := json.Marshal(byTopic[topic])
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "error marshalling EdgeX event"))
+			continue
+		}
+
+		mb.lc.Debug("Publishing inventory events to the message bus.",
+			"topic", topic, "events", len(byTopic[topic].Readings))
+		if err := mb.pub.Publish(types.MessageEnvelope{
+			CorrelationID: newID(),
+			Payload:       payload,
+			ContentType:   clients.ContentTypeJSON,
+		}, topic); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to publish events to %s", topic))
+		}
+	}
+
+	if errs != nil {
+		return llrp.MultiErr(errs)
+	}
+	return nil
+}
+
+// topic returns the topic to which the event is published.
+func (mb *MessageBus) topic(event inventory.Event) string {
+	_, alias, _ := eventFields(event)
+	return mb.config.Topic + "/" + topicLevel(string(event.OfType())) + "/" + topicLevel(alias)
+}
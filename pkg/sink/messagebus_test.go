@@ -0,0 +1,92 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sink
+
+import (
+	"context"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"encoding/json"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type published struct {
+	topic    string
+	envelope types.MessageEnvelope
+}
+
+// recordingPublisher records the messages published to it,
+// failing those published to failTopic.
+type recordingPublisher struct {
+	messages  []published
+	failTopic string
+}
+
+func (p *recordingPublisher) Publish(message types.MessageEnvelope, topic string) error {
+	if topic == p.failTopic {
+		return errors.New("publish failed")
+	}
+	p.messages = append(p.messages, published{topic: topic, envelope: message})
+	return nil
+}
+
+func TestMessageBus(t *testing.T) {
+	pub := &recordingPublisher{}
+	mb, err := NewMessageBus(getTestingLogger(), pub, MessageBusConfig{
+		Topic:         "events",
+		DeviceName:    "rfid-llrp-inventory",
+		ReadingPrefix: "InventoryEvent",
+	})
+	require.NoError(t, err)
+
+	arrived1 := inventory.ArrivedEvent{BaseEvent: inventory.BaseEvent{EPC: "01"}, Location: "Freezer"}
+	offline := inventory.ReaderOfflineEvent{DeviceName: "Reader/1"}
+	arrived2 := inventory.ArrivedEvent{BaseEvent: inventory.BaseEvent{EPC: "02"}, Location: "Freezer"}
+	position := inventory.PositionUpdatedEvent{BaseEvent: inventory.BaseEvent{EPC: "03"}}
+	require.NoError(t, mb.Send(context.Background(), []inventory.Event{arrived1, offline, arrived2, position}))
+
+	// events are grouped by topic, in the order of each topic's first event
+	require.Len(t, pub.messages, 3)
+	for i, expected := range []struct {
+		topic  string
+		events []inventory.Event
+	}{
+		{"events/Arrived/Freezer", []inventory.Event{arrived1, arrived2}},
+		{"events/ReaderOffline/Reader_1", []inventory.Event{offline}},
+		{"events/PositionUpdated/unknown", []inventory.Event{position}},
+	} {
+		msg := pub.messages[i]
+		assert.Equal(t, expected.topic, msg.topic)
+		assert.Equal(t, "application/json", msg.envelope.ContentType)
+		assert.NotEmpty(t, msg.envelope.CorrelationID)
+
+		var edgeXEvent models.Event
+		require.NoError(t, json.Unmarshal(msg.envelope.Payload, &edgeXEvent))
+		assert.Equal(t, "rfid-llrp-inventory", edgeXEvent.Device)
+		require.Len(t, edgeXEvent.Readings, len(expected.events))
+		for j, e := range expected.events {
+			reading := edgeXEvent.Readings[j]
+			assert.Equal(t, "InventoryEvent"+string(e.OfType()), reading.Name)
+			payload, err := json.Marshal(e)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(payload), reading.Value)
+		}
+	}
+
+	// a failed topic doesn't prevent the others from being published
+	pub.failTopic = "events/Arrived/Freezer"
+	pub.messages = nil
+	assert.Error(t, mb.Send(context.Background(), []inventory.Event{arrived1, offline}))
+	require.Len(t, pub.messages, 1)
+	assert.Equal(t, "events/ReaderOffline/Reader_1", pub.messages[0].topic)
+
+	_, err = NewMessageBus(getTestingLogger(), pub, MessageBusConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,468 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/llrp"
+	"encoding/json"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMQTTTopic publishes each event type to its own topic per site and location.
+	DefaultMQTTTopic = "rfid/{site}/{eventType}/{alias}"
+
+	mqttConnectTimeout = 30 * time.Second
+	mqttPublishTimeout = 30 * time.Second
+	mqttFlushTimeout   = 5 * time.Second
+	mqttDisconnectMs   = 250
+	// mqttClearInterval is how often Departed tags' states are checked for clearing.
+	mqttClearInterval = time.Minute
+
+	// unknownTopicLevel replaces topic variables which an event doesn't have.
+	unknownTopicLevel = "unknown"
+)
+
+// MQTTConfig configures an MQTT sink.
+type MQTTConfig struct {
+	// BrokerURL is the broker's address, such as tcp://localhost:1883 or ssl://localhost:8883.
+	BrokerURL string
+	ClientID  string
+	Username  string
+	Password  string
+
+	// Topic is the template for the topic to which each event is published.
+	// It may contain the following variables, each of which is replaced by a single topic level:
+	//   {site}      - the Site
+	//   {eventType} - the event's type, e.g., Arrived
+	//   {alias}     - the event's location alias, or its Reader's name for Reader events
+	//   {epc}       - the event's tag's EPC
+	//   {device}    - the event's Reader's name
+	// Variables an event doesn't have are replaced by "unknown".
+	Topic string
+	// TagStateTopic, if set, is the template for a topic to which a retained message
+	// with each tag's latest state is published when it Arrives, Moves, or Departs.
+	// It must use {epc} and may use {site}, but not the other variables,
+	// so that each tag's state stays on a single topic.
+	TagStateTopic string
+	// TagStateTTL, if set, is how long after a tag Departs its retained state is cleared.
+	// The inventory forgets Departed tags after its AgeOutHours, so it's normally the same.
+	TagStateTTL time.Duration
+	Site        string
+	QoS         byte
+
+	// CAFile, CertFile, and KeyFile are PEM files with the CA certificates
+	// with which to verify the broker, and the client's certificate and key.
+	// They're only needed when using TLS, and then only if the defaults won't do.
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// TagState is the payload of a retained tag state message.
+type TagState struct {
+	EPC       string             `json:"epc"`
+	TID       string             `json:"tid"`
+	State     inventory.TagState `json:"state"`
+	Location  string             `json:"location"`
+	Timestamp int64              `json:"timestamp"`
+}
+
+// MQTT publishes inventory events to an MQTT broker,
+// using the same JSON payloads as the events sent to EdgeX.
+type MQTT struct {
+	lc     logger.LoggingClient
+	client mqtt.Client
+	config MQTTConfig
+
+	// sendMu serializes publishing events and clearing tag states,
+	// so a tag's state isn't cleared just as it Arrives again.
+	sendMu sync.Mutex
+	// departed holds when each Departed tag departed (Unix Epoch milliseconds),
+	// if TagStateTTL is set, so its state can be cleared once it expires.
+	departed   map[string]int64
+	departedMu sync.Mutex
+	// stop ends the clearLoop, which closes stopped when it returns.
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+var topicVariable = regexp.MustCompile(`{[^}]*}`)
+
+// validateTopic returns an error if the template has unknown variables
+// or wildcards, which can't be published to.
+func validateTopic(template string) error {
+	if strings.ContainsAny(topicVariable.ReplaceAllString(template, ""), "+#") {
+		return errors.Errorf("topic template %q contains a wildcard", template)
+	}
+	for _, v := range topicVariable.FindAllString(template, -1) {
+		switch v {
+		case "{site}", "{eventType}", "{alias}", "{epc}", "{device}":
+		default:
+			return errors.Errorf("topic template %q has unknown variable %s", template, v)
+		}
+	}
+	return nil
+}
+
+// validateTagStateTopic returns an error if the template isn't a valid topic,
+// doesn't use {epc}, or uses variables which change between a tag's events.
+func validateTagStateTopic(template string) error {
+	if err := validateTopic(template); err != nil {
+		return err
+	}
+	if !strings.Contains(template, "{epc}") {
+		return errors.Errorf("tag state topic template %q doesn't contain {epc}", template)
+	}
+	for _, v := range topicVariable.FindAllString(template, -1) {
+		if v != "{site}" && v != "{epc}" {
+			return errors.Errorf("tag state topic template %q can't use %s", template, v)
+		}
+	}
+	return nil
+}
+
+// NewMQTT returns a new MQTT sink, which connects to the broker when it first sends events
+// and reconnects if it loses the connection.
+// It returns an error if the configuration is invalid.
+func NewMQTT(lc logger.LoggingClient, cfg MQTTConfig) (*MQTT, error) {
+	if cfg.BrokerURL == "" {
+		return nil, errors.New("missing MQTT broker URL")
+	}
+	if cfg.Topic == "" {
+		return nil, errors.New("missing MQTT topic")
+	}
+	if err := validateTopic(cfg.Topic); err != nil {
+		return nil, err
+	}
+	if cfg.TagStateTopic != "" {
+		if err := validateTagStateTopic(cfg.TagStateTopic); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.QoS > 2 {
+		return nil, errors.Errorf("invalid MQTT QoS %d", cfg.QoS)
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.BrokerURL).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetConnectTimeout(mqttConnectTimeout).
+		SetAutoReconnect(true).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			lc.Warn("Lost connection to MQTT broker.", "broker", cfg.BrokerURL, "error", err.Error())
+		})
+
+	if cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != "" || cfg.InsecureSkipVerify {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	m := &MQTT{lc: lc, config: cfg}
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		lc.Info("Connected to MQTT broker.", "broker", cfg.BrokerURL)
+		m.watchTagStates(client)
+	})
+	m.client = mqtt.NewClient(opts)
+
+	if cfg.TagStateTopic != "" && cfg.TagStateTTL > 0 {
+		m.departed = map[string]int64{}
+		m.stop = make(chan struct{})
+		m.stopped = make(chan struct{})
+		go m.clearLoop()
+	}
+	return m, nil
+}
+
+// newTLSConfig returns the TLS configuration for the client.
+func newTLSConfig(cfg MQTTConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read MQTT CA file")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates in MQTT CA file %q", cfg.CAFile)
+		}
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load MQTT client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Send publishes each event, along with its tag's state if TagStateTopic is set.
+// It's an inventory.EventHandler, except that it returns an error.
+//
+// Events queued when the service shuts down are sent with a cancelled ctx;
+// rather than dropping them, Send gives them mqttFlushTimeout to be published.
+func (m *MQTT) Send(ctx context.Context, events []inventory.Event) error {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), mqttFlushTimeout)
+		defer cancel()
+	}
+
+	if !m.client.IsConnected() {
+		if err := wait(ctx, m.client.Connect(), mqttConnectTimeout); err != nil {
+			return errors.Wrapf(err, "failed to connect to MQTT broker %s", m.config.BrokerURL)
+		}
+	}
+
+	var errs []error
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "error marshalling event"))
+			continue
+		}
+
+		topic := m.topic(m.config.Topic, event)
+		m.lc.Debug("Publishing inventory event to MQTT.", "topic", topic)
+		if err := wait(ctx, m.client.Publish(topic, m.config.QoS, false, payload), mqttPublishTimeout); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to publish event to %s", topic))
+		}
+
+		if m.config.TagStateTopic == "" {
+			continue
+		}
+		state, ok := tagState(event)
+		if !ok {
+			continue
+		}
+		if payload, err = json.Marshal(state); err != nil {
+			errs = append(errs, errors.Wrap(err, "error marshalling tag state"))
+			continue
+		}
+		topic = m.topic(m.config.TagStateTopic, event)
+		if err := wait(ctx, m.client.Publish(topic, m.config.QoS, true, payload), mqttPublishTimeout); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to publish tag state to %s", topic))
+			continue
+		}
+		m.trackTagState(state)
+	}
+
+	if errs != nil {
+		return llrp.MultiErr(errs)
+	}
+	return nil
+}
+
+// Close stops clearing tag states and disconnects from the broker.
+func (m *MQTT) Close() {
+	if m.stop != nil {
+		close(m.stop)
+		<-m.stopped
+	}
+	if m.client.IsConnected() {
+		m.client.Disconnect(mqttDisconnectMs)
+	}
+}
+
+// topic fills in the template's variables for the event.
+func (m *MQTT) topic(template string, event inventory.Event) string {
+	epc, alias, device := eventFields(event)
+	return topicVariable.ReplaceAllStringFunc(template, func(v string) string {
+		switch v {
+		case "{site}":
+			return topicLevel(m.config.Site)
+		case "{eventType}":
+			return topicLevel(string(event.OfType()))
+		case "{alias}":
+			return topicLevel(alias)
+		case "{epc}":
+			return topicLevel(epc)
+		case "{device}":
+			return topicLevel(device)
+		}
+		return v
+	})
+}
+
+// topicLevel makes a value safe to use as a single topic level.
+func topicLevel(value string) string {
+	if value == "" {
+		return unknownTopicLevel
+	}
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(value)
+}
+
+// tagState returns the tag's state after the event, if it's one which changes it.
+func tagState(event inventory.Event) (TagState, bool) {
+	switch e := event.(type) {
+	case inventory.ArrivedEvent:
+		return TagState{EPC: e.EPC, TID: e.TID, State: inventory.Present,
+			Location: e.Location, Timestamp: e.Timestamp}, true
+	case inventory.MovedEvent:
+		return TagState{EPC: e.EPC, TID: e.TID, State: inventory.Present,
+			Location: e.NewLocation, Timestamp: e.Timestamp}, true
+	case inventory.DepartedEvent:
+		return TagState{EPC: e.EPC, TID: e.TID, State: inventory.Departed,
+			Location: e.LastKnownLocation, Timestamp: e.Timestamp}, true
+	}
+	return TagState{}, false
+}
+
+// watchTagStates subscribes to the retained tag states, if they're cleared,
+// so that the states of tags which Departed before the service restarted expire too.
+// It's called whenever the client (re)connects.
+func (m *MQTT) watchTagStates(client mqtt.Client) {
+	if m.departed == nil {
+		return
+	}
+
+	// the tag state topic only varies by EPC
+	levels := strings.Split(m.topic(m.config.TagStateTopic, inventory.DepartedEvent{}), "/")
+	for i, level := range strings.Split(m.config.TagStateTopic, "/") {
+		if strings.Contains(level, "{epc}") {
+			levels[i] = "+"
+		}
+	}
+	filter := strings.Join(levels, "/")
+
+	token := client.Subscribe(filter, m.config.QoS, func(_ mqtt.Client, msg mqtt.Message) {
+		var state TagState
+		// cleared states are empty, and they're no longer tracked
+		if len(msg.Payload()) == 0 || json.Unmarshal(msg.Payload(), &state) != nil || state.EPC == "" {
+			return
+		}
+		m.trackTagState(state)
+	})
+	if err := wait(context.Background(), token, mqttConnectTimeout); err != nil {
+		m.lc.Warn("Failed to subscribe to MQTT tag states; states published before a restart won't be cleared.",
+			"topic", filter, "error", err.Error())
+	}
+}
+
+// trackTagState notes when the tag Departed, or forgets it if it's no longer Departed.
+// It does nothing unless tag states are cleared.
+func (m *MQTT) trackTagState(state TagState) {
+	if m.departed == nil {
+		return
+	}
+	m.departedMu.Lock()
+	if state.State == inventory.Departed {
+		m.departed[state.EPC] = state.Timestamp
+	} else {
+		delete(m.departed, state.EPC)
+	}
+	m.departedMu.Unlock()
+}
+
+// clearLoop periodically clears the states of tags which Departed more than TagStateTTL ago,
+// until the MQTT sink is closed.
+func (m *MQTT) clearLoop() {
+	defer close(m.stopped)
+	ticker := time.NewTicker(mqttClearInterval)
+	defer ticker.Stop()
+
+	// closing the sink interrupts clearing
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-m.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			// the client first connects when there are events to send
+			if !m.client.IsConnected() {
+				continue
+			}
+			if err := m.clearTagStates(ctx, now); err != nil {
+				m.lc.Error("Failed to clear MQTT tag states.", "error", err.Error())
+			}
+		}
+	}
+}
+
+// clearTagStates clears the retained states of tags which Departed more than TagStateTTL before now.
+func (m *MQTT) clearTagStates(ctx context.Context, now time.Time) error {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+
+	minTimestamp := inventory.UnixMilli(now.Add(-m.config.TagStateTTL))
+	var expired []string
+	m.departedMu.Lock()
+	for epc, departed := range m.departed {
+		if departed < minTimestamp {
+			expired = append(expired, epc)
+		}
+	}
+	m.departedMu.Unlock()
+
+	var errs []error
+	for _, epc := range expired {
+		topic := m.topic(m.config.TagStateTopic, inventory.DepartedEvent{BaseEvent: inventory.BaseEvent{EPC: epc}})
+		m.lc.Debug("Clearing MQTT tag state.", "topic", topic)
+		// an empty retained message clears the topic's state
+		if err := wait(ctx, m.client.Publish(topic, m.config.QoS, true, []byte{}), mqttPublishTimeout); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to clear tag state at %s", topic))
+			continue
+		}
+		m.departedMu.Lock()
+		delete(m.departed, epc)
+		m.departedMu.Unlock()
+	}
+
+	if errs != nil {
+		return llrp.MultiErr(errs)
+	}
+	return nil
+}
+
+// wait waits for the token to complete, until the timeout or ctx is done,
+// and returns its error.
+func wait(ctx context.Context, token mqtt.Token, timeout time.Duration) error {
+	done := make(chan bool, 1)
+	go func() { done <- token.WaitTimeout(timeout) }()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case ok := <-done:
+		if !ok {
+			return errors.New("timed out")
+		}
+		return token.Error()
+	}
+}
@@ -0,0 +1,349 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sink
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"encoding/json"
+	"encoding/pem"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/mochi-co/mqtt/server"
+	"github.com/mochi-co/mqtt/server/listeners"
+	"github.com/mochi-co/mqtt/server/listeners/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func getTestingLogger() logger.LoggingClient {
+	if testing.Verbose() {
+		return logger.NewClientStdOut("test", false, "DEBUG")
+	}
+
+	return logger.NewMockClient()
+}
+
+// testBroker is an embedded MQTT broker.
+// It always listens for plain TCP connections, and for TLS ones if it has a certificate.
+type testBroker struct {
+	server  *server.Server
+	addr    string
+	tlsAddr string
+}
+
+func newTestBroker(t *testing.T, cert *listeners.TLS) *testBroker {
+	t.Helper()
+	b := &testBroker{server: server.New(), addr: freeAddr(t)}
+	require.NoError(t, b.server.AddListener(listeners.NewTCP("tcp", b.addr),
+		&listeners.Config{Auth: new(auth.Allow)}))
+	if cert != nil {
+		b.tlsAddr = freeAddr(t)
+		require.NoError(t, b.server.AddListener(listeners.NewTCP("ssl", b.tlsAddr),
+			&listeners.Config{Auth: new(auth.Allow), TLS: cert}))
+	}
+	require.NoError(t, b.server.Serve())
+	return b
+}
+
+// freeAddr returns a local address on which nothing is listening.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func (b *testBroker) close() {
+	_ = b.server.Close()
+}
+
+// subscribe connects a new client which subscribes to the topic filter,
+// and returns a channel of the messages it receives.
+func (b *testBroker) subscribe(t *testing.T, clientID, filter string) <-chan mqtt.Message {
+	t.Helper()
+	client := mqtt.NewClient(mqtt.NewClientOptions().
+		AddBroker("tcp://" + b.addr).
+		SetClientID(clientID))
+	require.NoError(t, wait(context.Background(), client.Connect(), 5*time.Second))
+	t.Cleanup(func() { client.Disconnect(mqttDisconnectMs) })
+
+	messages := make(chan mqtt.Message, 100)
+	require.NoError(t, wait(context.Background(), client.Subscribe(filter, 1,
+		func(_ mqtt.Client, msg mqtt.Message) { messages <- msg }), 5*time.Second))
+	return messages
+}
+
+func nextMessage(t *testing.T, messages <-chan mqtt.Message) mqtt.Message {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return nil
+	}
+}
+
+func assertNoMessage(t *testing.T, messages <-chan mqtt.Message) {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		t.Errorf("unexpected message on %s: %s", msg.Topic(), msg.Payload())
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func assertPayload(t *testing.T, expected interface{}, msg mqtt.Message) {
+	t.Helper()
+	payload, err := json.Marshal(expected)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(payload), string(msg.Payload()), msg.Topic())
+}
+
+func TestMQTT(t *testing.T) {
+	broker := newTestBroker(t, nil)
+	defer broker.close()
+	messages := broker.subscribe(t, "events", "rfid/#")
+
+	m, err := NewMQTT(getTestingLogger(), MQTTConfig{
+		BrokerURL:     "tcp://" + broker.addr,
+		ClientID:      "test",
+		Topic:         DefaultMQTTTopic,
+		TagStateTopic: "rfid/{site}/tags/{epc}",
+		TagStateTTL:   time.Hour,
+		Site:          "Store/1",
+		QoS:           1,
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	arrived := inventory.ArrivedEvent{
+		BaseEvent: inventory.BaseEvent{EPC: "0102", TID: "03", Timestamp: 10},
+		Location:  "Freezer",
+	}
+	departed := inventory.DepartedEvent{
+		BaseEvent:         inventory.BaseEvent{EPC: "0102", Timestamp: 20},
+		LastKnownLocation: "Freezer",
+	}
+	offline := inventory.ReaderOfflineEvent{DeviceName: "Reader1", Timestamp: 30}
+	require.NoError(t, m.Send(context.Background(), []inventory.Event{arrived, departed, offline}))
+
+	arrivedState := TagState{EPC: "0102", TID: "03", State: inventory.Present, Location: "Freezer", Timestamp: 10}
+	departedState := TagState{EPC: "0102", State: inventory.Departed, Location: "Freezer", Timestamp: 20}
+	for _, expected := range []struct {
+		topic   string
+		payload interface{}
+	}{
+		{"rfid/Store_1/Arrived/Freezer", arrived},
+		{"rfid/Store_1/tags/0102", arrivedState},
+		{"rfid/Store_1/Departed/Freezer", departed},
+		{"rfid/Store_1/tags/0102", departedState},
+		{"rfid/Store_1/ReaderOffline/Reader1", offline},
+	} {
+		msg := nextMessage(t, messages)
+		assert.Equal(t, expected.topic, msg.Topic())
+		assert.Equal(t, byte(1), msg.Qos())
+		assertPayload(t, expected.payload, msg)
+	}
+
+	// a new subscriber receives only the tag's latest state
+	tags := broker.subscribe(t, "tags", "rfid/+/tags/#")
+	msg := nextMessage(t, tags)
+	assert.Equal(t, "rfid/Store_1/tags/0102", msg.Topic())
+	assert.True(t, msg.Retained())
+	assertPayload(t, departedState, msg)
+	assertNoMessage(t, tags)
+
+	// the state is cleared once the TTL has passed since the tag Departed
+	require.NoError(t, m.clearTagStates(context.Background(), time.Unix(0, 0).Add(time.Hour)))
+	assertNoMessage(t, messages)
+	require.NoError(t, m.clearTagStates(context.Background(), time.Now()))
+	msg = nextMessage(t, messages)
+	assert.Equal(t, "rfid/Store_1/tags/0102", msg.Topic())
+	assert.Empty(t, msg.Payload())
+
+	assertNoMessage(t, broker.subscribe(t, "tags2", "rfid/+/tags/#"))
+}
+
+func TestMQTT_clearsAfterRestart(t *testing.T) {
+	broker := newTestBroker(t, nil)
+	defer broker.close()
+
+	cfg := MQTTConfig{
+		BrokerURL:     "tcp://" + broker.addr,
+		ClientID:      "test",
+		Topic:         DefaultMQTTTopic,
+		TagStateTopic: "rfid/{site}/tags/{epc}",
+		TagStateTTL:   time.Hour,
+		Site:          "Store1",
+		QoS:           1,
+	}
+	m, err := NewMQTT(getTestingLogger(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, m.Send(context.Background(), []inventory.Event{
+		inventory.DepartedEvent{BaseEvent: inventory.BaseEvent{EPC: "0102", Timestamp: 20}},
+		inventory.DepartedEvent{BaseEvent: inventory.BaseEvent{EPC: "0304", Timestamp: inventory.UnixMilliNow()}},
+	}))
+	m.Close()
+
+	// after a restart, the states are learned from the broker
+	m, err = NewMQTT(getTestingLogger(), cfg)
+	require.NoError(t, err)
+	defer m.Close()
+	require.NoError(t, m.Send(context.Background(), []inventory.Event{inventory.ReaderOfflineEvent{DeviceName: "Reader1"}}))
+	require.Eventually(t, func() bool {
+		m.departedMu.Lock()
+		defer m.departedMu.Unlock()
+		return len(m.departed) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	tags := broker.subscribe(t, "tags", "rfid/+/tags/#")
+	for range []string{"0102", "0304"} {
+		assert.True(t, nextMessage(t, tags).Retained())
+	}
+	require.NoError(t, m.clearTagStates(context.Background(), time.Now()))
+	msg := nextMessage(t, tags)
+	assert.Equal(t, "rfid/Store1/tags/0102", msg.Topic())
+	assert.Empty(t, msg.Payload())
+	assertNoMessage(t, tags)
+}
+
+func TestMQTT_cancelled(t *testing.T) {
+	broker := newTestBroker(t, nil)
+	defer broker.close()
+	messages := broker.subscribe(t, "events", "rfid/#")
+
+	m, err := NewMQTT(getTestingLogger(), MQTTConfig{
+		BrokerURL: "tcp://" + broker.addr,
+		ClientID:  "test",
+		Topic:     "rfid/{eventType}",
+		QoS:       1,
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	// events sent while shutting down are still published
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	event := inventory.ReaderOfflineEvent{DeviceName: "Reader1"}
+	require.NoError(t, m.Send(ctx, []inventory.Event{event}))
+	assert.Equal(t, "rfid/ReaderOffline", nextMessage(t, messages).Topic())
+}
+
+func TestMQTT_topics(t *testing.T) {
+	m := &MQTT{config: MQTTConfig{Site: "site"}}
+	reader := inventory.AntennaEvent{ReaderEvent: inventory.ReaderEvent{DeviceName: "Reader1"}}
+	position := inventory.PositionUpdatedEvent{BaseEvent: inventory.BaseEvent{EPC: "0102"}}
+
+	assert.Equal(t, "site/Antenna/Reader1/Reader1/unknown",
+		m.topic("{site}/{eventType}/{alias}/{device}/{epc}", reader))
+	assert.Equal(t, "site/PositionUpdated/unknown/0102",
+		m.topic("{site}/{eventType}/{alias}/{epc}", position))
+
+	for _, bad := range []string{"rfid/#", "rfid/+/{epc}", "rfid/{location}"} {
+		_, err := NewMQTT(getTestingLogger(), MQTTConfig{BrokerURL: "tcp://localhost:1883", Topic: bad})
+		assert.Error(t, err, bad)
+	}
+
+	// each tag's state must stay on the same topic
+	for _, bad := range []string{"rfid/tags", "rfid/{site}/tags", "rfid/{eventType}/{epc}", "rfid/{alias}/{epc}", "rfid/{device}/{epc}"} {
+		_, err := NewMQTT(getTestingLogger(), MQTTConfig{BrokerURL: "tcp://localhost:1883", Topic: "rfid", TagStateTopic: bad})
+		assert.Error(t, err, bad)
+	}
+	_, err := NewMQTT(getTestingLogger(), MQTTConfig{BrokerURL: "tcp://localhost:1883", Topic: "rfid", TagStateTopic: "rfid/{site}/tags/{epc}"})
+	assert.NoError(t, err)
+
+	_, err = NewMQTT(getTestingLogger(), MQTTConfig{BrokerURL: "tcp://localhost:1883", Topic: "rfid", QoS: 3})
+	assert.Error(t, err)
+}
+
+func TestMQTT_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mqtt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cert, caFile := selfSignedCert(t, dir)
+	broker := newTestBroker(t, cert)
+	defer broker.close()
+	messages := broker.subscribe(t, "events", "rfid/#")
+
+	m, err := NewMQTT(getTestingLogger(), MQTTConfig{
+		BrokerURL: "ssl://" + broker.tlsAddr,
+		ClientID:  "test",
+		Topic:     "rfid/{eventType}",
+		CAFile:    caFile,
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	event := inventory.ReaderRecoveredEvent{DeviceName: "Reader1"}
+	require.NoError(t, m.Send(context.Background(), []inventory.Event{event}))
+	assert.Equal(t, "rfid/ReaderRecovered", nextMessage(t, messages).Topic())
+
+	// without the CA, the broker's certificate isn't trusted
+	untrusting, err := NewMQTT(getTestingLogger(), MQTTConfig{
+		BrokerURL: "ssl://" + broker.tlsAddr,
+		ClientID:  "test2",
+		Topic:     "rfid/{eventType}",
+	})
+	require.NoError(t, err)
+	defer untrusting.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.Error(t, untrusting.Send(ctx, []inventory.Event{event}))
+
+	_, err = NewMQTT(getTestingLogger(), MQTTConfig{
+		BrokerURL: "ssl://" + broker.tlsAddr,
+		Topic:     "rfid/{eventType}",
+		CertFile:  filepath.Join(dir, "missing.pem"),
+	})
+	assert.Error(t, err)
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and its key
+// and the path of a PEM file with which to trust it.
+func selfSignedCert(t *testing.T, dir string) (*listeners.TLS, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, certPEM, 0644))
+	return &listeners.TLS{
+		Certificate: certPEM,
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, caFile
+}
@@ -0,0 +1,35 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sink has destinations for inventory events,
+// which can be subscribed to an inventory.Engine.
+package sink
+
+import (
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+)
+
+// eventFields returns the event's tag's EPC, its location alias, and its Reader's name,
+// each of which is empty if the event doesn't have it.
+// For Reader events, the alias is the Reader's name.
+func eventFields(event inventory.Event) (epc, alias, device string) {
+	switch e := event.(type) {
+	case inventory.ArrivedEvent:
+		epc, alias = e.EPC, e.Location
+	case inventory.MovedEvent:
+		epc, alias = e.EPC, e.NewLocation
+	case inventory.DepartedEvent:
+		epc, alias = e.EPC, e.LastKnownLocation
+	case inventory.PositionUpdatedEvent:
+		epc = e.EPC
+	case inventory.ReaderOfflineEvent:
+		device, alias = e.DeviceName, e.DeviceName
+	case inventory.ReaderRecoveredEvent:
+		device, alias = e.DeviceName, e.DeviceName
+	case interface{ Device() string }:
+		device, alias = e.Device(), e.Device()
+	}
+	return epc, alias, device
+}
@@ -0,0 +1,859 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// WebhookSignatureHeader holds the hex HMAC-SHA256 of a delivery's body,
+	// keyed with its webhook's secret, as "sha256={signature}".
+	// It's only sent for webhooks with a secret.
+	WebhookSignatureHeader = "X-Inventory-Signature"
+	// WebhookIDHeader holds the ID of the webhook to which a delivery was sent.
+	WebhookIDHeader = "X-Inventory-Webhook"
+	// WebhookDeliveryHeader holds a delivery's ID, which is the same for each attempt,
+	// so receivers can ignore duplicates.
+	WebhookDeliveryHeader = "X-Inventory-Delivery"
+
+	defaultWebhookMaxAttempts    = 5
+	defaultWebhookInitialBackoff = time.Second
+	defaultWebhookMaxBackoff     = time.Minute
+	defaultWebhookTimeout        = 10 * time.Second
+	defaultWebhookQueueSize      = 100
+	defaultWebhookDeadLetters    = 100
+	defaultWebhookCloseTimeout   = 5 * time.Second
+	defaultWebhookSaveDelay      = 5 * time.Second
+
+	// AnyWebhookHost allows webhooks to deliver to any host.
+	AnyWebhookHost = "*"
+
+	// webhookResponseBytes limits how much of a response is read before it's discarded.
+	webhookResponseBytes = 4096
+	// shutdownReason is the error of dead letters which were queued when their webhook stopped.
+	shutdownReason = "not delivered before shutdown"
+)
+
+var (
+	// ErrWebhookNotFound is returned for operations on webhooks which aren't registered.
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrWebhookExists is returned when registering a webhook with an ID that's already in use.
+	ErrWebhookExists = errors.New("webhook already exists")
+	// ErrInvalidWebhook is returned when registering a webhook with an invalid configuration.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrWebhookNotAllowed is returned when registering a webhook whose URL's host isn't allowed.
+	ErrWebhookNotAllowed = errors.New("webhook host not allowed")
+	// ErrWebhooksClosed is returned when registering or removing a webhook after the Webhooks are closed.
+	ErrWebhooksClosed = errors.New("webhooks closed")
+)
+
+// WebhookConfig is a webhook's registration.
+//
+// A webhook only receives the events which match all of its filters;
+// an empty filter matches every event.
+type WebhookConfig struct {
+	// ID identifies the webhook. If it's empty when the webhook is registered, one is generated.
+	ID string `json:"id"`
+	// URL is the http or https URL to which batches of events are POSTed.
+	URL string `json:"url"`
+	// Secret, if set, is the key with which each delivery is signed.
+	Secret string `json:"secret,omitempty"`
+
+	// EventTypes restricts the webhook to events of these types.
+	EventTypes []inventory.EventType `json:"event_types,omitempty"`
+	// Aliases restricts the webhook to events at these locations,
+	// or, for Reader events, from these Readers.
+	// Events without a location don't match.
+	Aliases []string `json:"aliases,omitempty"`
+	// EPCPrefixes restricts the webhook to events for tags whose EPCs start with one of these,
+	// ignoring case. Events without an EPC don't match.
+	EPCPrefixes []string `json:"epc_prefixes,omitempty"`
+}
+
+// WebhookEvent is an event as it's sent to a webhook.
+// Each delivery's body is a JSON array of them.
+type WebhookEvent struct {
+	Type  inventory.EventType `json:"type"`
+	Event inventory.Event     `json:"event"`
+}
+
+// WebhookStatus reports a webhook's registration, without its secret, and its deliveries.
+// Its counters start at zero when the service starts.
+type WebhookStatus struct {
+	ID          string                `json:"id"`
+	URL         string                `json:"url"`
+	Signed      bool                  `json:"signed"`
+	EventTypes  []inventory.EventType `json:"event_types,omitempty"`
+	Aliases     []string              `json:"aliases,omitempty"`
+	EPCPrefixes []string              `json:"epc_prefixes,omitempty"`
+
+	// Queued is how many deliveries are waiting to be sent.
+	Queued int `json:"queued"`
+	// Delivered is how many deliveries succeeded.
+	Delivered uint64 `json:"delivered"`
+	// Retries is how many attempts failed and were retried.
+	Retries uint64 `json:"retries"`
+	// Failed is how many deliveries were moved to the dead letters.
+	Failed uint64 `json:"failed"`
+	// Dropped is how many batches of events were dropped because the queue was full.
+	Dropped uint64 `json:"dropped"`
+	// DeadLetters is how many failed deliveries are stored.
+	DeadLetters int `json:"dead_letters"`
+
+	// LastAttempt and LastSuccess are in milliseconds since the epoch, or 0 if there's been none.
+	LastAttempt    int64  `json:"last_attempt,omitempty"`
+	LastSuccess    int64  `json:"last_success,omitempty"`
+	LastStatusCode int    `json:"last_status_code,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+// DeadLetter is a delivery which failed every attempt, or which was still queued at shutdown.
+type DeadLetter struct {
+	DeliveryID string `json:"delivery_id"`
+	// Events is the delivery's body.
+	Events    json.RawMessage `json:"events"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	// Timestamp is when the delivery failed, in milliseconds since the epoch.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// WebhooksConfig configures the Webhooks. Zero values use the defaults.
+type WebhooksConfig struct {
+	// Path is the file in which registrations and dead letters are kept.
+	// If it's empty, they're only kept in memory.
+	Path string
+
+	// AllowedHosts are the hostnames to which webhooks may deliver, or AnyWebhookHost.
+	// Anyone who can register a webhook can make the service send requests
+	// to the hosts it can reach, so if there are none, webhooks can't be registered.
+	AllowedHosts []string
+
+	// MaxAttempts is how many times a delivery is attempted before it's dead-lettered.
+	// Attempts which fail with a 4xx status other than 408 or 429 aren't retried.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, which doubles after each one,
+	// up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout limits each attempt.
+	Timeout time.Duration
+	// QueueSize is how many deliveries each webhook queues before dropping new ones.
+	QueueSize int
+	// MaxDeadLetters is how many dead letters each webhook keeps; older ones are discarded.
+	MaxDeadLetters int
+	// CloseTimeout is how long Close waits for in-flight attempts to finish.
+	CloseTimeout time.Duration
+	// SaveDelay is how long after a delivery is dead-lettered the file is saved,
+	// so that a failing webhook's dead letters are saved together.
+	SaveDelay time.Duration
+}
+
+// Webhooks is a registry of webhooks, to each of which Send delivers the events its filters match.
+//
+// Each webhook has its own queue and goroutine, so a slow or failing one doesn't delay the rest.
+// Failed attempts are retried with exponential backoff,
+// and deliveries which can't be made are kept as dead letters, which can be redelivered.
+//
+// Its methods are safe to call from multiple goroutines.
+type Webhooks struct {
+	lc     logger.LoggingClient
+	config WebhooksConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	hooks  map[string]*webhook
+	closed bool
+	wg     sync.WaitGroup
+
+	// saveMu serializes writes to the file, so they land in the order they're made.
+	saveMu sync.Mutex
+	// saveTimer is set while a delayed save is pending. It's guarded by mu.
+	saveTimer *time.Timer
+}
+
+// webhook is a registered webhook. Other than its queue and stop channel,
+// its fields are guarded by the Webhooks' mutex.
+type webhook struct {
+	config      WebhookConfig
+	status      WebhookStatus
+	deadLetters []DeadLetter
+
+	queue chan delivery
+	stop  chan struct{}
+	// removed is set when the webhook is unregistered, so its pending deliveries are discarded.
+	removed bool
+}
+
+type delivery struct {
+	id       string
+	body     []byte
+	attempts int
+}
+
+// webhooksFile is the persisted form of the registry.
+type webhooksFile struct {
+	Webhooks []persistedWebhook `json:"webhooks"`
+}
+
+type persistedWebhook struct {
+	Config      WebhookConfig `json:"config"`
+	DeadLetters []DeadLetter  `json:"dead_letters,omitempty"`
+}
+
+// NewWebhooks returns a registry with the webhooks in the config's Path, if it exists,
+// each of which starts delivering events, including any it has queued.
+// It returns an error if the file can't be read.
+func NewWebhooks(lc logger.LoggingClient, cfg WebhooksConfig) (*Webhooks, error) {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultWebhookInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultWebhookMaxBackoff
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultWebhookQueueSize
+	}
+	if cfg.MaxDeadLetters <= 0 {
+		cfg.MaxDeadLetters = defaultWebhookDeadLetters
+	}
+	if cfg.CloseTimeout <= 0 {
+		cfg.CloseTimeout = defaultWebhookCloseTimeout
+	}
+	if cfg.SaveDelay <= 0 {
+		cfg.SaveDelay = defaultWebhookSaveDelay
+	}
+
+	w := &Webhooks{
+		lc:     lc,
+		config: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// a redirect could lead to a host which isn't allowed
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		hooks: map[string]*webhook{},
+	}
+
+	if cfg.Path == "" {
+		return w, nil
+	}
+
+	data, err := ioutil.ReadFile(cfg.Path)
+	if os.IsNotExist(err) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read webhooks")
+	}
+
+	var f webhooksFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse webhooks file %q", cfg.Path)
+	}
+	for _, p := range f.Webhooks {
+		if err := w.checkHost(p.Config.URL); err != nil {
+			lc.Warn("Dropping webhook whose host is no longer allowed.",
+				"id", p.Config.ID, "url", p.Config.URL)
+			continue
+		}
+		h := w.newWebhook(p.Config)
+		h.deadLetters = p.DeadLetters
+		w.hooks[h.config.ID] = h
+		w.start(h)
+	}
+	lc.Info("Loaded webhooks.", "count", len(w.hooks))
+
+	return w, nil
+}
+
+func (w *Webhooks) newWebhook(cfg WebhookConfig) *webhook {
+	return &webhook{
+		config: cfg,
+		queue:  make(chan delivery, w.config.QueueSize),
+		stop:   make(chan struct{}),
+	}
+}
+
+// validate returns an error wrapping ErrInvalidWebhook if the config is invalid.
+func (cfg WebhookConfig) validate() error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return errors.Wrapf(ErrInvalidWebhook, "bad URL: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Wrapf(ErrInvalidWebhook, "URL %q must be an absolute http or https URL", cfg.URL)
+	}
+	for _, t := range cfg.EventTypes {
+		if t == "" {
+			return errors.Wrap(ErrInvalidWebhook, "empty event type")
+		}
+	}
+	for _, p := range cfg.EPCPrefixes {
+		if p == "" {
+			return errors.Wrap(ErrInvalidWebhook, "empty EPC prefix")
+		}
+	}
+	return nil
+}
+
+// checkHost returns an error wrapping ErrWebhookNotAllowed
+// unless rawURL's host is one of the AllowedHosts.
+func (w *Webhooks) checkHost(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrapf(ErrInvalidWebhook, "bad URL: %v", err)
+	}
+	for _, allowed := range w.config.AllowedHosts {
+		if allowed == AnyWebhookHost || strings.EqualFold(allowed, u.Hostname()) {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrWebhookNotAllowed, "host %q", u.Hostname())
+}
+
+// Register adds a webhook and returns its config, with its ID if one was generated.
+// Its URL's host must be one of the AllowedHosts.
+func (w *Webhooks) Register(cfg WebhookConfig) (WebhookConfig, error) {
+	if err := cfg.validate(); err != nil {
+		return cfg, err
+	}
+	if err := w.checkHost(cfg.URL); err != nil {
+		return cfg, err
+	}
+	if cfg.ID == "" {
+		cfg.ID = newID()
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return cfg, ErrWebhooksClosed
+	}
+	if _, ok := w.hooks[cfg.ID]; ok {
+		w.mu.Unlock()
+		return cfg, errors.Wrapf(ErrWebhookExists, "id %q", cfg.ID)
+	}
+	h := w.newWebhook(cfg)
+	w.hooks[cfg.ID] = h
+	w.start(h)
+	w.mu.Unlock()
+
+	w.lc.Info("Registered webhook.", "id", cfg.ID, "url", cfg.URL)
+	w.save()
+	return cfg, nil
+}
+
+// Remove unregisters a webhook, discarding its pending deliveries and dead letters.
+func (w *Webhooks) Remove(id string) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWebhooksClosed
+	}
+	h, ok := w.hooks[id]
+	if !ok {
+		w.mu.Unlock()
+		return errors.Wrapf(ErrWebhookNotFound, "id %q", id)
+	}
+	delete(w.hooks, id)
+	h.removed = true
+	close(h.stop)
+	w.mu.Unlock()
+
+	w.lc.Info("Removed webhook.", "id", id)
+	w.save()
+	return nil
+}
+
+// List returns the status of each webhook, ordered by ID.
+func (w *Webhooks) List() []WebhookStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	statuses := make([]WebhookStatus, 0, len(w.hooks))
+	for _, h := range w.hooks {
+		statuses = append(statuses, h.statusLocked())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// Status returns a webhook's status.
+func (w *Webhooks) Status(id string) (WebhookStatus, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	h, ok := w.hooks[id]
+	if !ok {
+		return WebhookStatus{}, errors.Wrapf(ErrWebhookNotFound, "id %q", id)
+	}
+	return h.statusLocked(), nil
+}
+
+// DeadLetters returns a webhook's dead letters, oldest first.
+func (w *Webhooks) DeadLetters(id string) ([]DeadLetter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	h, ok := w.hooks[id]
+	if !ok {
+		return nil, errors.Wrapf(ErrWebhookNotFound, "id %q", id)
+	}
+	return append([]DeadLetter{}, h.deadLetters...), nil
+}
+
+// ClearDeadLetters discards a webhook's dead letters.
+func (w *Webhooks) ClearDeadLetters(id string) error {
+	w.mu.Lock()
+	h, ok := w.hooks[id]
+	if !ok {
+		w.mu.Unlock()
+		return errors.Wrapf(ErrWebhookNotFound, "id %q", id)
+	}
+	h.deadLetters = nil
+	w.mu.Unlock()
+
+	w.save()
+	return nil
+}
+
+// Redeliver queues as many of a webhook's dead letters as fit in its queue,
+// oldest first, and returns how many it queued.
+// Each keeps its delivery ID, but its attempts start over.
+func (w *Webhooks) Redeliver(id string) (int, error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrWebhooksClosed
+	}
+	h, ok := w.hooks[id]
+	if !ok {
+		w.mu.Unlock()
+		return 0, errors.Wrapf(ErrWebhookNotFound, "id %q", id)
+	}
+
+	n := 0
+queue:
+	for _, dl := range h.deadLetters {
+		select {
+		case h.queue <- delivery{id: dl.DeliveryID, body: dl.Events}:
+			n++
+		default:
+			break queue
+		}
+	}
+	h.deadLetters = h.deadLetters[n:]
+	w.mu.Unlock()
+
+	if n > 0 {
+		w.save()
+	}
+	return n, nil
+}
+
+// Send queues a delivery of the events each webhook's filters match.
+// It doesn't wait for them to be delivered,
+// so it's an inventory.EventHandler, except that it returns an error.
+func (w *Webhooks) Send(_ context.Context, events []inventory.Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, h := range w.hooks {
+		var batch []WebhookEvent
+		for _, e := range events {
+			if h.config.matches(e) {
+				batch = append(batch, WebhookEvent{Type: e.OfType(), Event: e})
+			}
+		}
+		if len(batch) == 0 {
+			continue
+		}
+
+		body, err := json.Marshal(batch)
+		if err != nil {
+			return errors.Wrap(err, "error marshalling events")
+		}
+
+		select {
+		case h.queue <- delivery{id: newID(), body: body}:
+		default:
+			h.status.Dropped++
+			w.lc.Warn("Webhook's queue is full; dropping events.",
+				"id", h.config.ID, "events", len(batch), "totalDropped", h.status.Dropped)
+		}
+	}
+	return nil
+}
+
+// Close stops delivering events and waits up to the CloseTimeout
+// for the webhooks' in-flight attempts to finish, after which they're cancelled.
+// Attempts which fail aren't retried. Deliveries which haven't succeeded,
+// including those still queued, are kept as dead letters.
+// Afterwards, webhooks can't be registered, removed or redelivered.
+func (w *Webhooks) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	for _, h := range w.hooks {
+		close(h.stop)
+	}
+	if w.saveTimer != nil {
+		w.saveTimer.Stop()
+		w.saveTimer = nil
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	w.save()
+}
+
+// matches returns true if the event passes all the webhook's filters.
+func (cfg WebhookConfig) matches(event inventory.Event) bool {
+	if len(cfg.EventTypes) != 0 {
+		found := false
+		for _, t := range cfg.EventTypes {
+			if t == event.OfType() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	epc, alias, _ := eventFields(event)
+
+	if len(cfg.Aliases) != 0 {
+		found := false
+		for _, a := range cfg.Aliases {
+			if alias != "" && a == alias {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(cfg.EPCPrefixes) != 0 {
+		found := false
+		for _, p := range cfg.EPCPrefixes {
+			if epc != "" && strings.HasPrefix(strings.ToLower(epc), strings.ToLower(p)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// start starts the webhook's delivery goroutine.
+func (w *Webhooks) start(h *webhook) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run(h)
+	}()
+}
+
+// run delivers the webhook's queued events until it's stopped.
+// An attempt in flight when it's stopped is cancelled after the CloseTimeout.
+func (w *Webhooks) run(h *webhook) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.stop:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-time.After(w.config.CloseTimeout):
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case <-h.stop:
+			w.drain(h)
+			return
+		case d := <-h.queue:
+			if h.stopped() {
+				w.deadLetter(h, d, shutdownReason)
+				continue
+			}
+			w.deliver(ctx, h, d)
+		}
+	}
+}
+
+// drain dead-letters the webhook's queued deliveries, unless it's been removed.
+func (w *Webhooks) drain(h *webhook) {
+	for {
+		select {
+		case d := <-h.queue:
+			w.deadLetter(h, d, shutdownReason)
+		default:
+			return
+		}
+	}
+}
+
+// deliver attempts the delivery until it succeeds, fails in a way that isn't worth retrying,
+// runs out of attempts, or the webhook is stopped, in which case it's dead-lettered.
+func (w *Webhooks) deliver(ctx context.Context, h *webhook, d delivery) {
+	backoff := w.config.InitialBackoff
+	for {
+		d.attempts++
+		code, err := w.post(ctx, h.config, d)
+
+		w.mu.Lock()
+		h.status.LastAttempt = inventory.UnixMilliNow()
+		h.status.LastStatusCode = code
+		if err == nil {
+			h.status.Delivered++
+			h.status.LastSuccess = h.status.LastAttempt
+			h.status.LastError = ""
+			w.mu.Unlock()
+			return
+		}
+		h.status.LastError = err.Error()
+		retry := retryable(code) && d.attempts < w.config.MaxAttempts && !h.stopped()
+		if retry {
+			h.status.Retries++
+		}
+		w.mu.Unlock()
+
+		if !retry {
+			w.lc.Warn("Failed to deliver events to webhook.",
+				"id", h.config.ID, "delivery", d.id, "attempts", d.attempts, "error", err.Error())
+			w.deadLetter(h, d, err.Error())
+			return
+		}
+
+		w.lc.Debug("Retrying webhook delivery.",
+			"id", h.config.ID, "delivery", d.id, "backoff", backoff.String(), "error", err.Error())
+		select {
+		case <-h.stop:
+			w.deadLetter(h, d, err.Error())
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > w.config.MaxBackoff {
+			backoff = w.config.MaxBackoff
+		}
+	}
+}
+
+// stopped returns true if the webhook has been stopped.
+func (h *webhook) stopped() bool {
+	select {
+	case <-h.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryable returns true if an attempt which failed with the status code is worth retrying.
+// A code of 0 means the request didn't get a response.
+func retryable(code int) bool {
+	return code == 0 || code >= 500 ||
+		code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
+
+// post makes one attempt at a delivery and returns the response's status code,
+// which is 0 if there's no response, and an error unless it's a 2xx.
+func (w *Webhooks) post(ctx context.Context, cfg WebhookConfig, d delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create webhook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, cfg.ID)
+	req.Header.Set(WebhookDeliveryHeader, d.id)
+	if cfg.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, Signature(cfg.Secret, d.body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, webhookResponseBytes))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Signature returns the value of the WebhookSignatureHeader for a body signed with the secret.
+// Receivers can compare it to the header using hmac.Equal.
+func Signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deadLetter stores a failed delivery, unless the webhook has been removed,
+// discarding the oldest dead letter if there are too many.
+// The file is saved after the SaveDelay, along with any other dead letters by then.
+func (w *Webhooks) deadLetter(h *webhook, d delivery, reason string) {
+	w.mu.Lock()
+	if h.removed {
+		w.mu.Unlock()
+		return
+	}
+	h.status.Failed++
+	h.deadLetters = append(h.deadLetters, DeadLetter{
+		DeliveryID: d.id,
+		Events:     d.body,
+		Attempts:   d.attempts,
+		LastError:  reason,
+		Timestamp:  inventory.UnixMilliNow(),
+	})
+	if extra := len(h.deadLetters) - w.config.MaxDeadLetters; extra > 0 {
+		w.lc.Warn("Too many dead letters for webhook; discarding the oldest.",
+			"id", h.config.ID, "discarded", extra)
+		h.deadLetters = append([]DeadLetter{}, h.deadLetters[extra:]...)
+	}
+	w.saveLaterLocked()
+	w.mu.Unlock()
+}
+
+// statusLocked returns the webhook's status. The caller must hold the Webhooks' mutex.
+func (h *webhook) statusLocked() WebhookStatus {
+	s := h.status
+	s.ID = h.config.ID
+	s.URL = h.config.URL
+	s.Signed = h.config.Secret != ""
+	s.EventTypes = h.config.EventTypes
+	s.Aliases = h.config.Aliases
+	s.EPCPrefixes = h.config.EPCPrefixes
+	s.Queued = len(h.queue)
+	s.DeadLetters = len(h.deadLetters)
+	return s
+}
+
+// save writes the registrations and dead letters to the file, if there is one,
+// logging any error.
+func (w *Webhooks) save() {
+	if w.config.Path == "" {
+		return
+	}
+
+	w.saveMu.Lock()
+	defer w.saveMu.Unlock()
+
+	w.mu.Lock()
+	f := webhooksFile{Webhooks: make([]persistedWebhook, 0, len(w.hooks))}
+	for _, h := range w.hooks {
+		f.Webhooks = append(f.Webhooks, persistedWebhook{Config: h.config, DeadLetters: h.deadLetters})
+	}
+	w.mu.Unlock()
+	sort.Slice(f.Webhooks, func(i, j int) bool { return f.Webhooks[i].Config.ID < f.Webhooks[j].Config.ID })
+
+	if err := writeFile(w.config.Path, f); err != nil {
+		w.lc.Error("Failed to save webhooks.", "path", w.config.Path, "error", err.Error())
+	}
+}
+
+// saveLaterLocked saves the file after the SaveDelay, unless a save is already pending
+// or the Webhooks are closed, in which case Close saves it.
+// The caller must hold the Webhooks' mutex.
+func (w *Webhooks) saveLaterLocked() {
+	if w.config.Path == "" || w.closed || w.saveTimer != nil {
+		return
+	}
+	w.saveTimer = time.AfterFunc(w.config.SaveDelay, func() {
+		w.mu.Lock()
+		w.saveTimer = nil
+		w.mu.Unlock()
+		w.save()
+	})
+}
+
+// writeFile replaces the file at path with v's JSON,
+// which is readable only by its owner since it has the webhooks' secrets.
+func writeFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal webhooks")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary webhooks file")
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to write webhooks")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to sync webhooks")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to close webhooks")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), path), "failed to replace webhooks")
+}
+
+// newID returns a random hex ID.
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand doesn't fail on supported platforms
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,374 @@
+//
+// Copyright (C) 2021 Intel Corporation
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sink
+
+import (
+	"context"
+	"edgexfoundry/app-rfid-llrp-inventory/pkg/inventory"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testHosts allows webhooks to deliver to httptest servers.
+var testHosts = []string{"127.0.0.1"}
+
+// webhookReceiver records the deliveries made to it
+// and responds to each with the next of its status codes, or 200 once they run out.
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	statuses   []int
+	deliveries []*receivedDelivery
+}
+
+type receivedDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(statuses ...int) *webhookReceiver {
+	r := &webhookReceiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+
+		r.mu.Lock()
+		r.deliveries = append(r.deliveries, &receivedDelivery{header: req.Header, body: body})
+		status := http.StatusOK
+		if len(r.statuses) != 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	return r
+}
+
+func (r *webhookReceiver) received() []*receivedDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*receivedDelivery{}, r.deliveries...)
+}
+
+// waitForStatus waits until the webhook's status satisfies done.
+func waitForStatus(t *testing.T, w *Webhooks, id string, done func(s WebhookStatus) bool) WebhookStatus {
+	t.Helper()
+	var s WebhookStatus
+	require.Eventually(t, func() bool {
+		var err error
+		s, err = w.Status(id)
+		require.NoError(t, err)
+		return done(s)
+	}, 5*time.Second, time.Millisecond)
+	return s
+}
+
+func TestWebhooks(t *testing.T) {
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	w, err := NewWebhooks(getTestingLogger(), WebhooksConfig{AllowedHosts: testHosts})
+	require.NoError(t, err)
+	defer w.Close()
+
+	filtered, err := w.Register(WebhookConfig{
+		ID:          "freezer",
+		URL:         receiver.URL,
+		Secret:      "s3cret",
+		EventTypes:  []inventory.EventType{inventory.ArrivedType, inventory.MovedType},
+		Aliases:     []string{"Freezer"},
+		EPCPrefixes: []string{"30AB"},
+	})
+	require.NoError(t, err)
+	all, err := w.Register(WebhookConfig{URL: receiver.URL})
+	require.NoError(t, err)
+	assert.NotEmpty(t, all.ID)
+
+	_, err = w.Register(WebhookConfig{ID: "freezer", URL: receiver.URL})
+	assert.True(t, errors.Is(err, ErrWebhookExists))
+
+	arrived := inventory.ArrivedEvent{BaseEvent: inventory.BaseEvent{EPC: "30ab01"}, Location: "Freezer"}
+	elsewhere := inventory.ArrivedEvent{BaseEvent: inventory.BaseEvent{EPC: "30ab02"}, Location: "Door"}
+	otherEPC := inventory.MovedEvent{BaseEvent: inventory.BaseEvent{EPC: "e201"}, NewLocation: "Freezer"}
+	departed := inventory.DepartedEvent{BaseEvent: inventory.BaseEvent{EPC: "30ab01"}, LastKnownLocation: "Freezer"}
+	events := []inventory.Event{arrived, elsewhere, otherEPC, departed}
+	require.NoError(t, w.Send(context.Background(), events))
+
+	waitForStatus(t, w, filtered.ID, func(s WebhookStatus) bool { return s.Delivered == 1 })
+	waitForStatus(t, w, all.ID, func(s WebhookStatus) bool { return s.Delivered == 1 })
+
+	deliveries := map[string]*receivedDelivery{}
+	for _, d := range receiver.received() {
+		deliveries[d.header.Get(WebhookIDHeader)] = d
+	}
+	require.Len(t, deliveries, 2)
+
+	d := deliveries[filtered.ID]
+	require.NotNil(t, d)
+	assert.Equal(t, "application/json", d.header.Get("Content-Type"))
+	assert.NotEmpty(t, d.header.Get(WebhookDeliveryHeader))
+	assert.Equal(t, Signature("s3cret", d.body), d.header.Get(WebhookSignatureHeader))
+	expected, err := json.Marshal([]WebhookEvent{{Type: inventory.ArrivedType, Event: arrived}})
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(d.body))
+
+	d = deliveries[all.ID]
+	require.NotNil(t, d)
+	assert.Empty(t, d.header.Get(WebhookSignatureHeader))
+	var batch []WebhookEvent
+	for _, e := range events {
+		batch = append(batch, WebhookEvent{Type: e.OfType(), Event: e})
+	}
+	expected, err = json.Marshal(batch)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(d.body))
+
+	// statuses are sorted by ID and don't include secrets
+	statuses := w.List()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "freezer", statuses[1].ID)
+	assert.True(t, statuses[1].Signed)
+	assert.Equal(t, []string{"Freezer"}, statuses[1].Aliases)
+	status, err := json.Marshal(statuses)
+	require.NoError(t, err)
+	assert.NotContains(t, string(status), "s3cret")
+
+	require.NoError(t, w.Remove(filtered.ID))
+	assert.True(t, errors.Is(w.Remove(filtered.ID), ErrWebhookNotFound))
+	_, err = w.Status(filtered.ID)
+	assert.True(t, errors.Is(err, ErrWebhookNotFound))
+}
+
+func TestWebhooks_retry(t *testing.T) {
+	receiver := newWebhookReceiver(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer receiver.Close()
+
+	w, err := NewWebhooks(getTestingLogger(), WebhooksConfig{AllowedHosts: testHosts, InitialBackoff: time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	hook, err := w.Register(WebhookConfig{URL: receiver.URL})
+	require.NoError(t, err)
+	require.NoError(t, w.Send(context.Background(), []inventory.Event{
+		inventory.ReaderOfflineEvent{DeviceName: "Reader1"},
+	}))
+
+	s := waitForStatus(t, w, hook.ID, func(s WebhookStatus) bool { return s.Delivered == 1 })
+	assert.Equal(t, uint64(2), s.Retries)
+	assert.Equal(t, uint64(0), s.Failed)
+	assert.Equal(t, http.StatusOK, s.LastStatusCode)
+	assert.Empty(t, s.LastError)
+
+	// every attempt has the same delivery ID
+	deliveries := receiver.received()
+	require.Len(t, deliveries, 3)
+	for _, d := range deliveries[1:] {
+		assert.Equal(t, deliveries[0].header.Get(WebhookDeliveryHeader), d.header.Get(WebhookDeliveryHeader))
+	}
+}
+
+func TestWebhooks_deadLetters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	receiver := newWebhookReceiver(http.StatusBadRequest,
+		http.StatusInternalServerError, http.StatusInternalServerError)
+	defer receiver.Close()
+
+	cfg := WebhooksConfig{
+		Path:           filepath.Join(dir, "webhooks.json"),
+		AllowedHosts:   testHosts,
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+		SaveDelay:      time.Millisecond,
+	}
+	w, err := NewWebhooks(getTestingLogger(), cfg)
+	require.NoError(t, err)
+
+	hook, err := w.Register(WebhookConfig{ID: "hook", URL: receiver.URL, Secret: "s3cret"})
+	require.NoError(t, err)
+
+	// a 4xx isn't retried, but a 5xx is, until it runs out of attempts
+	first := []inventory.Event{inventory.ReaderOfflineEvent{DeviceName: "Reader1"}}
+	second := []inventory.Event{inventory.ReaderRecoveredEvent{DeviceName: "Reader1"}}
+	require.NoError(t, w.Send(context.Background(), first))
+	require.NoError(t, w.Send(context.Background(), second))
+
+	s := waitForStatus(t, w, hook.ID, func(s WebhookStatus) bool { return s.Failed == 2 })
+	assert.Equal(t, uint64(1), s.Retries)
+	assert.Equal(t, 2, s.DeadLetters)
+	assert.Equal(t, http.StatusInternalServerError, s.LastStatusCode)
+
+	// dead letters are saved shortly after they fail, without waiting for Close
+	require.Eventually(t, func() bool {
+		var f webhooksFile
+		data, err := ioutil.ReadFile(cfg.Path)
+		return err == nil && json.Unmarshal(data, &f) == nil &&
+			len(f.Webhooks) == 1 && len(f.Webhooks[0].DeadLetters) == 2
+	}, 5*time.Second, time.Millisecond)
+	w.Close()
+
+	_, err = w.Register(WebhookConfig{URL: receiver.URL})
+	assert.True(t, errors.Is(err, ErrWebhooksClosed))
+	_, err = w.Redeliver(hook.ID)
+	assert.True(t, errors.Is(err, ErrWebhooksClosed))
+
+	// the registration and dead letters survive a restart
+	w, err = NewWebhooks(getTestingLogger(), cfg)
+	require.NoError(t, err)
+	defer w.Close()
+
+	letters, err := w.DeadLetters(hook.ID)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	assert.Equal(t, 1, letters[0].Attempts)
+	assert.Equal(t, 2, letters[1].Attempts)
+	assert.Contains(t, letters[1].LastError, "500")
+
+	n, err := w.Redeliver(hook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	waitForStatus(t, w, hook.ID, func(s WebhookStatus) bool { return s.Delivered == 2 })
+
+	deliveries := receiver.received()
+	require.Len(t, deliveries, 5)
+	for i, d := range deliveries[3:] {
+		assert.Equal(t, letters[i].DeliveryID, d.header.Get(WebhookDeliveryHeader))
+		assert.JSONEq(t, string(letters[i].Events), string(d.body))
+		assert.Equal(t, Signature("s3cret", d.body), d.header.Get(WebhookSignatureHeader))
+	}
+
+	letters, err = w.DeadLetters(hook.ID)
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+func TestWebhooks_shutdown(t *testing.T) {
+	// the receiver never answers, so deliveries are still in flight or queued at shutdown
+	block := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-block }))
+	defer receiver.Close()
+	defer close(block)
+
+	w, err := NewWebhooks(getTestingLogger(), WebhooksConfig{AllowedHosts: testHosts, CloseTimeout: time.Millisecond})
+	require.NoError(t, err)
+	hook, err := w.Register(WebhookConfig{URL: receiver.URL})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.Send(context.Background(), []inventory.Event{
+			inventory.ReaderOfflineEvent{DeviceName: "Reader1"},
+		}))
+	}
+	waitForStatus(t, w, hook.ID, func(s WebhookStatus) bool { return s.LastAttempt == 0 && s.Queued == 2 })
+
+	w.Close()
+	letters, err := w.DeadLetters(hook.ID)
+	require.NoError(t, err)
+	assert.Len(t, letters, 3)
+}
+
+func TestWebhooks_closeWaits(t *testing.T) {
+	// the receiver answers once the delivery is in flight at shutdown
+	started := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer receiver.Close()
+
+	w, err := NewWebhooks(getTestingLogger(), WebhooksConfig{AllowedHosts: testHosts})
+	require.NoError(t, err)
+	hook, err := w.Register(WebhookConfig{URL: receiver.URL})
+	require.NoError(t, err)
+	require.NoError(t, w.Send(context.Background(), []inventory.Event{
+		inventory.ReaderOfflineEvent{DeviceName: "Reader1"},
+	}))
+
+	<-started
+	w.Close()
+	s, err := w.Status(hook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), s.Delivered)
+	assert.Equal(t, 0, s.DeadLetters)
+}
+
+func TestWebhooks_allowedHosts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// redirects aren't followed, since they could lead anywhere
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(receiver.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+
+	cfg := WebhooksConfig{Path: filepath.Join(dir, "webhooks.json"), AllowedHosts: testHosts}
+	w, err := NewWebhooks(getTestingLogger(), cfg)
+	require.NoError(t, err)
+
+	_, err = w.Register(WebhookConfig{URL: "http://169.254.169.254/latest"})
+	assert.True(t, errors.Is(err, ErrWebhookNotAllowed))
+	hook, err := w.Register(WebhookConfig{URL: redirect.URL})
+	require.NoError(t, err)
+	require.NoError(t, w.Send(context.Background(), []inventory.Event{
+		inventory.ReaderOfflineEvent{DeviceName: "Reader1"},
+	}))
+	s := waitForStatus(t, w, hook.ID, func(s WebhookStatus) bool { return s.Failed == 1 })
+	assert.Equal(t, http.StatusTemporaryRedirect, s.LastStatusCode)
+	assert.Empty(t, receiver.received())
+	w.Close()
+
+	// without allowed hosts, webhooks can't be registered, and saved ones are dropped
+	cfg.AllowedHosts = nil
+	w, err = NewWebhooks(getTestingLogger(), cfg)
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Empty(t, w.List())
+	_, err = w.Register(WebhookConfig{URL: receiver.URL})
+	assert.True(t, errors.Is(err, ErrWebhookNotAllowed))
+
+	w2, err := NewWebhooks(getTestingLogger(), WebhooksConfig{AllowedHosts: []string{AnyWebhookHost}})
+	require.NoError(t, err)
+	defer w2.Close()
+	_, err = w2.Register(WebhookConfig{URL: "https://example.com/hook"})
+	assert.NoError(t, err)
+}
+
+func TestWebhookConfig(t *testing.T) {
+	for _, bad := range []WebhookConfig{
+		{URL: ""},
+		{URL: "localhost:8080/hook"},
+		{URL: "ftp://localhost/hook"},
+		{URL: "http://localhost/hook", EPCPrefixes: []string{""}},
+		{URL: "http://localhost/hook", EventTypes: []inventory.EventType{""}},
+	} {
+		assert.True(t, errors.Is(bad.validate(), ErrInvalidWebhook), bad.URL)
+	}
+
+	cfg := WebhookConfig{Aliases: []string{"Reader1", "Door"}}
+	assert.True(t, cfg.matches(inventory.ReaderOfflineEvent{DeviceName: "Reader1"}))
+	assert.True(t, cfg.matches(inventory.DepartedEvent{LastKnownLocation: "Door"}))
+	assert.False(t, cfg.matches(inventory.PositionUpdatedEvent{BaseEvent: inventory.BaseEvent{EPC: "01"}}))
+
+	cfg = WebhookConfig{EPCPrefixes: []string{"E2"}}
+	assert.True(t, cfg.matches(inventory.ArrivedEvent{BaseEvent: inventory.BaseEvent{EPC: "e20001"}}))
+	assert.False(t, cfg.matches(inventory.ArrivedEvent{BaseEvent: inventory.BaseEvent{EPC: "300001"}}))
+	assert.False(t, cfg.matches(inventory.ReaderOfflineEvent{DeviceName: "Reader1"}))
+}